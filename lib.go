@@ -18,14 +18,12 @@ package main
 import "C"
 
 import (
-	"crypto/rand"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
-	"io"
 	"log"
-	"math/big"
 	"runtime"
+	"sync"
 	"unsafe"
 
 	secp256k1 "github.com/armoniax/go-secp256k1"
@@ -101,6 +99,16 @@ func wallet_get_public_keys_() *C.char {
 	return renderData(keys)
 }
 
+//export wallet_set_chain_profile_
+func wallet_set_chain_profile_(profile *C.char, length C.int) *C.char {
+	_profile, err := uuoskit.ParseChainProfile(C.GoBytes(unsafe.Pointer(profile), length))
+	if err != nil {
+		return renderError(err)
+	}
+	uuoskit.GetWallet().SetChainProfile(_profile)
+	return renderData("ok")
+}
+
 //export wallet_sign_digest_
 func wallet_sign_digest_(digest *C.char, pubKey *C.char) *C.char {
 	_pubKey := C.GoString(pubKey)
@@ -118,30 +126,49 @@ func wallet_sign_digest_(digest *C.char, pubKey *C.char) *C.char {
 }
 
 var gChainContexts []*uuoskit.ChainContext
+var gChainContextsMu sync.RWMutex
 
 //export new_chain_context_
 func new_chain_context_() C.int64_t {
+	return C.int64_t(addChainContext(uuoskit.NewChainContext()))
+}
+
+//export new_chain_context_with_profile_
+func new_chain_context_with_profile_(profile *C.char, length C.int) C.int64_t {
+	_profile, err := uuoskit.ParseChainProfile(C.GoBytes(unsafe.Pointer(profile), length))
+	if err != nil {
+		log.Println(err)
+		return C.int64_t(-1)
+	}
+	return C.int64_t(addChainContext(uuoskit.NewChainContextWithProfile(_profile)))
+}
+
+func addChainContext(ctx *uuoskit.ChainContext) int {
+	gChainContextsMu.Lock()
+	defer gChainContextsMu.Unlock()
 	if gChainContexts == nil {
 		gChainContexts = make([]*uuoskit.ChainContext, 0, 64)
 	}
 
 	for i := 0; i < len(gChainContexts); i++ {
 		if gChainContexts[i] == nil {
-			gChainContexts[i] = uuoskit.NewChainContext()
-			return C.int64_t(i)
+			gChainContexts[i] = ctx
+			return i
 		}
 	}
 
 	if len(gChainContexts) >= 64 {
-		return C.int64_t(-1)
+		return -1
 	}
 
-	gChainContexts = append(gChainContexts, uuoskit.NewChainContext())
-	return C.int64_t(len(gChainContexts) - 1)
+	gChainContexts = append(gChainContexts, ctx)
+	return len(gChainContexts) - 1
 }
 
 //export chain_context_free_
 func chain_context_free_(_index C.int64_t) *C.char {
+	gChainContextsMu.Lock()
+	defer gChainContextsMu.Unlock()
 	index := int(_index)
 	if index < 0 || index >= len(gChainContexts) {
 		return renderError(fmt.Errorf("bad chain index %d", index))
@@ -151,43 +178,23 @@ func chain_context_free_(_index C.int64_t) *C.char {
 }
 
 func getChainContext(index int) (*uuoskit.ChainContext, error) {
+	gChainContextsMu.RLock()
+	defer gChainContextsMu.RUnlock()
 	if index < 0 || index >= len(gChainContexts) {
 		return nil, fmt.Errorf("invalid chain index %d", index)
 	}
-	return gChainContexts[int(index)], nil
-}
-
-func validateIndex(packedTxs []*uuoskit.PackedTransaction, idx C.int64_t) error {
-	if idx < 0 || idx >= C.int64_t(len(packedTxs)) {
-		return fmt.Errorf("invalid transaction index %d", idx)
-	}
-
-	if packedTxs[idx] == nil {
-		return fmt.Errorf("transaction at index %d is nil!", idx)
+	if gChainContexts[index] == nil {
+		return nil, fmt.Errorf("chain context at index %d is nil!", index)
 	}
-
-	return nil
+	return gChainContexts[int(index)], nil
 }
 
-func addPackedTx(ctx *uuoskit.ChainContext, packedTx *uuoskit.PackedTransaction) C.int64_t {
-	packedTxs := ctx.PackedTxs
-	if packedTxs == nil {
-		return -1
-	}
-
-	for i := 0; i < len(packedTxs); i++ {
-		if packedTxs[i] == nil {
-			packedTxs[i] = packedTx
-			return C.int64_t(i)
-		}
-	}
-
-	if len(packedTxs) >= 1024 {
-		return C.int64_t(-1)
+func getPackedTx(chainIndex C.int64_t, idx C.int64_t) (*uuoskit.PackedTransaction, error) {
+	ctx, err := getChainContext(int(chainIndex))
+	if err != nil {
+		return nil, err
 	}
-
-	ctx.PackedTxs = append(ctx.PackedTxs, packedTx)
-	return C.int64_t(len(ctx.PackedTxs) - 1)
+	return ctx.GetPackedTx(int(idx))
 }
 
 //export transaction_new_
@@ -202,7 +209,7 @@ func transaction_new_(chainIndex C.int64_t, expiration C.int64_t, refBlock *C.ch
 	packedTx := uuoskit.NewPackedTransaction(tx)
 	packedTx.SetChainId(C.GoString(chainId))
 
-	return addPackedTx(ctx, packedTx)
+	return C.int64_t(ctx.AddPackedTx(packedTx))
 }
 
 //export transaction_from_json_
@@ -218,7 +225,7 @@ func transaction_from_json_(chainIndex C.int64_t, tx *C.char, chainId *C.char) *
 		return renderError(err)
 	}
 	packedTx.SetChainId(C.GoString(chainId))
-	i := addPackedTx(ctx, packedTx)
+	i := ctx.AddPackedTx(packedTx)
 	return renderData(i)
 }
 
@@ -229,26 +236,21 @@ func transaction_free_(chainIndex C.int64_t, _index C.int64_t) *C.char {
 		return renderError(err)
 	}
 
-	index := int(_index)
-	if index < 0 || index >= len(ctx.PackedTxs) {
-		return renderError(fmt.Errorf("bad transaction index %d", index))
+	err = ctx.RemovePackedTx(int(_index))
+	if err != nil {
+		return renderError(err)
 	}
-	ctx.PackedTxs[int(index)] = nil
 	return renderData("ok")
 }
 
 //export transaction_set_chain_id_
 func transaction_set_chain_id_(chainIndex C.int64_t, _index C.int64_t, chainId *C.char) *C.char {
-	ctx, err := getChainContext(int(chainIndex))
+	packedTx, err := getPackedTx(chainIndex, _index)
 	if err != nil {
 		return renderError(err)
 	}
 
-	index := int(_index)
-	if index < 0 || index >= len(ctx.PackedTxs) {
-		return renderError(fmt.Errorf("invalid index"))
-	}
-	err = ctx.PackedTxs[int(index)].SetChainId(C.GoString(chainId))
+	err = packedTx.SetChainId(C.GoString(chainId))
 	if err != nil {
 		return renderError(err)
 	}
@@ -262,7 +264,8 @@ func transaction_add_action_(chainIndex C.int64_t, idx C.int64_t, account *C.cha
 		return renderError(err)
 	}
 
-	if err := validateIndex(ctx.PackedTxs, idx); err != nil {
+	packedTx, err := ctx.GetPackedTx(int(idx))
+	if err != nil {
 		return renderError(err)
 	}
 
@@ -294,7 +297,7 @@ func transaction_add_action_(chainIndex C.int64_t, idx C.int64_t, account *C.cha
 		}
 	}
 
-	err = ctx.PackedTxs[idx].AddAction(action)
+	err = packedTx.AddAction(action)
 	if err != nil {
 		return renderError(err)
 	}
@@ -308,12 +311,8 @@ func transaction_sign_(chainIndex C.int64_t, idx C.int64_t, pub *C.char) *C.char
 		return renderError(err)
 	}
 
-	if err := validateIndex(ctx.PackedTxs, idx); err != nil {
-		return renderError(err)
-	}
-
 	_pub := C.GoString(pub)
-	sign, err := ctx.PackedTxs[idx].Sign(_pub)
+	sign, err := ctx.SignPackedTx(int(idx), _pub)
 	if err != nil {
 		return renderError(err)
 	}
@@ -322,17 +321,13 @@ func transaction_sign_(chainIndex C.int64_t, idx C.int64_t, pub *C.char) *C.char
 
 //export transaction_digest_
 func transaction_digest_(chainIndex C.int64_t, idx C.int64_t, chainId *C.char) *C.char {
-	ctx, err := getChainContext(int(chainIndex))
+	packedTx, err := getPackedTx(chainIndex, idx)
 	if err != nil {
 		return renderError(err)
 	}
 
-	if err := validateIndex(ctx.PackedTxs, idx); err != nil {
-		return renderError(err)
-	}
-
 	_chainId := C.GoString(chainId)
-	digest, err := ctx.PackedTxs[idx].Digest(_chainId)
+	digest, err := packedTx.Digest(_chainId)
 	if err != nil {
 		return renderError(err)
 	}
@@ -341,16 +336,12 @@ func transaction_digest_(chainIndex C.int64_t, idx C.int64_t, chainId *C.char) *
 
 //export transaction_sign_by_private_key_
 func transaction_sign_by_private_key_(chainIndex C.int64_t, idx C.int64_t, priv *C.char) *C.char {
-	ctx, err := getChainContext(int(chainIndex))
+	packedTx, err := getPackedTx(chainIndex, idx)
 	if err != nil {
 		return renderError(err)
 	}
 
-	if err := validateIndex(ctx.PackedTxs, idx); err != nil {
-		return renderError(err)
-	}
-
-	sign, err := ctx.PackedTxs[idx].SignByPrivateKey(C.GoString(priv))
+	sign, err := packedTx.SignByPrivateKey(C.GoString(priv))
 	if err != nil {
 		return renderError(err)
 	}
@@ -359,36 +350,28 @@ func transaction_sign_by_private_key_(chainIndex C.int64_t, idx C.int64_t, priv
 
 //export transaction_pack_
 func transaction_pack_(chainIndex C.int64_t, idx C.int64_t, compress C.int) *C.char {
-	ctx, err := getChainContext(int(chainIndex))
+	packedTx, err := getPackedTx(chainIndex, idx)
 	if err != nil {
 		return renderError(err)
 	}
 
-	if err := validateIndex(ctx.PackedTxs, idx); err != nil {
-		return renderError(err)
-	}
-
 	var result string
 	if compress != 0 {
-		result = ctx.PackedTxs[idx].Pack(true)
+		result = packedTx.Pack(true)
 	} else {
-		result = ctx.PackedTxs[idx].Pack(false)
+		result = packedTx.Pack(false)
 	}
 	return renderData(result)
 }
 
 //export transaction_marshal_
 func transaction_marshal_(chainIndex C.int64_t, idx C.int64_t) *C.char {
-	ctx, err := getChainContext(int(chainIndex))
+	packedTx, err := getPackedTx(chainIndex, idx)
 	if err != nil {
 		return renderError(err)
 	}
 
-	if err := validateIndex(ctx.PackedTxs, idx); err != nil {
-		return renderError(err)
-	}
-
-	result := ctx.PackedTxs[idx].Marshal()
+	result := packedTx.Marshal()
 	return renderData(result)
 }
 
@@ -585,6 +568,9 @@ func crypto_sign_digest_(digest *C.char, privateKey *C.char) *C.char {
 	return renderData(result.String())
 }
 
+// Deprecated: eosPub selects the AM prefix whatever the chain, use
+// crypto_get_public_key_with_prefix_ with the key prefix of the profile.
+//
 //export crypto_get_public_key_
 func crypto_get_public_key_(privateKey *C.char, eosPub C.int) *C.char {
 	_privateKey, err := secp256k1.NewPrivateKeyFromBase58(C.GoString(privateKey))
@@ -601,6 +587,24 @@ func crypto_get_public_key_(privateKey *C.char, eosPub C.int) *C.char {
 	}
 }
 
+//export crypto_get_public_key_with_prefix_
+func crypto_get_public_key_with_prefix_(privateKey *C.char, prefix *C.char) *C.char {
+	_privateKey, err := secp256k1.NewPrivateKeyFromBase58(C.GoString(privateKey))
+	if err != nil {
+		return renderError(err)
+	}
+
+	pub := _privateKey.GetPublicKey()
+	_prefix := C.GoString(prefix)
+	// an empty prefix selects the PUB_K1_ format
+	if _prefix == "" {
+		return renderData(pub.String())
+	}
+	profile := uuoskit.DefaultChainProfile()
+	profile.KeyPrefix = _prefix
+	return renderData(profile.PublicKeyString(pub))
+}
+
 //export crypto_recover_key_
 func crypto_recover_key_(digest *C.char, signature *C.char, format C.int) *C.char {
 	_digest, err := hex.DecodeString(C.GoString(digest))
@@ -627,54 +631,10 @@ func crypto_recover_key_(digest *C.char, signature *C.char, format C.int) *C.cha
 
 //export crypto_create_key_
 func crypto_create_key_(oldPubKeyFormat C.bool) *C.char {
-	ret := CreateKey(bool(oldPubKeyFormat))
+	ret := uuoskit.CreateKey(bool(oldPubKeyFormat))
 	return renderData(ret)
 }
 
-func CreateKey(oldPubKeyFormat bool) map[string]string {
-	priv := genPrivKey(rand.Reader)
-	_priv := secp256k1.NewPrivateKey(priv)
-
-	ret := make(map[string]string)
-	ret["private"] = _priv.String()
-	if oldPubKeyFormat {
-		ret["public"] = _priv.GetPublicKey().StringAM()
-	} else {
-		ret["public"] = _priv.GetPublicKey().String()
-	}
-	return ret
-}
-
-// https://github.com/tendermint/tendermint/blob/de2cffe7a44e99bf81a62c542d50ccbf28dc852a/crypto/secp256k1/secp256k1.go#L73
-// genPrivKey generates a new secp256k1 private key using the provided reader.
-func genPrivKey(rand io.Reader) []byte {
-	var privKeyBytes [32]byte
-	d := new(big.Int)
-
-	//"github.com/btcsuite/btcd/btcec"
-	//btcec.S256().N
-	//log.Println(btcec.S256().N.Bytes())
-	N := big.NewInt(0).SetBytes([]byte{255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 254, 186, 174, 220, 230, 175, 72, 160, 59, 191, 210, 94, 140, 208, 54, 65, 65})
-
-	for {
-		privKeyBytes = [32]byte{}
-		_, err := io.ReadFull(rand, privKeyBytes[:])
-		if err != nil {
-			panic(err)
-		}
-
-		d.SetBytes(privKeyBytes[:])
-		// break if we found a valid point (i.e. > 0 and < N == curverOrder)
-		// isValidFieldElement := 0 < d.Sign() && d.Cmp(btcec.S256().N) < 0
-		isValidFieldElement := 0 < d.Sign() && d.Cmp(N) < 0
-		if isValidFieldElement {
-			break
-		}
-	}
-
-	return privKeyBytes[:]
-}
-
 //export set_debug_flag_
 func set_debug_flag_(debug C.bool) {
 	uuoskit.SetDebug(bool(debug))
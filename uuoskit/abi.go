@@ -3,12 +3,9 @@ package uuoskit
 import (
 	"encoding/hex"
 	"encoding/json"
-	"fmt"
 	"math"
-	"math/big"
 	"strconv"
 	"strings"
-	"time"
 
 	secp256k1 "github.com/armoniax/go-secp256k1"
 	"github.com/iancoleman/orderedmap"
@@ -28,6 +25,13 @@ type ABIAction struct {
 	RicardianContract string `json:"ricardian_contract"`
 }
 
+// ABIActionResult declares the type returned by a read-only or regular
+// action
+type ABIActionResult struct {
+	Name       string `json:"name"`
+	ResultType string `json:"result_type"`
+}
+
 type ABIStructField struct {
 	Name string `json:"name"`
 	Type string `json:"type"`
@@ -77,15 +81,58 @@ type AbiExtendedAsset struct {
 }
 
 type ABI struct {
-	Version          string         `json:"version"`
-	Types            []ABIType      `json:"types"`
-	Structs          []ABIStruct    `json:"structs"`
-	Actions          []ABIAction    `json:"actions"`
-	Tables           []ABITable     `json:"tables"`
-	RicardianClauses []ClausePair   `json:"ricardian_clauses"`
-	ErrorMessages    []ErrorMessage `json:"error_messages"`
-	AbiExtensions    []AbiExtension `json:"abi_extensions"`
-	Variants         []VariantDef   `json:"variants"`
+	Version          string            `json:"version"`
+	Types            []ABIType         `json:"types"`
+	Structs          []ABIStruct       `json:"structs"`
+	Actions          []ABIAction       `json:"actions"`
+	Tables           []ABITable        `json:"tables"`
+	RicardianClauses []ClausePair      `json:"ricardian_clauses"`
+	ErrorMessages    []ErrorMessage    `json:"error_messages"`
+	AbiExtensions    []AbiExtension    `json:"abi_extensions"`
+	Variants         []VariantDef      `json:"variants"`
+	ActionResults    []ABIActionResult `json:"action_results,omitempty"`
+
+	opts        UnpackOptions
+	decoderOpts DecoderOptions
+}
+
+// SetDecoderOptions sets the limits applied when decoding values of the ABI
+func (t *ABI) SetDecoderOptions(opts DecoderOptions) {
+	t.decoderOpts = opts
+}
+
+func (t *ABI) GetDecoderOptions() DecoderOptions {
+	return t.decoderOpts.withDefaults()
+}
+
+// newDecoder returns a decoder of a value of type typ, errors report their
+// path from typ
+func (t *ABI) newDecoder(data []byte, typ string) *Decoder {
+	dec := NewDecoderWithOptions(data, t.decoderOpts)
+	dec.pushField(typ)
+	return dec
+}
+
+// SetUnpackOptions controls the JSON produced by the unpacker
+func (t *ABI) SetUnpackOptions(opts UnpackOptions) {
+	t.opts = opts
+}
+
+func (t *ABI) GetUnpackOptions() UnpackOptions {
+	return t.opts
+}
+
+// SetDecimalInt128 makes the unpacker render int128 and uint128 values as
+// decimal strings instead of 0x prefixed hex.
+func (t *ABI) SetDecimalInt128(decimal bool) {
+	t.opts.DecimalInt128 = decimal
+}
+
+// SetDecimalFloat128 makes the unpacker render float128 values as the
+// shortest decimal string that converts back to the same value, instead
+// of 0x prefixed little endian hex.
+func (t *ABI) SetDecimalFloat128(decimal bool) {
+	t.opts.DecimalFloat128 = decimal
 }
 
 func (t *ABI) PackAbiType(abiType string, args string) ([]byte, error) {
@@ -106,7 +153,7 @@ func (t *ABI) PackAbiType(abiType string, args string) ([]byte, error) {
 }
 
 func (t *ABI) UnpackAbiType(abiName string, packedValue []byte) ([]byte, error) {
-	dec := NewDecoder(packedValue)
+	dec := t.newDecoder(packedValue, abiName)
 	result := orderedmap.New()
 	err := t.UnpackAbiStruct(dec, abiName, result)
 	if err != nil {
@@ -189,6 +236,10 @@ func (t *ABI) ParseAbiStringValue(enc *Encoder, typ string, v string) error {
 		}
 		enc.PackUint32(uint32(n))
 	case "int64":
+		// quoted as produced with UnpackOptions.Int64AsString
+		if vv, ok := StripString(v); ok {
+			v = vv
+		}
 		n, err := StringToInt(v)
 		if err != nil {
 			return newError(err)
@@ -199,6 +250,9 @@ func (t *ABI) ParseAbiStringValue(enc *Encoder, typ string, v string) error {
 		enc.PackInt64(int64(n))
 		break
 	case "uint64":
+		if vv, ok := StripString(v); ok {
+			v = vv
+		}
 		n, err := strconv.ParseUint(v, 10, 64)
 		if err != nil {
 			return newError(err)
@@ -207,60 +261,30 @@ func (t *ABI) ParseAbiStringValue(enc *Encoder, typ string, v string) error {
 			return newErrorf("uint64 overflow: %d", n)
 		}
 		enc.PackUint64(uint64(n))
-	case "int128", "uint128", "float128":
+	case "int128", "uint128":
+		// decimal, either quoted or as a bare number, or quoted 0x prefixed hex
 		if vv, ok := StripString(v); ok {
-			if vv[:2] != "0x" {
-				return newErrorf("invalid %s, value: %s", typ, vv)
-			}
-
-			vv = vv[2:]
-			if typ == "float128" && len(vv) != 32 {
-				return newErrorf("invalid %s, %s, should be 0x followed by 32 hex character", typ, vv)
-			}
-
-			bs, err := hex.DecodeString(vv)
-			if err != nil {
-				return newError(err)
-			}
-			if len(bs) > 16 {
-				return newErrorf("invalid %s, value: %s", typ, v)
-			}
-			buf := make([]byte, 16)
-			if typ != "float128" {
-				reverseBytes(bs)
-			}
-			copy(buf[:], bs)
-			enc.WriteBytes(buf)
-		} else {
-			if typ == "float128" {
-				return newErrorf("invalid %s, value: %s", typ, v)
-			}
-			n := new(big.Int)
-			n, ok := n.SetString(v, 10)
-			if !ok {
-				return newErrorf("invalid %s, value: %s", typ, v)
-			}
-			bs := n.Bytes()
-			if len(bs) > 16 {
-				return newErrorf("invalid %s, value: %s", typ, v)
-			}
-			if n.Sign() < 0 {
-				if typ == "uint128" || typ == "float128" {
-					return newErrorf("invalid %s, value: %s", typ, v)
-				}
-				m := new(big.Int)
-				m.SetBytes([]byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff})
-				m.Sub(m, n.Abs(n))
-				tmp := &big.Int{}
-				tmp.SetUint64(1)
-				m.Add(m, tmp)
-				bs = m.Bytes()
-			}
-			buf := make([]byte, 16)
-			reverseBytes(bs)
-			copy(buf[:], bs)
-			enc.WriteBytes(buf)
+			v = vv
+		}
+		buf := [16]byte{}
+		n, err := parseBigInt(v, 16, typ == "int128")
+		if err != nil {
+			return newErrorf("invalid %s, value: %s", typ, v)
+		}
+		if err := bigToLE(n, buf[:], typ == "int128"); err != nil {
+			return newErrorf("invalid %s, value: %s", typ, v)
+		}
+		enc.WriteBytes(buf[:])
+	case "float128":
+		// 0x prefixed little endian hex or a decimal, quoted or bare
+		if vv, ok := StripString(v); ok {
+			v = vv
+		}
+		f := Float128{}
+		if err := f.parse(v); err != nil {
+			return newErrorf("invalid %s, value: %s", typ, v)
 		}
+		enc.WriteBytes(f[:])
 	case "varint32":
 		n, err := StringToInt(v)
 		if err != nil {
@@ -297,35 +321,49 @@ func (t *ABI) ParseAbiStringValue(enc *Encoder, typ string, v string) error {
 		if !ok {
 			return newErrorf("invalid time_point value: %s", v)
 		}
-		tt, err := time.Parse(time.RFC3339, string(v)+"Z")
+		tt, err := ParseTime(v)
 		if err != nil {
-			return newError(err)
+			return err
 		}
-		n := tt.UnixMicro()
-		enc.PackInt64(n)
+		enc.PackInt64(tt.UnixMicro())
 	case "time_point_sec":
 		v, ok := StripString(v)
 		if !ok {
 			return newErrorf("invalid time_point_sec value: %s", v)
 		}
-		tt, err := time.Parse(time.RFC3339, string(v)+"Z")
+		tt, err := ParseTime(v)
 		if err != nil {
-			return newError(err)
+			return err
 		}
-		n := tt.Unix()
-		enc.PackUint32(uint32(n))
+		tp, err := NewTimePointSec(tt)
+		if err != nil {
+			return err
+		}
+		enc.PackUint32(tp.UTCSeconds)
 	case "block_timestamp_type":
-		return newErrorf("packing block_timestamp_type type does not supported")
+		v, ok := StripString(v)
+		if !ok {
+			return newErrorf("invalid block_timestamp_type value: %s", v)
+		}
+		tt, err := ParseTime(v)
+		if err != nil {
+			return err
+		}
+		bt, err := NewBlockTimestamp(tt)
+		if err != nil {
+			return err
+		}
+		enc.PackUint32(bt.Slot)
 	case "name":
 		v, ok := StripString(v)
 		if !ok {
 			return newErrorf("invalid name value: %s", v)
 		}
-		n := S2N(v)
-		if N2S(n) != v {
-			return newErrorf("invalid name value: %s", v)
+		n, err := ParseName(v)
+		if err != nil {
+			return err
 		}
-		enc.PackUint64(n)
+		enc.PackUint64(n.N)
 	case "bytes":
 		v, ok := StripString(v)
 		if !ok {
@@ -392,9 +430,9 @@ func (t *ABI) ParseAbiStringValue(enc *Encoder, typ string, v string) error {
 		if !ok {
 			return newErrorf("invalid public_key value: %s", v)
 		}
-		pub, err := secp256k1.NewPublicKeyFromBase58(v)
+		pub, err := parsePublicKey(v, t.opts.LegacyKeyPrefix)
 		if err != nil {
-			return newError(err)
+			return err
 		}
 		enc.WriteBytes([]byte{0})
 		enc.WriteBytes(pub.Data[:])
@@ -419,11 +457,15 @@ func (t *ABI) ParseAbiStringValue(enc *Encoder, typ string, v string) error {
 		if len(vv) != 2 {
 			return newErrorf("invalid symbol value: %s", v)
 		}
+		// accept the code first form produced by SymbolCodeFirst as well
+		if IsSymbolValid(vv[0]) {
+			vv[0], vv[1] = vv[1], vv[0]
+		}
 		n, err := strconv.ParseUint(vv[0], 10, 64)
 		if err != nil {
 			return newError(err)
 		}
-		if n > 16 {
+		if n > maxAssetPrecision {
 			return newErrorf("invalid symbol value: %s", v)
 		}
 		if len(vv[1]) > 7 || len(vv[1]) <= 0 {
@@ -455,28 +497,28 @@ func (t *ABI) ParseAbiStringValue(enc *Encoder, typ string, v string) error {
 		if !ok {
 			return newErrorf("StripString: Invalid asset value: %s", v)
 		}
-		r, ok := ParseAsset(v)
-		if !ok {
-			return newErrorf("ParseAsset: Invalid asset value: %s", v)
+		r, err := ParseAsset(v)
+		if err != nil {
+			return err
 		}
-		enc.WriteBytes(r)
+		enc.WriteBytes(r.Pack())
 	case "extended_asset":
 		a := AbiExtendedAsset{}
 		err := json.Unmarshal([]byte(v), &a)
 		if err != nil {
 			return newError(err)
 		}
-		r, ok := ParseAsset(a.Quantity)
-		if !ok {
-			return newErrorf("invalid asset value: %s", v)
+		r, err := ParseAsset(a.Quantity)
+		if err != nil {
+			return err
 		}
-		enc.WriteBytes(r)
+		enc.WriteBytes(r.Pack())
 
-		n := S2N(a.Contract)
-		if N2S(n) != v {
-			return newErrorf("invalid name value: %s", v)
+		n, err := ParseName(a.Contract)
+		if err != nil {
+			return err
 		}
-		enc.PackUint64(n)
+		enc.PackUint64(n.N)
 	default:
 		return newErrorf("unsupported type: %s %T, %v\n", typ, v, v)
 	}
@@ -533,19 +575,25 @@ func (t *ABI) unpackAbiStructField(dec *Decoder, typ string) (interface{}, error
 		if err != nil {
 			return nil, newError(err)
 		}
-		return v, nil
+		return t.opts.int64Value(v), nil
 	case "uint64":
 		v, err := dec.UnpackUint64()
 		if err != nil {
 			return nil, newError(err)
 		}
-		return v, nil
+		return t.opts.uint64Value(v), nil
 	case "int128", "uint128", "float128":
 		buf := [16]byte{}
 		err := dec.Read(buf[:])
 		if err != nil {
 			return nil, newError(err)
 		}
+		if typ != "float128" && t.opts.DecimalInt128 {
+			return leToBig(buf[:], typ == "int128").String(), nil
+		}
+		if typ == "float128" && t.opts.DecimalFloat128 {
+			return Float128(buf).String(), nil
+		}
 		if typ != "float128" {
 			reverseBytes(buf[:])
 		}
@@ -579,17 +627,19 @@ func (t *ABI) unpackAbiStructField(dec *Decoder, typ string) (interface{}, error
 		if err != nil {
 			return nil, newError(err)
 		}
-		//convert seconds to iso8601
-		return time.UnixMicro(int64(v)).Format("2006-01-02T15:04:05"), nil
+		return TimePoint{v}.String(), nil
 	case "time_point_sec":
 		v, err := dec.ReadUint32()
 		if err != nil {
 			return nil, newError(err)
 		}
-		//convert seconds to iso8601
-		return time.Unix(int64(v), 0).Format("2006-01-02T15:04:05"), nil
+		return TimePointSec{v}.String(), nil
 	case "block_timestamp_type":
-		return nil, newErrorf("block_timestamp_type is not supported in unpacking")
+		v, err := dec.ReadUint32()
+		if err != nil {
+			return nil, newError(err)
+		}
+		return BlockTimestampType{v}.String(), nil
 	case "name":
 		v, err := dec.ReadUint64()
 		if err != nil {
@@ -637,7 +687,7 @@ func (t *ABI) unpackAbiStructField(dec *Decoder, typ string) (interface{}, error
 		}
 		pub := secp256k1.PublicKey{}
 		copy(pub.Data[:], v[1:])
-		return pub.StringAM(), nil
+		return t.opts.publicKey(&pub), nil
 	case "signature":
 		v := make([]byte, 66)
 		err := dec.Read(v)
@@ -648,15 +698,13 @@ func (t *ABI) unpackAbiStructField(dec *Decoder, typ string) (interface{}, error
 		copy(sig.Data[:], v[1:])
 		return sig.String(), nil
 	case "symbol":
-		buf := make([]byte, 8)
-		err := dec.Read(buf)
+		sym := Symbol{}
+		v, err := dec.UnpackUint64()
 		if err != nil {
 			return nil, newError(err)
 		}
-		precision := int(buf[0])
-		sym := string(buf[1:])
-		sym = strings.TrimRight(sym, "\x00")
-		return fmt.Sprintf("%s,%d", sym, precision), nil
+		sym.Value = v
+		return t.opts.symbol(&sym), nil
 	case "symbol_code":
 		buf := make([]byte, 8)
 		err := dec.Read(buf)
@@ -667,20 +715,17 @@ func (t *ABI) unpackAbiStructField(dec *Decoder, typ string) (interface{}, error
 		sym = strings.TrimRight(sym, "\x00")
 		return sym, nil
 	case "asset":
+		a := Asset{}
 		amount, err := dec.UnpackInt64()
 		if err != nil {
 			return nil, newError(err)
 		}
-		sym := make([]byte, 8)
-		err = dec.Read(sym)
+		a.Amount = amount
+		a.Symbol.Value, err = dec.UnpackUint64()
 		if err != nil {
 			return nil, newError(err)
 		}
-		precision := int64(sym[0])
-		_sym := strings.TrimRight(string(sym[1:]), "\x00")
-		format := fmt.Sprintf("%%d.%%0%dd %%s", precision)
-		precision = int64(math.Pow10(int(precision)))
-		return fmt.Sprintf(format, amount/precision, amount%precision, _sym), nil
+		return t.opts.asset(&a), nil
 	case "extended_asset":
 		// {"quantity":"1.0000 EOS","contract":"eosio.token"}
 		quantity, err := t.unpackAbiStructField(dec, "asset")
@@ -696,15 +741,41 @@ func (t *ABI) unpackAbiStructField(dec *Decoder, typ string) (interface{}, error
 		m.Set("contract", contract)
 		return m, nil
 	default:
-		return nil, newErrorf("unknown type %s", typ)
+		return nil, dec.errorf("unknown type %s", typ)
+	}
+}
+
+// splitArrayType splits the outermost array suffix from typ, size is -1
+// for dynamic arrays (T[]) and N for fixed size arrays (T[N]).
+func splitArrayType(typ string) (string, int, bool) {
+	if !strings.HasSuffix(typ, "]") {
+		return "", 0, false
+	}
+	i := strings.LastIndexByte(typ, '[')
+	if i <= 0 {
+		return "", 0, false
 	}
+	if i == len(typ)-2 {
+		return typ[:i], -1, true
+	}
+	size, err := strconv.ParseUint(typ[i+1:len(typ)-1], 10, 32)
+	if err != nil {
+		return "", 0, false
+	}
+	return typ[:i], int(size), true
 }
 
 func (t *ABI) PackArrayAbiValue(enc *Encoder, typ string, value []JsonValue) error {
+	return t.packArrayAbiValue(enc, strings.TrimSuffix(typ, "[]"), -1, value, 0)
+}
+
+func (t *ABI) packArrayAbiValue(enc *Encoder, typ string, size int, value []JsonValue, depth int) error {
+	if size >= 0 && len(value) != size {
+		return newErrorf("array size mismatch, expected %d, got %d", size, len(value))
+	}
 	enc.PackVarUint32(uint32(len(value)))
 	for _, v := range value {
-		_typ := strings.TrimSuffix(typ, "[]")
-		err := t.PackAbiValue(enc, _typ, v)
+		err := t.packAbiValue(enc, typ, v, depth+1)
 		if err != nil {
 			return newError(err)
 		}
@@ -732,13 +803,20 @@ func (t *ABI) GetVariantType(typ string) (*VariantDef, bool) {
 }
 
 func (t *ABI) PackAbiStruct(enc *Encoder, structName string, m map[string]JsonValue) error {
+	return t.packAbiStruct(enc, structName, m, 0)
+}
+
+func (t *ABI) packAbiStruct(enc *Encoder, structName string, m map[string]JsonValue, depth int) error {
+	if depth > maxAbiTypeDepth {
+		return newErrorf("struct %s is nested too deeply", structName)
+	}
 	abiStruct := t.GetAbiStruct(structName)
 	if abiStruct == nil {
 		return newErrorf("abi struct %s not found", structName)
 	}
 
 	if abiStruct.Base != "" {
-		err := t.PackAbiStruct(enc, abiStruct.Base, m)
+		err := t.packAbiStruct(enc, abiStruct.Base, m, depth+1)
 		if err != nil {
 			return newError(err)
 		}
@@ -752,44 +830,12 @@ func (t *ABI) PackAbiStruct(enc *Encoder, structName string, m map[string]JsonVa
 			//handle binary_extension
 			if strings.HasSuffix(typ, "$") {
 				continue
-				//typ = strings.TrimSuffix(typ, "$")
 			}
 			return newErrorf("missing field %s", name)
 		}
 
-		if strings.HasSuffix(typ, "$") {
-			typ = strings.TrimSuffix(typ, "$")
-		} else if strings.HasSuffix(typ, "?") {
-			typ = strings.TrimSuffix(typ, "?")
-			if value, ok := abiValue.GetValue().(string); ok {
-				if value == "null" {
-					enc.PackBool(false)
-					continue
-				} else {
-					enc.PackBool(true)
-				}
-			}
-		}
-
-		for i := range t.Types {
-			v := &t.Types[i]
-			if v.NewTypeName == typ {
-				typ = v.Type
-				break
-			}
-		}
-
-		err := t.PackAbiValue(enc, typ, abiValue)
-		if err == nil {
-			continue
-		}
-
-		typ, ok = t.GetBaseABIType(typ)
-		if !ok {
-			return newError(err)
-		}
-
-		err = t.PackAbiValue(enc, typ, abiValue)
+		typ = strings.TrimSuffix(typ, "$")
+		err := t.packAbiValue(enc, typ, abiValue, depth+1)
 		if err != nil {
 			return newError(err)
 		}
@@ -798,193 +844,270 @@ func (t *ABI) PackAbiStruct(enc *Encoder, structName string, m map[string]JsonVa
 }
 
 func (t *ABI) UnpackAbiStruct(dec *Decoder, structName string, result *orderedmap.OrderedMap) error {
+	return t.unpackAbiStruct(dec, structName, result, 0)
+}
+
+// unpackAbiStruct counts fields and base structs in depth so that recursive
+// types and cyclic bases can not exhaust the stack
+func (t *ABI) unpackAbiStruct(dec *Decoder, structName string, result *orderedmap.OrderedMap, depth int) error {
+	if depth > dec.opts.MaxDepth {
+		return dec.errorf("struct %s is nested too deeply", structName)
+	}
 	abiStruct := t.GetAbiStruct(structName)
 	if abiStruct == nil {
-		return newErrorf("abi struct %s not found", structName)
+		return dec.errorf("abi struct %s not found", structName)
 	}
 
 	if abiStruct.Base != "" {
-		err := t.UnpackAbiStruct(dec, abiStruct.Base, result)
+		err := t.unpackAbiStruct(dec, abiStruct.Base, result, depth+1)
 		if err != nil {
 			return newError(err)
 		}
 	}
 
-	err := t.unpackAbiStructFields(dec, abiStruct.Fields, result)
+	err := t.unpackAbiStructFields(dec, abiStruct.Fields, result, depth)
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
-func (t *ABI) unpackAbiStructFields(dec *Decoder, fields []ABIStructField, result *orderedmap.OrderedMap) error {
+func (t *ABI) unpackAbiStructFields(dec *Decoder, fields []ABIStructField, result *orderedmap.OrderedMap, depth int) error {
 	for _, v := range fields {
 		typ := v.Type
 		name := v.Name
 
-		//handle optional
-		if strings.HasSuffix(typ, "?") {
-			v, err := dec.UnpackBool()
-			if err != nil {
-				return newError(err)
-			}
-			if !v {
-				result.Set(name, nil)
-				continue
-			}
-			typ = strings.TrimRight(typ, "?")
-		} else if strings.HasSuffix(typ, "$") { //handle binary_extension
+		//handle binary_extension
+		if strings.HasSuffix(typ, "$") {
 			if dec.IsEnd() {
 				return nil
 			}
-			typ = strings.TrimRight(typ, "$")
+			typ = strings.TrimSuffix(typ, "$")
 		}
 
-		//try to find base type
-		if baseName, ok := t.GetBaseName(typ); ok {
-			typ = baseName
-		}
-		//try to unpack inner abi type
-		if _, ok := gBaseTypes[typ]; ok {
-			v, err := t.unpackAbiStructField(dec, typ)
-			if err != nil {
-				return err
-			}
-			result.Set(name, v)
-			continue
+		dec.pushField(name)
+		value, err := t.unpackAbiValue(dec, typ, depth+1)
+		dec.popPath()
+		if err != nil {
+			return err
 		}
+		result.Set(name, value)
+	}
+	return nil
+}
 
-		//try to unpack Abi struct
-		subStruct := t.GetAbiStruct(typ)
-		if subStruct != nil {
-			subResult := orderedmap.New()
-			result.Set(name, subResult)
-			err := t.UnpackAbiStruct(dec, typ, subResult)
-			if err != nil {
-				return newError(err)
-			}
-			continue
-		}
-		//try to unpack variant type
-		if v, ok := t.GetVariantType(typ); ok {
-			index, err := dec.UnpackUint8()
-			if err != nil {
-				return err
-			}
+// UnpackAbiValue decodes a value of any type expression supported by the ABI:
+// built-in types, aliases, structs, variants and arbitrary compositions of
+// optionals (T?) and arrays (T[], T[N]) of them.
+func (t *ABI) UnpackAbiValue(dec *Decoder, typ string) (interface{}, error) {
+	return t.unpackAbiValue(dec, typ, 0)
+}
 
-			if int(index) >= len(v.Types) {
-				return newErrorf("invalid variant index %d", index)
-			}
-			tp := v.Types[int(index)]
-			arr := make([]interface{}, 0)
-			arr = append(arr, tp)
-			value, err := t.unpackAbiStructField(dec, tp)
-			if err != nil {
-				return err
-			}
-			arr = append(arr, value)
-			result.Set(name, arr)
-			continue
+func (t *ABI) unpackAbiValue(dec *Decoder, typ string, depth int) (interface{}, error) {
+	if depth > dec.opts.MaxDepth {
+		return nil, dec.errorf("type %s is nested too deeply", typ)
+	}
+
+	//handle optional
+	if strings.HasSuffix(typ, "?") {
+		present, err := dec.UnpackBool()
+		if err != nil {
+			return nil, newError(err)
 		}
-		//try to unpack array
-		if !strings.HasSuffix(typ, "[]") {
-			return newErrorf("unknown type %s", typ)
+		if !present {
+			return nil, nil
 		}
-		typ = strings.TrimSuffix(typ, "[]")
-		arr := make([]interface{}, 0)
+		return t.unpackAbiValue(dec, strings.TrimSuffix(typ, "?"), depth+1)
+	}
+
+	//handle array
+	if inner, size, ok := splitArrayType(typ); ok {
+		start := dec.Pos()
 		count, err := dec.UnpackLength()
 		if err != nil {
-			return newError(err)
+			return nil, newError(err)
 		}
-
-		//try to unpack inner abi type
-		if _, ok := gBaseTypes[typ]; ok {
-			for i := 0; i < count; i++ {
-				v, err := t.unpackAbiStructField(dec, typ)
-				if err != nil {
-					return newError(err)
-				}
-				arr = append(arr, v)
-			}
-			result.Set(name, arr)
-			continue
+		if size >= 0 && count != size {
+			return nil, dec.errorAt(start, "array size mismatch, expected %d, got %d", size, count)
 		}
-
-		subStruct = t.GetAbiStruct(typ)
-		if subStruct == nil {
-			return newErrorf("unknown type %s", typ)
+		minSize := 1
+		if count > len(dec.Remains()) && t.isZeroSized(inner, 0) {
+			minSize = 0
 		}
+		if err := dec.checkCount(start, count, minSize); err != nil {
+			return nil, err
+		}
+		arr := make([]interface{}, 0)
 		for i := 0; i < count; i++ {
-			subResult := orderedmap.New()
-			err := t.UnpackAbiStruct(dec, typ, subResult)
+			dec.pushIndex(i)
+			v, err := t.unpackAbiValue(dec, inner, depth+1)
+			dec.popPath()
 			if err != nil {
-				return newError(err)
+				return nil, err
 			}
-			arr = append(arr, subResult)
+			arr = append(arr, v)
 		}
-		result.Set(name, arr)
+		return arr, nil
 	}
-	return nil
+
+	//try to find base type, which may be an array or an optional
+	if baseName, ok := t.GetBaseName(typ); ok {
+		return t.unpackAbiValue(dec, baseName, depth+1)
+	}
+
+	//try to unpack inner abi type
+	if _, ok := gBaseTypes[typ]; ok {
+		return t.unpackAbiStructField(dec, typ)
+	}
+
+	//try to unpack variant type
+	if v, ok := t.GetVariantType(typ); ok {
+		start := dec.Pos()
+		index, err := dec.UnpackVarUint32()
+		if err != nil {
+			return nil, err
+		}
+
+		if int(index) >= len(v.Types) {
+			return nil, dec.errorAt(start, "invalid variant index %d", index)
+		}
+		tp := v.Types[int(index)]
+		value, err := t.unpackAbiValue(dec, tp, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		return t.opts.variant(tp, value), nil
+	}
+
+	//try to unpack Abi struct
+	if subStruct := t.GetAbiStruct(typ); subStruct != nil {
+		subResult := orderedmap.New()
+		err := t.unpackAbiStruct(dec, typ, subResult, depth)
+		if err != nil {
+			return nil, newError(err)
+		}
+		return subResult, nil
+	}
+	return nil, dec.errorf("unknown type %s", typ)
 }
 
 func (t *ABI) PackAbiValue(enc *Encoder, typ string, abiValue JsonValue) error {
-	for i := range t.Types {
-		v := &t.Types[i]
-		if v.NewTypeName == typ {
-			typ = v.Type
-			break
+	return t.packAbiValue(enc, typ, abiValue, 0)
+}
+
+func (t *ABI) packAbiValue(enc *Encoder, typ string, abiValue JsonValue, depth int) error {
+	if depth > maxAbiTypeDepth {
+		return newErrorf("type %s is nested too deeply", typ)
+	}
+
+	//handle optional
+	if strings.HasSuffix(typ, "?") {
+		if abiValue.IsNull() {
+			enc.PackBool(false)
+			return nil
 		}
+		enc.PackBool(true)
+		return t.packAbiValue(enc, strings.TrimSuffix(typ, "?"), abiValue, depth+1)
 	}
 
-	switch v := abiValue.GetValue().(type) {
-	case string:
-		err := t.ParseAbiStringValue(enc, typ, v)
-		if err != nil {
-			return newError(err)
+	//handle array
+	if inner, size, ok := splitArrayType(typ); ok {
+		if abiValue.Kind() != JsonArray {
+			return newErrorf("invalid array value for type %s", typ)
 		}
-	case JsonValue:
-		err := t.PackAbiValue(enc, typ, v)
-		if err != nil {
-			return newError(err)
+		v, _ := abiValue.GetArray()
+		return t.packArrayAbiValue(enc, inner, size, v, depth)
+	}
+
+	if baseName, ok := t.GetBaseName(typ); ok {
+		return t.packAbiValue(enc, baseName, abiValue, depth+1)
+	}
+
+	if varType, ok := t.GetVariantType(typ); ok {
+		// either ["type", value] or {"type": "type", "value": value}
+		v, err := abiValue.GetArray()
+		if abiValue.Kind() == JsonObject {
+			innerType, err1 := abiValue.Get("type")
+			value, err2 := abiValue.Get("value")
+			v, err = []JsonValue{innerType, value}, err1
+			if err == nil {
+				err = err2
+			}
 		}
-	case []JsonValue:
-		if varType, ok := t.GetVariantType(typ); ok {
-			if len(v) != 2 {
-				return newErrorf("Invalid variant value %v", v)
+		if err != nil || len(v) != 2 {
+			return newErrorf("Invalid variant value %s", abiValue.String())
+		}
+		innerType, ok := v[0].GetStringValue()
+		if !ok {
+			return newErrorf("Invalid variant value %v", v)
+		}
+		for i, variantType := range varType.Types {
+			if variantType == innerType {
+				enc.PackVarUint32(uint32(i))
+				return t.packAbiValue(enc, variantType, v[1], depth+1)
 			}
-			innerType, ok := v[0].GetStringValue()
+		}
+		return newErrorf("type %s not found in variant %v", innerType, typ)
+	}
+
+	switch v := abiValue.GetValue().(type) {
+	case []JsonValue:
+		return newErrorf("invalid array value for type %s", typ)
+	case map[string]JsonValue:
+		if typ == "extended_asset" {
+			quantity, ok := v["quantity"]
 			if !ok {
-				return newErrorf("+++++Invalid variant value %v", v)
+				return newErrorf("missing field quantity")
 			}
-			found := false
-			for i, variantType := range varType.Types {
-				if variantType == innerType {
-					enc.PackUint8(uint8(i))
-					t.PackAbiValue(enc, variantType, v[1])
-					found = true
-					break
-				}
-			}
-			if !found {
-				return newErrorf("type %s not found in variant %v", innerType, typ)
+			contract, ok := v["contract"]
+			if !ok {
+				return newErrorf("missing field contract")
 			}
-		} else {
-			err := t.PackArrayAbiValue(enc, typ, v)
-			if err != nil {
-				return newError(err)
+			if err := t.packAbiValue(enc, "asset", quantity, depth+1); err != nil {
+				return err
 			}
+			return t.packAbiValue(enc, "name", contract, depth+1)
 		}
-	case map[string]JsonValue:
-		err := t.PackAbiStruct(enc, typ, v)
+		if typ == "asset" {
+			return packAssetObject(enc, abiValue)
+		}
+		err := t.packAbiStruct(enc, typ, v, depth)
 		if err != nil {
 			return newError(err)
 		}
 	default:
-		return newErrorf("Unsupported type %[1]T: %[1]s", v)
+		err := t.ParseAbiStringValue(enc, typ, abiValue.abiText())
+		if err != nil {
+			return newError(err)
+		}
 	}
 	return nil
 }
 
+// isZeroSized reports whether values of typ can pack to no bytes, which only
+// structs made of such types and of binary extensions do
+func (t *ABI) isZeroSized(typ string, depth int) bool {
+	if depth > maxAbiTypeDepth {
+		return false
+	}
+	if baseName, ok := t.GetBaseName(typ); ok {
+		return t.isZeroSized(baseName, depth+1)
+	}
+	s := t.GetAbiStruct(typ)
+	if s == nil {
+		return false
+	}
+	if s.Base != "" && !t.isZeroSized(s.Base, depth+1) {
+		return false
+	}
+	for _, f := range s.Fields {
+		if !strings.HasSuffix(f.Type, "$") && !t.isZeroSized(f.Type, depth+1) {
+			return false
+		}
+	}
+	return true
+}
+
 func (t *ABI) GetBaseName(structName string) (string, bool) {
 	for j := range t.Types {
 		s := &t.Types[j]
@@ -1041,3 +1164,36 @@ func (t *ABI) GetActionStructType(actionName string) string {
 	}
 	return ""
 }
+
+// GetActionResultType returns the return type of actionName, empty if the
+// action does not declare one
+func (t *ABI) GetActionResultType(actionName string) string {
+	for i := range t.ActionResults {
+		if t.ActionResults[i].Name == actionName {
+			return t.ActionResults[i].ResultType
+		}
+	}
+	return ""
+}
+
+// packAssetObject packs an asset given in the form produced by AssetObject
+func packAssetObject(enc *Encoder, v JsonValue) error {
+	amount, err := v.GetInt64("amount")
+	if err != nil {
+		return newErrorf("invalid asset value: %s", v.String())
+	}
+	precision, err := v.GetInt64("precision")
+	if err != nil || precision < 0 || precision > maxAssetPrecision {
+		return newErrorf("invalid asset value: %s", v.String())
+	}
+	code, err := v.GetString("symbol")
+	if err != nil || !IsSymbolValid(code) {
+		return newErrorf("invalid asset value: %s", v.String())
+	}
+	if !isAmountWithInRange(amount) {
+		return newErrorf("magnitude of asset amount must be less than 2^62: %s", v.String())
+	}
+	a := Asset{amount, NewSymbol(code, int(precision))}
+	enc.WriteBytes(a.Pack())
+	return nil
+}
@@ -1,13 +1,20 @@
 package uuoskit
 
 import (
+	"bytes"
 	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"io/ioutil"
 	"log"
 
 	"fmt"
+	"math/big"
+	"strings"
+	"sync"
 	"testing"
 
+	secp256k1 "github.com/armoniax/go-secp256k1"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -144,6 +151,13 @@ func TestPackAbiType(t *testing.T) {
 	AssertPackAbiValue(t, "int128", `"0x70680300000000000000000000000000"`, "00000000000000000000000000036870")
 	// "uint128"
 	AssertPackAbiValue(t, "uint128", `"0x70680300000000000000000000000000"`, "00000000000000000000000000036870")
+	AssertPackAbiValue(t, "uint128", `"340282366920938463463374607431768211455"`, "ffffffffffffffffffffffffffffffff")
+	AssertPackAbiValue(t, "uint128", "1", "01000000000000000000000000000000")
+	AssertPackAbiValue(t, "int128", "-1", "ffffffffffffffffffffffffffffffff")
+	AssertPackAbiValue(t, "int128", `"-170141183460469231731687303715884105728"`, "00000000000000000000000000000080")
+	AssertPackAbiValueError(t, "uint128", `"340282366920938463463374607431768211456"`, fmt.Errorf("invalid uint128, value: 340282366920938463463374607431768211456"))
+	AssertPackAbiValueError(t, "uint128", "-1", fmt.Errorf("invalid uint128, value: -1"))
+	AssertPackAbiValueError(t, "int128", `"170141183460469231731687303715884105728"`, fmt.Errorf("invalid int128, value: 170141183460469231731687303715884105728"))
 	// "varint32"
 	AssertPackAbiValue(t, "varint32", "128", "8002")
 	// "varuint32"
@@ -156,12 +170,20 @@ func TestPackAbiType(t *testing.T) {
 
 	// "float128"
 	AssertPackAbiValue(t, "float128", `"0x70680300000000000000000000000000"`, "70680300000000000000000000000000")
+	AssertPackAbiValue(t, "float128", "1", "0000000000000000000000000000ff3f")
+	AssertPackAbiValue(t, "float128", `"-2.5"`, "000000000000000000000000004000c0")
+	AssertPackAbiValueError(t, "float128", `"abc"`, fmt.Errorf("invalid float128, value: abc"))
 
 	// "time_point"
 	AssertPackAbiValue(t, "time_point", `"2023-03-10T14:44:30.000"`, "80af7acc8cf60500")
 	// "time_point_sec"
 	AssertPackAbiValue(t, "time_point_sec", `"2023-03-10T14:44:30.000"`, "4e420b64")
+	AssertPackAbiValue(t, "time_point_sec", `"2023-03-10T14:44:30Z"`, "4e420b64")
+	AssertPackAbiValue(t, "time_point_sec", `"2023-03-10T22:44:30+08:00"`, "4e420b64")
+	AssertPackAbiValueError(t, "time_point_sec", `"1969-12-31T23:59:59"`, fmt.Errorf("time 1969-12-31T23:59:59 out of range for time_point_sec"))
 	// "block_timestamp_type"
+	AssertPackAbiValue(t, "block_timestamp_type", `"2000-01-01T00:00:01.000"`, "02000000")
+	AssertPackAbiValue(t, "block_timestamp_type", `"2023-03-10T14:44:30.500"`, "9dfd3b57")
 	// "name"
 	AssertPackAbiValue(t, "name", `"hello"`, "00000000001aa36a")
 
@@ -801,3 +823,809 @@ func TestVariant(t *testing.T) {
 	}
 	t.Logf("%s", string(r))
 }
+
+func TestValidateABI(t *testing.T) {
+	assert := assert.New(t)
+
+	abi := &ABI{}
+	err := json.Unmarshal([]byte(eosioTokenAbi), abi)
+	assert.Nil(err)
+	assert.Empty(abi.Validate())
+
+	raw, err := ioutil.ReadFile("./data/atomicassets.abi")
+	assert.Nil(err)
+	abi = &ABI{}
+	err = json.Unmarshal(raw, abi)
+	assert.Nil(err)
+	assert.False(HasABIErrors(abi.Validate()))
+
+	bad := `{
+		"version": "eosio::abi/1.1",
+		"types": [
+			{"new_type_name": "loop1", "type": "loop2"},
+			{"new_type_name": "loop2", "type": "loop1"},
+			{"new_type_name": "name", "type": "uint64"}
+		],
+		"structs": [
+			{"name": "a", "base": "b", "fields": []},
+			{"name": "b", "base": "a", "fields": []},
+			{"name": "c", "base": "", "fields": [
+				{"name": "x", "type": "unknown"},
+				{"name": "x", "type": "uint8$[]"},
+				{"name": "y", "type": "uint8$"},
+				{"name": "z", "type": "uint8"},
+				{"name": "w", "type": "uint8??"}
+			]},
+			{"name": "c", "base": "", "fields": []}
+		],
+		"actions": [
+			{"name": "Bad", "type": "c", "ricardian_contract": ""},
+			{"name": "ok", "type": "nostruct", "ricardian_contract": ""}
+		],
+		"tables": [
+			{"name": "tbl", "type": "missing", "index_type": "i64", "key_names": [], "key_types": []}
+		],
+		"variants": [
+			{"name": "v", "types": ["uint8", "nothing"]}
+		]
+	}`
+	abi = &ABI{}
+	err = json.Unmarshal([]byte(bad), abi)
+	assert.Nil(err)
+	diags := abi.Validate()
+	assert.True(HasABIErrors(diags))
+
+	paths := make(map[string]string)
+	for _, d := range diags {
+		paths[d.Path] = d.Message
+	}
+	expected := []string{
+		"types[0].type",
+		"types[2].new_type_name",
+		"structs[0].base",
+		"structs[2].fields[0].type",
+		"structs[2].fields[1].name",
+		"structs[2].fields[1].type",
+		"structs[2].fields[3].type",
+		"structs[2].fields[4].type",
+		"structs[3].name",
+		"actions[0].name",
+		"actions[1].type",
+		"tables[0].type",
+		"variants[0].types[1]",
+	}
+	for _, path := range expected {
+		_, ok := paths[path]
+		assert.True(ok, "missing diagnostic for %s", path)
+	}
+
+	s := NewABISerializer()
+	s.SetValidateABI(true)
+	err = s.SetContractABI("bad", []byte(bad))
+	assert.NotNil(err)
+	assert.False(s.IsAbiCached("bad"))
+	err = s.SetContractABI("token", []byte(eosioTokenAbi))
+	assert.Nil(err)
+}
+
+func TestDiffABI(t *testing.T) {
+	assert := assert.New(t)
+
+	oldAbi := &ABI{
+		Version: "eosio::abi/1.1",
+		Types:   []ABIType{{NewTypeName: "account_name", Type: "name"}},
+		Structs: []ABIStruct{
+			{Name: "transfer", Fields: []ABIStructField{{"from", "account_name"}, {"to", "account_name"}, {"quantity", "asset"}}},
+			{Name: "account", Fields: []ABIStructField{{"balance", "asset"}, {"owner", "name"}}},
+			{Name: "old", Fields: []ABIStructField{{"a", "uint8"}}},
+		},
+		Actions:  []ABIAction{{Name: "transfer", Type: "transfer"}, {Name: "old", Type: "old"}},
+		Tables:   []ABITable{{Name: "accounts", Type: "account", IndexType: "i64"}},
+		Variants: []VariantDef{{Name: "v", Types: []string{"uint8", "string"}}},
+	}
+
+	newAbi := &ABI{
+		Version: "eosio::abi/1.1",
+		Types:   []ABIType{},
+		Structs: []ABIStruct{
+			{Name: "transfer", Fields: []ABIStructField{{"from", "name"}, {"to", "name"}, {"quantity", "asset"}, {"memo", "string$"}}},
+			{Name: "account", Fields: []ABIStructField{{"owner", "name"}, {"balance", "asset"}}},
+		},
+		Actions:  []ABIAction{{Name: "transfer", Type: "transfer"}},
+		Tables:   []ABITable{{Name: "accounts", Type: "account", IndexType: "i64"}},
+		Variants: []VariantDef{{Name: "v", Types: []string{"uint8", "string", "asset"}}},
+	}
+
+	diff := DiffABI(oldAbi, newAbi)
+	t.Log(diff.String())
+	assert.True(diff.IsBreaking())
+
+	changes := make(map[string]ABIChange)
+	for _, c := range diff.Changes {
+		changes[c.Path] = c
+	}
+	assert.False(changes["structs.transfer.fields.from.type"].Breaking)
+	assert.False(changes["structs.transfer.fields.memo"].Breaking)
+	assert.True(changes["structs.account.fields.owner"].Breaking)
+	assert.True(changes["structs.account.fields.owner.type"].Breaking)
+	assert.True(changes["actions.old"].Breaking)
+	assert.False(changes["structs.old"].Breaking)
+	assert.False(changes["types.account_name"].Breaking)
+	assert.False(changes["variants.v"].Breaking)
+
+	compatible := &ABI{
+		Structs: []ABIStruct{
+			{Name: "transfer", Fields: []ABIStructField{{"from", "account_name"}, {"to", "account_name"}, {"quantity", "asset"}, {"memo", "string$"}}},
+		},
+		Types: []ABIType{{NewTypeName: "account_name", Type: "name"}},
+	}
+	oldAbi.Structs = oldAbi.Structs[:1]
+	oldAbi.Actions = nil
+	oldAbi.Tables = nil
+	oldAbi.Variants = nil
+	diff = DiffABI(oldAbi, compatible)
+	assert.False(diff.IsBreaking(), diff.String())
+	assert.Equal(1, len(diff.Changes))
+
+	// an extension is only compatible at the end of the packed data
+	extended := []ABIStructField{{"a", "uint8"}, {"b", "uint8$"}}
+	embeddings := map[string][]ABIStruct{
+		"base of":      {{Name: "outer", Base: "inner", Fields: []ABIStructField{{"c", "uint8"}}}},
+		"array of":     {{Name: "outer", Fields: []ABIStructField{{"c", "inner[]"}}}},
+		"optional":     {{Name: "outer", Fields: []ABIStructField{{"c", "inner?"}}}},
+		"middle field": {{Name: "outer", Fields: []ABIStructField{{"c", "inner"}, {"d", "uint8"}}}},
+		"nested middle field": {
+			{Name: "outer", Fields: []ABIStructField{{"c", "middle"}, {"d", "uint8"}}},
+			{Name: "middle", Fields: []ABIStructField{{"e", "uint8"}, {"f", "inner$"}}},
+		},
+		"trailing field": {
+			{Name: "outer", Fields: []ABIStructField{{"c", "uint8"}, {"d", "middle"}}},
+			{Name: "middle", Base: "inner", Fields: []ABIStructField{}},
+		},
+	}
+	for name, structs := range embeddings {
+		oldAbi := &ABI{
+			Structs: append([]ABIStruct{{Name: "inner", Fields: extended[:1]}}, structs...),
+			Tables:  []ABITable{{Name: "rows", Type: "outer", IndexType: "i64"}},
+		}
+		newAbi := &ABI{
+			Structs: append([]ABIStruct{{Name: "inner", Fields: extended}}, structs...),
+			Tables:  oldAbi.Tables,
+		}
+		diff := DiffABI(oldAbi, newAbi)
+		assert.Equal(1, len(diff.Changes), name)
+		assert.Equal(name != "trailing field", diff.IsBreaking(), "%s: %s", name, diff.String())
+	}
+	variantAbi := &ABI{
+		Structs:  []ABIStruct{{Name: "inner", Fields: extended}},
+		Variants: []VariantDef{{Name: "v", Types: []string{"uint8", "inner"}}},
+	}
+	diff = DiffABI(&ABI{Structs: []ABIStruct{{Name: "inner", Fields: extended[:1]}}, Variants: variantAbi.Variants}, variantAbi)
+	assert.True(diff.IsBreaking())
+	assert.Equal("[breaking] added field structs.inner.fields.b ( => uint8$): binary extension appended, "+
+		"but inner is an alternative of variant v", diff.Changes[0].String())
+
+	s := NewABISerializer()
+	diff, err := s.DiffContractABI("eosio.token", []byte(eosioTokenAbi))
+	assert.Nil(err)
+	assert.Empty(diff.Changes)
+}
+
+var gNestedAbi = `{
+		"version": "eosio::abi/1.1",
+		"types": [
+			{"new_type_name": "assets", "type": "asset[]"},
+			{"new_type_name": "account_name", "type": "name"}
+		],
+		"structs": [
+			{"name": "point", "base": "", "fields": [
+				{"name": "x", "type": "int32"},
+				{"name": "y", "type": "int32"}
+			]},
+			{"name": "test", "base": "", "fields": [
+				{"name": "a", "type": "MyVariant[]"},
+				{"name": "b", "type": "uint8?[]"},
+				{"name": "c", "type": "string[]?"},
+				{"name": "d", "type": "string[]?"},
+				{"name": "e", "type": "uint16[][]"},
+				{"name": "f", "type": "assets"},
+				{"name": "g", "type": "point?"},
+				{"name": "h", "type": "point[]"},
+				{"name": "i", "type": "account_name[2]"},
+				{"name": "j", "type": "extended_asset"}
+			]}
+		],
+		"actions": [{"name": "test", "type": "test", "ricardian_contract": ""}],
+		"tables": [],
+		"ricardian_clauses": [],
+		"variants": [
+			{"name": "MyVariant", "types": ["uint64", "asset", "point", "string[]"]}
+		],
+		"abi_extensions": [],
+		"error_messages": []
+	}`
+
+var gNestedArgs = `{"a":[["uint64",10],["asset","1.0000 EOS"],["point",{"x":1,"y":-1}],["string[]",["a","b"]]],"b":[1,null,3],"c":null,"d":["hello"],"e":[[1,2],[],[3]],"f":["1.0000 EOS","2.0000 EOS"],"g":{"x":5,"y":6},"h":[{"x":1,"y":2}],"i":["alice","bob"],"j":{"quantity":"1.0000 EOS","contract":"eosio.token"}}`
+
+func TestNestedAbiTypes(t *testing.T) {
+	assert := assert.New(t)
+	abi := gNestedAbi
+	ser := NewABISerializer()
+	ser.SetValidateABI(true)
+	err := ser.SetContractABI("test", []byte(abi))
+	assert.Nil(err)
+
+	args := gNestedArgs
+	r, err := ser.PackActionArgs("test", "test", args)
+	assert.Nil(err, "%v", err)
+
+	r2, err := ser.UnpackActionArgs("test", "test", r)
+	assert.Nil(err)
+	assert.Equal(args, string(r2))
+
+	args = `{"a":[],"b":[],"c":null,"d":null,"e":[],"f":[],"g":null,"h":[],"i":["alice"],"j":{"quantity":"1.0000 EOS","contract":"eosio.token"}}`
+	_, err = ser.PackActionArgs("test", "test", args)
+	assert.NotNil(err)
+}
+
+func TestCompiledABI(t *testing.T) {
+	assert := assert.New(t)
+	ser := NewABISerializer()
+	err := ser.SetContractABI("test", []byte(gNestedAbi))
+	assert.Nil(err)
+
+	packed, err := ser.PackActionArgs("test", "test", gNestedArgs)
+	assert.Nil(err)
+
+	expected, err := ser.UnpackActionArgs("test", "test", packed)
+	assert.Nil(err)
+
+	var buf bytes.Buffer
+	err = ser.UnpackActionArgsTo(&buf, "test", "test", packed)
+	assert.Nil(err)
+	assert.Equal(string(expected), buf.String())
+
+	buf.Reset()
+	err = ser.UnpackAbiTypeTo(&buf, "test", "point", []byte{1, 0, 0, 0, 0xff, 0xff, 0xff, 0xff})
+	assert.Nil(err)
+	assert.Equal(`{"x":1,"y":-1}`, buf.String())
+
+	memo := `{"from":"alice","to":"bob","quantity":"1.0000 EOS","memo":"<a href=\"x\"> \t\u0001&é"}`
+	packed, err = ser.PackActionArgs("eosio.token", "transfer", memo)
+	assert.Nil(err)
+	expected, err = ser.UnpackActionArgs("eosio.token", "transfer", packed)
+	assert.Nil(err)
+	buf.Reset()
+	err = ser.UnpackActionArgsTo(&buf, "eosio.token", "transfer", packed)
+	assert.Nil(err)
+	assert.Equal(string(expected), buf.String())
+
+	// large values are written in chunks while they are decoded
+	points := PackVarUint32(2000)
+	for i := 0; i < 2000; i++ {
+		points = append(points, 1, 0, 0, 0, 2, 0, 0, 0)
+	}
+	compiled, err := ser.GetCompiledABI("test")
+	assert.Nil(err)
+	expected, err = compiled.AppendJSON(nil, "point[]", points)
+	assert.Nil(err)
+	w := &chunkWriter{}
+	err = ser.UnpackAbiTypeTo(w, "test", "point[]", points)
+	assert.Nil(err)
+	assert.Equal(string(expected), w.buf.String())
+	assert.Greater(w.writes, 2)
+	for _, n := range w.sizes {
+		assert.LessOrEqual(n, jsonChunkSize+256)
+	}
+	w = &chunkWriter{fail: true}
+	err = ser.UnpackAbiTypeTo(w, "test", "point[]", points)
+	assert.Equal(errChunkWriter, err)
+	assert.Equal(1, w.writes)
+
+	abi := &ABI{}
+	err = json.Unmarshal([]byte(`{"structs":[{"name":"a","base":"","fields":[{"name":"x","type":"nothing"}]}]}`), abi)
+	assert.Nil(err)
+	_, err = abi.Compile()
+	assert.NotNil(err)
+}
+
+var errChunkWriter = errors.New("write failed")
+
+// chunkWriter records the size of every write
+type chunkWriter struct {
+	buf    bytes.Buffer
+	writes int
+	sizes  []int
+	fail   bool
+}
+
+func (w *chunkWriter) Write(p []byte) (int, error) {
+	w.writes++
+	w.sizes = append(w.sizes, len(p))
+	if w.fail {
+		return 0, errChunkWriter
+	}
+	return w.buf.Write(p)
+}
+
+func benchmarkTransferData(b *testing.B, ser *ABISerializer) []byte {
+	args := `{"from": "helloworld11", "to": "eosio.token", "quantity": "1.0000 EOS", "memo": "transfer from alice"}`
+	packed, err := ser.PackActionArgs("eosio.token", "transfer", args)
+	if err != nil {
+		b.Fatal(err)
+	}
+	return packed
+}
+
+func BenchmarkUnpackActionArgs(b *testing.B) {
+	ser := NewABISerializer()
+	packed := benchmarkTransferData(b, ser)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := ser.UnpackActionArgs("eosio.token", "transfer", packed); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkUnpackActionArgsTo(b *testing.B) {
+	ser := NewABISerializer()
+	packed := benchmarkTransferData(b, ser)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := ser.UnpackActionArgsTo(ioutil.Discard, "eosio.token", "transfer", packed); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkUnpackNested(b *testing.B) {
+	ser := NewABISerializer()
+	ser.SetContractABI("test", []byte(gNestedAbi))
+	packed, err := ser.PackActionArgs("test", "test", gNestedArgs)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := ser.UnpackActionArgs("test", "test", packed); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkCompiledAppendJSONNested(b *testing.B) {
+	ser := NewABISerializer()
+	ser.SetContractABI("test", []byte(gNestedAbi))
+	packed, err := ser.PackActionArgs("test", "test", gNestedArgs)
+	if err != nil {
+		b.Fatal(err)
+	}
+	c, err := ser.GetCompiledABI("test")
+	if err != nil {
+		b.Fatal(err)
+	}
+	buf := make([]byte, 0, 1024)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		buf, err = c.AppendJSON(buf[:0], "test", packed)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestConcurrentABISerializer(t *testing.T) {
+	ser := NewABISerializer()
+	args := `{"from": "alice", "to": "bob", "quantity": "1.0000 EOS", "memo": "hello"}`
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				contract := fmt.Sprintf("token%d", j%4)
+				if j%10 == 0 {
+					ser.SetContractABI(contract, []byte(eosioTokenAbi))
+				}
+				ser.IsAbiCached(contract)
+				packed, err := ser.PackActionArgs("eosio.token", "transfer", args)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				if _, err := ser.UnpackActionArgs("eosio.token", "transfer", packed); err != nil {
+					t.Error(err)
+					return
+				}
+				if err := ser.UnpackActionArgsTo(ioutil.Discard, "eosio.token", "transfer", packed); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestBigIntTypes(t *testing.T) {
+	max128, _ := new(big.Int).SetString("340282366920938463463374607431768211455", 10)
+	u, err := NewUint128(max128)
+	assert.Nil(t, err)
+	assert.Equal(t, max128.String(), u.String())
+	_, err = u.Add(Uint128{1})
+	assert.NotNil(t, err)
+	_, err = Uint128{}.Sub(Uint128{1})
+	assert.NotNil(t, err)
+	_, err = u.Div(Uint128{})
+	assert.NotNil(t, err)
+
+	var a, b Uint128
+	a.SetUint64(6)
+	b.SetUint64(4)
+	r, err := a.Mul(b)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(24), r.Uint64())
+	assert.Equal(t, 1, a.Cmp(b))
+
+	i := Int128{}
+	i.SetInt64(-7)
+	assert.Equal(t, "-7", i.String())
+	q, err := i.Div(Int128{2})
+	assert.Nil(t, err)
+	assert.Equal(t, "-3", q.String())
+	assert.Nil(t, i.SetString("0xffffffffffffffffffffffffffffffff"))
+	assert.Equal(t, "-1", i.String())
+
+	var w Uint256
+	assert.Nil(t, w.SetString("115792089237316195423570985008687907853269984665640564039457584007913129639935"))
+	_, err = w.Add(Uint256{1})
+	assert.NotNil(t, err)
+	assert.NotNil(t, w.SetString("-1"))
+
+	var v struct {
+		A Uint128
+		B Int128
+		C Uint256
+	}
+	assert.Nil(t, json.Unmarshal([]byte(`{"A": "12345678901234567890123", "B": -5, "C": "0x0100"}`), &v))
+	assert.Equal(t, "12345678901234567890123", v.A.String())
+	assert.Equal(t, "-5", v.B.String())
+	assert.Equal(t, "256", v.C.String())
+	bs, err := json.Marshal(v)
+	assert.Nil(t, err)
+	assert.Equal(t, `{"A":"12345678901234567890123","B":"-5","C":"256"}`, string(bs))
+	assert.NotNil(t, json.Unmarshal([]byte(`{"A": "-1"}`), &v))
+}
+
+func TestUnpackDecimalInt128(t *testing.T) {
+	s := NewABISerializer()
+	s.SetContractABI("test", []byte(fmt.Sprintf(gAbi, "int128")))
+	packed, err := s.PackAbiType("test", "test", `{"t": "-12345678901234567890"}`)
+	assert.Nil(t, err)
+
+	r, err := s.UnpackAbiType("test", "test", packed)
+	assert.Nil(t, err)
+	assert.Equal(t, `{"t":"0xffffffffffffffff54ab567314e0f52e"}`, string(r))
+
+	s.SetDecimalInt128(true)
+	r, err = s.UnpackAbiType("test", "test", packed)
+	assert.Nil(t, err)
+	assert.Equal(t, `{"t":"-12345678901234567890"}`, string(r))
+
+	var buf bytes.Buffer
+	assert.Nil(t, s.UnpackAbiTypeTo(&buf, "test", "test", packed))
+	assert.Equal(t, `{"t":"-12345678901234567890"}`, buf.String())
+
+	s.SetContractABI("test2", []byte(fmt.Sprintf(gAbi, "uint128")))
+	packed, err = s.PackAbiType("test2", "test", `{"t": "340282366920938463463374607431768211455"}`)
+	assert.Nil(t, err)
+	r, err = s.UnpackAbiType("test2", "test", packed)
+	assert.Nil(t, err)
+	assert.Equal(t, `{"t":"340282366920938463463374607431768211455"}`, string(r))
+}
+
+func float128FromBits(hi uint64, lo uint64) Float128 {
+	f := Float128{}
+	f.setBits(hi, lo)
+	return f
+}
+
+func TestFloat128(t *testing.T) {
+	cases := []struct {
+		value  string
+		hi, lo uint64
+	}{
+		{"0", 0, 0},
+		{"-0", 1 << 63, 0},
+		{"1", 0x3fff000000000000, 0},
+		{"0.1", 0x3ffb999999999999, 0x999999999999999a},
+		{"-2.5", 0xc000400000000000, 0},
+		{"1.189731495357231765085759326628007016e+4932", 0x7ffeffffffffffff, 0xffffffffffffffff},
+		{"3.362103143112093506262677817321752603e-4932", 0x0001000000000000, 0},
+		{"6.475175119438025110924438958227646552e-4966", 0, 1},
+		{"inf", 0x7fff000000000000, 0},
+		{"-inf", 0xffff000000000000, 0},
+		{"1e5000", 0x7fff000000000000, 0},
+		{"1e-5000", 0, 0},
+	}
+	for _, c := range cases {
+		f := Float128{}
+		assert.Nil(t, f.SetString(c.value), c.value)
+		assert.Equal(t, float128FromBits(c.hi, c.lo), f, c.value)
+	}
+
+	f := Float128{}
+	assert.Nil(t, f.SetString("nan"))
+	assert.True(t, f.IsNaN())
+	assert.Equal(t, "nan", f.String())
+	_, err := f.BigFloat()
+	assert.NotNil(t, err)
+	assert.NotNil(t, f.SetString("1.2.3"))
+
+	// rounding to nearest even at the last significand bit
+	one := float128FromBits(0x3fff000000000000, 0)
+	assert.Nil(t, f.SetString("1.0000000000000000000000000000000000962"))
+	assert.Equal(t, one, f)
+	assert.Nil(t, f.SetString("1.0000000000000000000000000000000001926"))
+	assert.Equal(t, float128FromBits(0x3fff000000000000, 1), f)
+
+	bits := [][2]uint64{
+		{0x3fff000000000000, 0},
+		{0x3ffb999999999999, 0x999999999999999a},
+		{0x400921fb54442d18, 0x469898cc51701b84},
+		{0xbfff123456789abc, 0xdef0123456789abc},
+		{0x0000800000000000, 0x1},
+		{0x7ffeffffffffffff, 0xffffffffffffffff},
+	}
+	for _, b := range bits {
+		f := float128FromBits(b[0], b[1])
+		g := Float128{}
+		assert.Nil(t, g.SetString(f.String()), f.String())
+		assert.Equal(t, f, g, f.String())
+
+		bf, err := f.BigFloat()
+		assert.Nil(t, err)
+		g.SetBigFloat(bf)
+		assert.Equal(t, f, g)
+	}
+
+	assert.Equal(t, "0.1", float128FromBits(0x3ffb999999999999, 0x999999999999999a).String())
+	f.SetBigFloat(big.NewFloat(0.5))
+	assert.Equal(t, "0.5", f.String())
+
+	var v struct{ F Float128 }
+	assert.Nil(t, json.Unmarshal([]byte(`{"F": 1.5}`), &v))
+	assert.Equal(t, "1.5", v.F.String())
+	assert.Nil(t, json.Unmarshal([]byte(`{"F": "0x0000000000000000000000000000ff3f"}`), &v))
+	assert.Equal(t, "1", v.F.String())
+	bs, err := json.Marshal(v)
+	assert.Nil(t, err)
+	assert.Equal(t, `{"F":"1"}`, string(bs))
+}
+
+func TestUnpackDecimalFloat128(t *testing.T) {
+	s := NewABISerializer()
+	s.SetContractABI("test", []byte(fmt.Sprintf(gAbi, "float128")))
+	packed, err := s.PackAbiType("test", "test", `{"t": "-0.1"}`)
+	assert.Nil(t, err)
+
+	r, err := s.UnpackAbiType("test", "test", packed)
+	assert.Nil(t, err)
+	assert.Equal(t, `{"t":"0x9a99999999999999999999999999fbbf"}`, string(r))
+
+	s.SetDecimalFloat128(true)
+	r, err = s.UnpackAbiType("test", "test", packed)
+	assert.Nil(t, err)
+	assert.Equal(t, `{"t":"-0.1"}`, string(r))
+
+	repacked, err := s.PackAbiType("test", "test", string(r))
+	assert.Nil(t, err)
+	assert.Equal(t, packed, repacked)
+}
+
+func TestUnpackOptions(t *testing.T) {
+	abi := `{
+		"version": "eosio::abi/1.1",
+		"structs": [
+			{"name": "test", "base": "", "fields": [
+				{"name": "i", "type": "int64"},
+				{"name": "u", "type": "uint64"},
+				{"name": "k", "type": "public_key"},
+				{"name": "s", "type": "symbol"},
+				{"name": "a", "type": "asset"},
+				{"name": "v", "type": "MyVariant[]"}
+			]}
+		],
+		"actions": [{"name": "test", "type": "test", "ricardian_contract": ""}],
+		"variants": [{"name": "MyVariant", "types": ["uint64", "asset"]}]
+	}`
+	s := NewABISerializer()
+	assert.Nil(t, s.SetContractABI("test", []byte(abi)))
+
+	pub, err := secp256k1.NewPublicKeyFromBase58("AM6AjF6hvF7GSuSd4sCgfPKq5uWaXvGM2aQtEUCwmEHygQaqxBSV")
+	assert.Nil(t, err)
+	args := fmt.Sprintf(`{"i": -9007199254740993, "u": 18446744073709551615, "k": "%s", "s": "4,EOS",`+
+		`"a": "-1.2345 EOS", "v": [["uint64", 10], ["asset", "1.0000 EOS"]]}`, pub.StringAM())
+	packed, err := s.PackActionArgs("test", "test", args)
+	assert.Nil(t, err)
+
+	check := func(expected string) {
+		r, err := s.UnpackActionArgs("test", "test", packed)
+		assert.Nil(t, err)
+		assert.Equal(t, expected, string(r))
+
+		var buf bytes.Buffer
+		assert.Nil(t, s.UnpackAbiTypeTo(&buf, "test", "test", packed))
+		assert.Equal(t, expected, buf.String())
+
+		repacked, err := s.PackActionArgs("test", "test", expected)
+		assert.Nil(t, err)
+		assert.Equal(t, packed, repacked)
+	}
+
+	check(fmt.Sprintf(`{"i":-9007199254740993,"u":18446744073709551615,"k":"%s","s":"EOS,4",`+
+		`"a":"-1.2345 EOS","v":[["uint64",10],["asset","1.0000 EOS"]]}`, pub.StringAM()))
+
+	s.SetUnpackOptions(UnpackOptions{
+		Int64AsString:   true,
+		VariantFormat:   VariantObject,
+		PublicKeyFormat: PublicKeyK1,
+		AssetFormat:     AssetObject,
+		SymbolFormat:    SymbolPrecisionFirst,
+	})
+	check(fmt.Sprintf(`{"i":"-9007199254740993","u":"18446744073709551615","k":"%s","s":"4,EOS",`+
+		`"a":{"amount":"-12345","precision":4,"symbol":"EOS"},`+
+		`"v":[{"type":"uint64","value":"10"},{"type":"asset","value":{"amount":"10000","precision":4,"symbol":"EOS"}}]}`,
+		pub.String()))
+
+	s.SetUnpackOptions(UnpackOptions{LegacyKeyPrefix: "EOS"})
+	check(fmt.Sprintf(`{"i":-9007199254740993,"u":18446744073709551615,"k":"EOS%s","s":"EOS,4",`+
+		`"a":"-1.2345 EOS","v":[["uint64",10],["asset","1.0000 EOS"]]}`, pub.StringAM()[2:]))
+	assert.Equal(t, "EOS", s.GetUnpackOptions().LegacyKeyPrefix)
+}
+
+func TestActionResults(t *testing.T) {
+	s := NewABISerializer()
+	abi := `{"version":"eosio::abi/1.2","types":[],"structs":[{"name":"get","base":"","fields":[{"name":"n","type":"uint8"}]}],` +
+		`"actions":[{"name":"get","type":"get","ricardian_contract":""}],"tables":[],"ricardian_clauses":[],` +
+		`"error_messages":[],"abi_extensions":[],"variants":[],"action_results":[{"name":"get","result_type":"uint64[]"}]}`
+	assert.Nil(t, s.SetContractABI("test", []byte(abi)))
+	r, err := s.UnpackActionResult("test", "get", []byte{2, 1, 0, 0, 0, 0, 0, 0, 0, 2, 0, 0, 0, 0, 0, 0, 0})
+	assert.Nil(t, err)
+	assert.Equal(t, `[1,2]`, string(r))
+	_, err = s.UnpackActionResult("test", "other", []byte{})
+	assert.NotNil(t, err)
+
+	// action_results is packed as a binary extension
+	bin, err := s.PackABI(abi)
+	assert.Nil(t, err)
+	unpacked, err := s.UnpackABI(bin)
+	assert.Nil(t, err)
+	assert.Equal(t, abi, unpacked)
+	noResults := strings.Replace(abi, `,"action_results":[{"name":"get","result_type":"uint64[]"}]`, "", 1)
+	bin2, err := s.PackABI(noResults)
+	assert.Nil(t, err)
+	assert.Equal(t, len(bin)-1-8-1-len("uint64[]"), len(bin2))
+	unpacked, err = s.UnpackABI(bin2)
+	assert.Nil(t, err)
+	assert.Equal(t, noResults, unpacked)
+
+	bad := &ABI{}
+	assert.Nil(t, json.Unmarshal([]byte(strings.Replace(abi, `"result_type":"uint64[]"`, `"result_type":"missing"`, 1)), bad))
+	assert.True(t, HasABIErrors(bad.Validate()))
+	bad = &ABI{}
+	assert.Nil(t, json.Unmarshal([]byte(strings.Replace(abi, `{"name":"get","result_type"`, `{"name":"put","result_type"`, 1)), bad))
+	assert.True(t, HasABIErrors(bad.Validate()))
+}
+
+func TestDecodeErrors(t *testing.T) {
+	s := NewABISerializer()
+	packed, err := s.PackActionArgs("eosio.token", "transfer",
+		`{"from": "alice", "to": "bob", "quantity": "1.0000 EOS", "memo": "hello"}`)
+	assert.Nil(t, err)
+
+	_, err = s.UnpackActionArgs("eosio.token", "transfer", packed[:17])
+	assert.Equal(t, "transfer.quantity: unexpected EOF at 17", err.Error())
+	decodeErr, ok := err.(*DecodeError)
+	assert.True(t, ok)
+	assert.Equal(t, 17, decodeErr.Offset)
+	assert.Equal(t, "transfer.quantity", decodeErr.Path)
+	var buf bytes.Buffer
+	err = s.UnpackActionArgsTo(&buf, "eosio.token", "transfer", packed[:17])
+	assert.Equal(t, "transfer.quantity: unexpected EOF at 17", err.Error())
+
+	// memo declared longer than the data
+	truncated := append([]byte{}, packed[:32]...)
+	truncated = append(truncated, 100)
+	_, err = s.UnpackActionArgs("eosio.token", "transfer", truncated)
+	assert.Equal(t, "transfer.memo: unexpected EOF at 33", err.Error())
+
+	assert.Nil(t, s.SetContractABI("fuzz", []byte(fuzzAbi)))
+	tree := []byte{1, 0, 0, 0, 2, 2, 0, 0, 0, 0, 3, 0}
+	_, err = s.UnpackAbiType("fuzz", "node", tree)
+	assert.Equal(t, "node.children[1].value: unexpected EOF at 12", err.Error())
+	c, err := s.GetCompiledABI("fuzz")
+	assert.Nil(t, err)
+	_, err = c.AppendJSON(nil, "node", tree)
+	assert.Equal(t, "node.children[1].value: unexpected EOF at 12", err.Error())
+
+	// errors of types decoded with their own Decoder are rebased
+	dec := NewDecoder([]byte{1, 2, 3, 4})
+	_, err = dec.UnpackUint16()
+	assert.Nil(t, err)
+	_, err = dec.Unpack(&Asset{})
+	assert.Equal(t, "unexpected EOF at 4", err.Error())
+	tx := NewTransaction(0)
+	tx.AddAction(NewAction(NewName("eosio.token"), NewName("transfer"),
+		[]PermissionLevel{{NewName("alice"), NewName("active")}}, []byte{1, 2, 3}))
+	data := tx.Pack()
+	_, err = (&Transaction{}).Unpack(data[:len(data)-3])
+	assert.Equal(t, fmt.Sprintf("unexpected EOF at %d", len(data)-3), err.Error())
+}
+
+func TestDecoderOptions(t *testing.T) {
+	s := NewABISerializer()
+	assert.Nil(t, s.SetContractABI("fuzz", []byte(fuzzAbi)))
+	assert.Equal(t, DefaultDecoderOptions(), s.GetDecoderOptions())
+	node, err := s.PackAbiType("fuzz", "node",
+		`{"value": 1, "children": [{"value": 2, "children": [{"value": 3, "children": []}]}, {"value": 4, "children": []}]}`)
+	assert.Nil(t, err)
+	_, err = s.UnpackAbiType("fuzz", "node", node)
+	assert.Nil(t, err)
+
+	s.SetDecoderOptions(DecoderOptions{MaxArrayLength: 1})
+	assert.Equal(t, 1, s.GetDecoderOptions().MaxArrayLength)
+	assert.Equal(t, maxArrayAllocSize, s.GetDecoderOptions().MaxAllocSize)
+	_, err = s.UnpackAbiType("fuzz", "node", node)
+	assert.Equal(t, "node.children: array length 2 exceeds the limit of 1 at 4", err.Error())
+	c, err := s.GetCompiledABI("fuzz")
+	assert.Nil(t, err)
+	_, err = c.AppendJSON(nil, "node", node)
+	assert.Equal(t, "node.children: array length 2 exceeds the limit of 1 at 4", err.Error())
+
+	s.SetDecoderOptions(DecoderOptions{MaxDepth: 3})
+	_, err = s.UnpackAbiType("fuzz", "node", node)
+	assert.Equal(t, "node.children[0].children[0]: type node is nested too deeply at 10", err.Error())
+
+	s.SetDecoderOptions(DecoderOptions{MaxAllocSize: 4})
+	packed, err := s.PackActionArgs("eosio.token", "transfer",
+		`{"from": "alice", "to": "bob", "quantity": "1.0000 EOS", "memo": "hello"}`)
+	assert.Nil(t, err)
+	_, err = s.UnpackActionArgs("eosio.token", "transfer", packed)
+	assert.Equal(t, "transfer.memo: length 5 exceeds the limit of 4 at 32", err.Error())
+
+	dec := NewDecoderWithOptions(PackVarUint32(5), DecoderOptions{MaxAllocSize: 4})
+	_, err = dec.UnpackBytes()
+	assert.Equal(t, "length 5 exceeds the limit of 4 at 0", err.Error())
+	assert.Equal(t, maxAbiTypeDepth, dec.Options().MaxDepth)
+
+	// the options apply to the values nested in actions and transactions
+	action := NewAction(NewName("hello"), NewName("sayhello"),
+		[]PermissionLevel{{NewName("alice"), NewName("active")}, {NewName("bob"), NewName("active")}})
+	action.Data = make([]byte, 200)
+	dec = NewDecoderWithOptions(action.Pack(), DecoderOptions{MaxAllocSize: 100})
+	_, err = dec.Unpack(&Action{})
+	assert.Equal(t, "length 200 exceeds the limit of 100 at 49", err.Error())
+	dec = NewDecoderWithOptions(action.Pack(), DecoderOptions{MaxArrayLength: 1})
+	_, err = dec.UnpackAction()
+	assert.Equal(t, "array length 2 exceeds the limit of 1 at 16", err.Error())
+
+	tx := NewTransaction(0)
+	tx.AddAction(action)
+	dec = NewDecoderWithOptions(tx.Pack(), DecoderOptions{MaxAllocSize: 100})
+	_, err = dec.Unpack(&Transaction{})
+	assert.Equal(t, "length 200 exceeds the limit of 100 at 64", err.Error())
+	tx.AddAction(action)
+	tx.AddAction(action)
+	dec = NewDecoderWithOptions(tx.Pack(), DecoderOptions{MaxArrayLength: 2})
+	_, err = dec.Unpack(&Transaction{})
+	assert.Equal(t, "array length 3 exceeds the limit of 2 at 14", err.Error())
+	n, err := NewDecoder(tx.Pack()).Unpack(&Transaction{})
+	assert.Nil(t, err)
+	assert.Equal(t, len(tx.Pack()), n)
+}
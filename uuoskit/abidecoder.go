@@ -0,0 +1,621 @@
+package uuoskit
+
+import (
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/iancoleman/orderedmap"
+)
+
+type abiPlanKind int
+
+const (
+	abiPlanBuiltin abiPlanKind = iota
+	abiPlanStruct
+	abiPlanVariant
+	abiPlanArray
+	abiPlanOptional
+)
+
+type abiFieldPlan struct {
+	name      string
+	plan      *abiTypePlan
+	extension bool
+}
+
+type abiVariantPlan struct {
+	name string
+	plan *abiTypePlan
+}
+
+// abiTypePlan is a type of the ABI with aliases resolved, base structs
+// flattened and every referenced type looked up once.
+type abiTypePlan struct {
+	kind     abiPlanKind
+	name     string
+	elem     *abiTypePlan
+	size     int
+	fields   []abiFieldPlan
+	variants []abiVariantPlan
+}
+
+// CompiledABI holds precompiled type plans of an ABI, decoding with it
+// avoids the linear lookups and the intermediate maps of UnpackAbiType.
+// A CompiledABI is read only and can be shared between goroutines.
+type CompiledABI struct {
+	abi      *ABI
+	types    map[string]string
+	structs  map[string]*ABIStruct
+	variants map[string]*VariantDef
+	plans    map[string]*abiTypePlan
+	actions  map[string]*abiTypePlan
+	tables   map[string]*abiTypePlan
+}
+
+// Compile resolves every type referenced by the ABI into a type plan,
+// it fails on types that can not be resolved.
+func (t *ABI) Compile() (*CompiledABI, error) {
+	c := &CompiledABI{
+		abi:      t,
+		types:    make(map[string]string, len(t.Types)),
+		structs:  make(map[string]*ABIStruct, len(t.Structs)),
+		variants: make(map[string]*VariantDef, len(t.Variants)),
+		plans:    make(map[string]*abiTypePlan),
+		actions:  make(map[string]*abiTypePlan, len(t.Actions)),
+		tables:   make(map[string]*abiTypePlan, len(t.Tables)),
+	}
+	for i := range t.Types {
+		c.types[t.Types[i].NewTypeName] = t.Types[i].Type
+	}
+	for i := range t.Structs {
+		c.structs[t.Structs[i].Name] = &t.Structs[i]
+	}
+	for i := range t.Variants {
+		c.variants[t.Variants[i].Name] = &t.Variants[i]
+	}
+
+	for i := range t.Structs {
+		if _, err := c.plan(t.Structs[i].Name, 0); err != nil {
+			return nil, err
+		}
+	}
+	for i := range t.Variants {
+		if _, err := c.plan(t.Variants[i].Name, 0); err != nil {
+			return nil, err
+		}
+	}
+	for i := range t.Actions {
+		a := &t.Actions[i]
+		p, err := c.plan(a.Type, 0)
+		if err != nil {
+			return nil, err
+		}
+		c.actions[a.Name] = p
+	}
+	for i := range t.Tables {
+		tb := &t.Tables[i]
+		p, err := c.plan(tb.Type, 0)
+		if err != nil {
+			return nil, err
+		}
+		c.tables[tb.Name] = p
+	}
+	return c, nil
+}
+
+func (c *CompiledABI) plan(typ string, depth int) (*abiTypePlan, error) {
+	if p, ok := c.plans[typ]; ok {
+		return p, nil
+	}
+	if depth > maxAbiTypeDepth {
+		return nil, newErrorf("type %s is nested too deeply", typ)
+	}
+
+	if strings.HasSuffix(typ, "?") {
+		elem, err := c.plan(strings.TrimSuffix(typ, "?"), depth+1)
+		if err != nil {
+			return nil, err
+		}
+		p := &abiTypePlan{kind: abiPlanOptional, name: typ, elem: elem}
+		c.plans[typ] = p
+		return p, nil
+	}
+
+	if inner, size, ok := splitArrayType(typ); ok {
+		elem, err := c.plan(inner, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		p := &abiTypePlan{kind: abiPlanArray, name: typ, elem: elem, size: size}
+		c.plans[typ] = p
+		return p, nil
+	}
+
+	if baseName, ok := c.types[typ]; ok {
+		p, err := c.plan(baseName, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		c.plans[typ] = p
+		return p, nil
+	}
+
+	if _, ok := gBaseTypes[typ]; ok {
+		p := &abiTypePlan{kind: abiPlanBuiltin, name: typ}
+		c.plans[typ] = p
+		return p, nil
+	}
+
+	if v, ok := c.variants[typ]; ok {
+		// registered before resolving members to allow recursive types
+		p := &abiTypePlan{kind: abiPlanVariant, name: typ}
+		c.plans[typ] = p
+		p.variants = make([]abiVariantPlan, 0, len(v.Types))
+		for _, tp := range v.Types {
+			member, err := c.plan(tp, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			p.variants = append(p.variants, abiVariantPlan{tp, member})
+		}
+		return p, nil
+	}
+
+	if s, ok := c.structs[typ]; ok {
+		p := &abiTypePlan{kind: abiPlanStruct, name: typ}
+		c.plans[typ] = p
+		fields, err := c.structFields(s, depth)
+		if err != nil {
+			return nil, err
+		}
+		p.fields = fields
+		return p, nil
+	}
+	return nil, newErrorf("unknown type %s", typ)
+}
+
+func (c *CompiledABI) structFields(s *ABIStruct, depth int) ([]abiFieldPlan, error) {
+	fields := make([]abiFieldPlan, 0, len(s.Fields))
+	if s.Base != "" {
+		if depth > maxAbiTypeDepth {
+			return nil, newErrorf("base of struct %s is nested too deeply", s.Name)
+		}
+		baseName := s.Base
+		if name, ok := c.types[baseName]; ok {
+			baseName = name
+		}
+		base, ok := c.structs[baseName]
+		if !ok {
+			return nil, newErrorf("abi struct %s not found", s.Base)
+		}
+		baseFields, err := c.structFields(base, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		fields = append(fields, baseFields...)
+	}
+	for _, f := range s.Fields {
+		typ := f.Type
+		extension := strings.HasSuffix(typ, "$")
+		typ = strings.TrimSuffix(typ, "$")
+		p, err := c.plan(typ, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		fields = append(fields, abiFieldPlan{f.Name, p, extension})
+	}
+	return fields, nil
+}
+
+// ABIVisitor receives the values decoded by CompiledABI.Walk in depth first
+// order. Scalars are passed to Value with the same Go values UnpackAbiType
+// would put into its result. Returning an error aborts the walk.
+type ABIVisitor interface {
+	BeginStruct(typ string) error
+	Field(name string) error
+	EndStruct() error
+	BeginArray(length int) error
+	EndArray() error
+	BeginVariant(typ string) error
+	EndVariant() error
+	Null() error
+	Value(typ string, value interface{}) error
+}
+
+func (c *CompiledABI) lookup(typ string) (*abiTypePlan, error) {
+	if p, ok := c.plans[typ]; ok {
+		return p, nil
+	}
+	return nil, newErrorf("unknown type %s", typ)
+}
+
+// Walk decodes data as typ and reports every value to visitor.
+func (c *CompiledABI) Walk(typ string, data []byte, visitor ABIVisitor) error {
+	p, err := c.lookup(typ)
+	if err != nil {
+		return err
+	}
+	return c.walk(c.abi.newDecoder(data, typ), p, visitor, 0)
+}
+
+// WalkAction decodes the arguments of action and reports them to visitor.
+func (c *CompiledABI) WalkAction(action string, data []byte, visitor ABIVisitor) error {
+	p, ok := c.actions[action]
+	if !ok {
+		return newErrorf("unknown action %s", action)
+	}
+	return c.walk(c.abi.newDecoder(data, p.name), p, visitor, 0)
+}
+
+func (c *CompiledABI) walk(dec *Decoder, p *abiTypePlan, v ABIVisitor, depth int) error {
+	if depth > dec.opts.MaxDepth {
+		return dec.errorf("type %s is nested too deeply", p.name)
+	}
+	switch p.kind {
+	case abiPlanBuiltin:
+		value, err := c.abi.unpackAbiStructField(dec, p.name)
+		if err != nil {
+			return err
+		}
+		return v.Value(p.name, value)
+	case abiPlanOptional:
+		present, err := dec.UnpackBool()
+		if err != nil {
+			return newError(err)
+		}
+		if !present {
+			return v.Null()
+		}
+		return c.walk(dec, p.elem, v, depth+1)
+	case abiPlanArray:
+		start := dec.Pos()
+		count, err := dec.UnpackLength()
+		if err != nil {
+			return newError(err)
+		}
+		if p.size >= 0 && count != p.size {
+			return dec.errorAt(start, "array size mismatch, expected %d, got %d", p.size, count)
+		}
+		minSize := 1
+		if count > len(dec.Remains()) && c.abi.isZeroSized(p.elem.name, 0) {
+			minSize = 0
+		}
+		if err := dec.checkCount(start, count, minSize); err != nil {
+			return err
+		}
+		if err := v.BeginArray(count); err != nil {
+			return err
+		}
+		for i := 0; i < count; i++ {
+			dec.pushIndex(i)
+			err := c.walk(dec, p.elem, v, depth+1)
+			dec.popPath()
+			if err != nil {
+				return err
+			}
+		}
+		return v.EndArray()
+	case abiPlanVariant:
+		start := dec.Pos()
+		index, err := dec.UnpackVarUint32()
+		if err != nil {
+			return err
+		}
+		if int(index) >= len(p.variants) {
+			return dec.errorAt(start, "invalid variant index %d", index)
+		}
+		member := &p.variants[index]
+		if err := v.BeginVariant(member.name); err != nil {
+			return err
+		}
+		if err := c.walk(dec, member.plan, v, depth+1); err != nil {
+			return err
+		}
+		return v.EndVariant()
+	case abiPlanStruct:
+		if err := v.BeginStruct(p.name); err != nil {
+			return err
+		}
+		for i := range p.fields {
+			f := &p.fields[i]
+			if f.extension && dec.IsEnd() {
+				break
+			}
+			if err := v.Field(f.name); err != nil {
+				return err
+			}
+			dec.pushField(f.name)
+			err := c.walk(dec, f.plan, v, depth+1)
+			dec.popPath()
+			if err != nil {
+				return err
+			}
+		}
+		return v.EndStruct()
+	}
+	return newErrorf("bad type plan %s", p.name)
+}
+
+// AppendJSON decodes data as typ and appends its JSON encoding to dst.
+// The output is identical to UnpackAbiType.
+func (c *CompiledABI) AppendJSON(dst []byte, typ string, data []byte) ([]byte, error) {
+	p, err := c.lookup(typ)
+	if err != nil {
+		return dst, err
+	}
+	j := c.newJSONVisitor(dst)
+	if err := c.walk(c.abi.newDecoder(data, typ), p, j, 0); err != nil {
+		return dst, err
+	}
+	return j.buf, nil
+}
+
+// DecodeJSON decodes data as typ and writes its JSON encoding to w. The
+// output is written in chunks of about jsonChunkSize bytes while data is
+// decoded, on error part of the document may already have been written.
+func (c *CompiledABI) DecodeJSON(w io.Writer, typ string, data []byte) error {
+	p, err := c.lookup(typ)
+	if err != nil {
+		return err
+	}
+	return c.writeJSON(w, c.abi.newDecoder(data, typ), p)
+}
+
+// DecodeActionJSON writes the JSON encoding of the arguments of action to w
+// the same way as DecodeJSON.
+func (c *CompiledABI) DecodeActionJSON(w io.Writer, action string, data []byte) error {
+	p, ok := c.actions[action]
+	if !ok {
+		return newErrorf("unknown action %s", action)
+	}
+	return c.writeJSON(w, c.abi.newDecoder(data, p.name), p)
+}
+
+// DecodeTableJSON writes the JSON encoding of a row of table to w the same
+// way as DecodeJSON.
+func (c *CompiledABI) DecodeTableJSON(w io.Writer, table string, data []byte) error {
+	p, ok := c.tables[table]
+	if !ok {
+		return newErrorf("unknown table %s", table)
+	}
+	return c.writeJSON(w, c.abi.newDecoder(data, p.name), p)
+}
+
+// jsonChunkSize is the amount of buffered output after which the JSON
+// visitor writes to its writer
+const jsonChunkSize = 4096
+
+// jsonChunkPool holds the chunk buffers of streaming JSON visitors
+var jsonChunkPool = sync.Pool{
+	New: func() interface{} {
+		buf := make([]byte, 0, jsonChunkSize+256)
+		return &buf
+	},
+}
+
+// maxPooledChunkSize keeps buffers grown by unusually large values out of
+// the pool
+const maxPooledChunkSize = 4 * jsonChunkSize
+
+func (c *CompiledABI) writeJSON(w io.Writer, dec *Decoder, p *abiTypePlan) error {
+	chunk := jsonChunkPool.Get().(*[]byte)
+	j := c.newJSONVisitor((*chunk)[:0])
+	j.w = w
+	err := c.walk(dec, p, j, 0)
+	if err == nil {
+		err = j.flush()
+	}
+	if cap(j.buf) <= maxPooledChunkSize {
+		*chunk = j.buf[:0]
+		jsonChunkPool.Put(chunk)
+	}
+	return err
+}
+
+// jsonABIVisitor writes the visited values as compact JSON. Without a
+// writer the output accumulates in buf, otherwise buf is written to w each
+// time it grows past jsonChunkSize.
+type jsonABIVisitor struct {
+	buf           []byte
+	w             io.Writer
+	err           error
+	first         []bool
+	afterKey      bool
+	variantObject bool
+}
+
+func (c *CompiledABI) newJSONVisitor(buf []byte) *jsonABIVisitor {
+	return &jsonABIVisitor{buf: buf, variantObject: c.abi.opts.VariantFormat == VariantObject}
+}
+
+func (j *jsonABIVisitor) flush() error {
+	if j.err == nil && len(j.buf) > 0 {
+		_, j.err = j.w.Write(j.buf)
+		j.buf = j.buf[:0]
+	}
+	return j.err
+}
+
+func (j *jsonABIVisitor) beginValue() {
+	if j.w != nil && len(j.buf) >= jsonChunkSize {
+		j.flush()
+	}
+	if j.afterKey {
+		j.afterKey = false
+		return
+	}
+	if n := len(j.first); n > 0 {
+		if j.first[n-1] {
+			j.first[n-1] = false
+		} else {
+			j.buf = append(j.buf, ',')
+		}
+	}
+}
+
+func (j *jsonABIVisitor) push(c byte) {
+	j.beginValue()
+	j.buf = append(j.buf, c)
+	j.first = append(j.first, true)
+}
+
+func (j *jsonABIVisitor) pop(c byte) {
+	j.first = j.first[:len(j.first)-1]
+	j.buf = append(j.buf, c)
+}
+
+func (j *jsonABIVisitor) BeginStruct(typ string) error {
+	j.push('{')
+	return j.err
+}
+
+func (j *jsonABIVisitor) Field(name string) error {
+	j.beginValue()
+	j.buf = appendJSONString(j.buf, name)
+	j.buf = append(j.buf, ':')
+	j.afterKey = true
+	return j.err
+}
+
+func (j *jsonABIVisitor) EndStruct() error {
+	j.pop('}')
+	return j.err
+}
+
+func (j *jsonABIVisitor) BeginArray(length int) error {
+	j.push('[')
+	return j.err
+}
+
+func (j *jsonABIVisitor) EndArray() error {
+	j.pop(']')
+	return j.err
+}
+
+func (j *jsonABIVisitor) BeginVariant(typ string) error {
+	if j.variantObject {
+		j.push('{')
+		j.first[len(j.first)-1] = false
+		j.buf = append(j.buf, `"type":`...)
+		j.buf = appendJSONString(j.buf, typ)
+		j.buf = append(j.buf, `,"value":`...)
+		j.afterKey = true
+		return j.err
+	}
+	j.push('[')
+	j.first[len(j.first)-1] = false
+	j.buf = appendJSONString(j.buf, typ)
+	return j.err
+}
+
+func (j *jsonABIVisitor) EndVariant() error {
+	if j.variantObject {
+		j.pop('}')
+		return j.err
+	}
+	j.pop(']')
+	return j.err
+}
+
+func (j *jsonABIVisitor) Null() error {
+	j.beginValue()
+	j.buf = append(j.buf, "null"...)
+	return j.err
+}
+
+func (j *jsonABIVisitor) Value(typ string, value interface{}) error {
+	j.beginValue()
+	switch v := value.(type) {
+	case string:
+		j.buf = appendJSONString(j.buf, v)
+	case bool:
+		j.buf = strconv.AppendBool(j.buf, v)
+	case int8:
+		j.buf = strconv.AppendInt(j.buf, int64(v), 10)
+	case uint8:
+		j.buf = strconv.AppendUint(j.buf, uint64(v), 10)
+	case int16:
+		j.buf = strconv.AppendInt(j.buf, int64(v), 10)
+	case uint16:
+		j.buf = strconv.AppendUint(j.buf, uint64(v), 10)
+	case int32:
+		j.buf = strconv.AppendInt(j.buf, int64(v), 10)
+	case uint32:
+		j.buf = strconv.AppendUint(j.buf, uint64(v), 10)
+	case int64:
+		j.buf = strconv.AppendInt(j.buf, v, 10)
+	case uint64:
+		j.buf = strconv.AppendUint(j.buf, v, 10)
+	case VarUint32:
+		j.buf = strconv.AppendUint(j.buf, uint64(v), 10)
+	case *orderedmap.OrderedMap:
+		bs, err := json.Marshal(v)
+		if err != nil {
+			return newError(err)
+		}
+		j.buf = append(j.buf, bs...)
+	default:
+		bs, err := json.Marshal(v)
+		if err != nil {
+			return newError(err)
+		}
+		j.buf = append(j.buf, bs...)
+	}
+	return j.err
+}
+
+const jsonHex = "0123456789abcdef"
+
+// appendJSONString quotes s the same way encoding/json does, including
+// HTML escaping and replacement of invalid UTF-8.
+func appendJSONString(dst []byte, s string) []byte {
+	dst = append(dst, '"')
+	start := 0
+	for i := 0; i < len(s); {
+		b := s[i]
+		if b < utf8.RuneSelf {
+			if b >= 0x20 && b != '"' && b != '\\' && b != '<' && b != '>' && b != '&' {
+				i++
+				continue
+			}
+			dst = append(dst, s[start:i]...)
+			switch b {
+			case '"', '\\':
+				dst = append(dst, '\\', b)
+			case '\n':
+				dst = append(dst, '\\', 'n')
+			case '\r':
+				dst = append(dst, '\\', 'r')
+			case '\t':
+				dst = append(dst, '\\', 't')
+			default:
+				dst = append(dst, '\\', 'u', '0', '0', jsonHex[b>>4], jsonHex[b&0xf])
+			}
+			i++
+			start = i
+			continue
+		}
+		c, size := utf8.DecodeRuneInString(s[i:])
+		if c == utf8.RuneError && size == 1 {
+			dst = append(dst, s[start:i]...)
+			dst = append(dst, `\ufffd`...)
+			i += size
+			start = i
+			continue
+		}
+		if c == '\u2028' || c == '\u2029' {
+			dst = append(dst, s[start:i]...)
+			dst = append(dst, '\\', 'u', '2', '0', '2', jsonHex[c&0xf])
+			i += size
+			start = i
+			continue
+		}
+		i += size
+	}
+	dst = append(dst, s[start:]...)
+	return append(dst, '"')
+}
@@ -0,0 +1,551 @@
+package uuoskit
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+type ABIChangeKind string
+
+const (
+	ABIChangeAdded   ABIChangeKind = "added"
+	ABIChangeRemoved ABIChangeKind = "removed"
+	ABIChangeChanged ABIChangeKind = "changed"
+)
+
+// ABIChange describes one difference between two ABIs. Breaking is set when
+// data serialized with the old ABI can not be read with the new one, or the
+// other way around.
+type ABIChange struct {
+	Kind     ABIChangeKind `json:"kind"`
+	Category string        `json:"category"`
+	Path     string        `json:"path"`
+	Old      string        `json:"old,omitempty"`
+	New      string        `json:"new,omitempty"`
+	Breaking bool          `json:"breaking"`
+	Reason   string        `json:"reason"`
+}
+
+func (c ABIChange) String() string {
+	compat := "compatible"
+	if c.Breaking {
+		compat = "breaking"
+	}
+	s := fmt.Sprintf("[%s] %s %s %s", compat, c.Kind, c.Category, c.Path)
+	if c.Old != "" || c.New != "" {
+		s += fmt.Sprintf(" (%s => %s)", c.Old, c.New)
+	}
+	if c.Reason != "" {
+		s += ": " + c.Reason
+	}
+	return s
+}
+
+type ABIDiff struct {
+	Changes []ABIChange `json:"changes"`
+}
+
+func (d *ABIDiff) IsBreaking() bool {
+	for i := range d.Changes {
+		if d.Changes[i].Breaking {
+			return true
+		}
+	}
+	return false
+}
+
+func (d *ABIDiff) BreakingChanges() []ABIChange {
+	ret := make([]ABIChange, 0)
+	for i := range d.Changes {
+		if d.Changes[i].Breaking {
+			ret = append(ret, d.Changes[i])
+		}
+	}
+	return ret
+}
+
+func (d *ABIDiff) String() string {
+	lines := make([]string, 0, len(d.Changes))
+	for i := range d.Changes {
+		lines = append(lines, d.Changes[i].String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+type abiDiffer struct {
+	old  *ABI
+	new  *ABI
+	diff *ABIDiff
+}
+
+func (d *abiDiffer) add(c ABIChange) {
+	d.diff.Changes = append(d.diff.Changes, c)
+}
+
+// DiffABI compares two ABIs and classifies every change as binary compatible
+// or breaking. Appending binary extension (`$`) fields or variant alternatives
+// is compatible, removing, reordering or retyping fields is breaking.
+func DiffABI(oldAbi, newAbi *ABI) *ABIDiff {
+	d := &abiDiffer{old: oldAbi, new: newAbi, diff: &ABIDiff{Changes: []ABIChange{}}}
+	d.diffActions()
+	d.diffTables()
+	d.diffStructs()
+	d.diffTypes()
+	d.diffVariants()
+	return d.diff
+}
+
+func (d *abiDiffer) diffActions() {
+	oldActions := make(map[string]*ABIAction)
+	for i := range d.old.Actions {
+		oldActions[d.old.Actions[i].Name] = &d.old.Actions[i]
+	}
+	newActions := make(map[string]*ABIAction)
+	for i := range d.new.Actions {
+		a := &d.new.Actions[i]
+		newActions[a.Name] = a
+		old, ok := oldActions[a.Name]
+		if !ok {
+			d.add(ABIChange{Kind: ABIChangeAdded, Category: "action", Path: "actions." + a.Name, New: a.Type})
+			continue
+		}
+		if old.Type != a.Type {
+			d.add(d.typeChange("action", "actions."+a.Name, old.Type, a.Type))
+		}
+	}
+	for i := range d.old.Actions {
+		a := &d.old.Actions[i]
+		if _, ok := newActions[a.Name]; !ok {
+			d.add(ABIChange{Kind: ABIChangeRemoved, Category: "action", Path: "actions." + a.Name, Old: a.Type, Breaking: true,
+				Reason: "clients sending this action will fail"})
+		}
+	}
+}
+
+func (d *abiDiffer) diffTables() {
+	oldTables := make(map[string]*ABITable)
+	for i := range d.old.Tables {
+		oldTables[d.old.Tables[i].Name] = &d.old.Tables[i]
+	}
+	newTables := make(map[string]*ABITable)
+	for i := range d.new.Tables {
+		tb := &d.new.Tables[i]
+		newTables[tb.Name] = tb
+		path := "tables." + tb.Name
+		old, ok := oldTables[tb.Name]
+		if !ok {
+			d.add(ABIChange{Kind: ABIChangeAdded, Category: "table", Path: path, New: tb.Type})
+			continue
+		}
+		if old.Type != tb.Type {
+			d.add(d.typeChange("table", path, old.Type, tb.Type))
+		}
+		if old.IndexType != tb.IndexType {
+			d.add(ABIChange{Kind: ABIChangeChanged, Category: "table", Path: path + ".index_type", Old: old.IndexType, New: tb.IndexType, Breaking: true,
+				Reason: "existing rows are indexed with the old key type"})
+		}
+		if strings.Join(old.KeyTypes, ",") != strings.Join(tb.KeyTypes, ",") {
+			d.add(ABIChange{Kind: ABIChangeChanged, Category: "table", Path: path + ".key_types",
+				Old: strings.Join(old.KeyTypes, ","), New: strings.Join(tb.KeyTypes, ","), Breaking: true,
+				Reason: "existing rows are indexed with the old key types"})
+		}
+	}
+	for i := range d.old.Tables {
+		tb := &d.old.Tables[i]
+		if _, ok := newTables[tb.Name]; !ok {
+			d.add(ABIChange{Kind: ABIChangeRemoved, Category: "table", Path: "tables." + tb.Name, Old: tb.Type, Breaking: true,
+				Reason: "existing rows can no longer be decoded"})
+		}
+	}
+}
+
+func (d *abiDiffer) diffStructs() {
+	oldStructs := make(map[string]*ABIStruct)
+	for i := range d.old.Structs {
+		oldStructs[d.old.Structs[i].Name] = &d.old.Structs[i]
+	}
+	newStructs := make(map[string]*ABIStruct)
+	for i := range d.new.Structs {
+		s := &d.new.Structs[i]
+		newStructs[s.Name] = s
+		old, ok := oldStructs[s.Name]
+		if !ok {
+			d.add(ABIChange{Kind: ABIChangeAdded, Category: "struct", Path: "structs." + s.Name})
+			continue
+		}
+		d.diffStruct(old, s)
+	}
+	for i := range d.old.Structs {
+		s := &d.old.Structs[i]
+		if _, ok := newStructs[s.Name]; !ok {
+			d.add(d.removedType("struct", "structs."+s.Name, s.Name))
+		}
+	}
+}
+
+func (d *abiDiffer) diffStruct(old, s *ABIStruct) {
+	path := "structs." + s.Name
+	if old.Base != s.Base {
+		oldLayout := abiTypeLayout(d.old, old.Base)
+		newLayout := abiTypeLayout(d.new, s.Base)
+		c := ABIChange{Kind: ABIChangeChanged, Category: "struct", Path: path + ".base", Old: old.Base, New: s.Base}
+		if oldLayout != newLayout {
+			c.Breaking = true
+			c.Reason = "base struct layout changed"
+		} else {
+			c.Reason = "base struct renamed, binary layout unchanged"
+		}
+		d.add(c)
+	}
+
+	n := len(old.Fields)
+	if len(s.Fields) < n {
+		n = len(s.Fields)
+	}
+	for i := 0; i < n; i++ {
+		of := &old.Fields[i]
+		nf := &s.Fields[i]
+		fieldPath := fmt.Sprintf("%s.fields.%s", path, nf.Name)
+		if of.Name != nf.Name {
+			c := ABIChange{Kind: ABIChangeChanged, Category: "field", Path: fieldPath, Old: of.Name, New: nf.Name}
+			if d.hasField(s, of.Name) {
+				c.Breaking = true
+				c.Reason = "fields reordered"
+			} else {
+				c.Reason = "field renamed, binary layout unchanged but JSON field name differs"
+			}
+			d.add(c)
+		}
+		if of.Type == nf.Type {
+			continue
+		}
+		oldLayout := abiTypeLayout(d.old, of.Type)
+		newLayout := abiTypeLayout(d.new, nf.Type)
+		c := ABIChange{Kind: ABIChangeChanged, Category: "field", Path: fieldPath + ".type", Old: of.Type, New: nf.Type}
+		if oldLayout == newLayout {
+			c.Reason = "type renamed, binary layout unchanged"
+		} else {
+			c.Breaking = true
+			c.Reason = "field type changed"
+		}
+		d.add(c)
+	}
+
+	for i := n; i < len(s.Fields); i++ {
+		f := &s.Fields[i]
+		c := ABIChange{Kind: ABIChangeAdded, Category: "field", Path: fmt.Sprintf("%s.fields.%s", path, f.Name), New: f.Type}
+		if strings.HasSuffix(f.Type, "$") {
+			if blocker := extensionBlocker(d.new, s.Name, map[string]bool{}); blocker != "" {
+				c.Breaking = true
+				c.Reason = "binary extension appended, but " + blocker
+			} else {
+				c.Reason = "binary extension appended"
+			}
+		} else {
+			c.Breaking = true
+			c.Reason = "appended field is not a binary extension, existing data lacks it"
+		}
+		d.add(c)
+	}
+
+	for i := n; i < len(old.Fields); i++ {
+		f := &old.Fields[i]
+		d.add(ABIChange{Kind: ABIChangeRemoved, Category: "field", Path: fmt.Sprintf("%s.fields.%s", path, f.Name), Old: f.Type, Breaking: true,
+			Reason: "existing data contains the removed field"})
+	}
+}
+
+func (d *abiDiffer) hasField(s *ABIStruct, name string) bool {
+	for i := range s.Fields {
+		if s.Fields[i].Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (d *abiDiffer) diffTypes() {
+	oldTypes := make(map[string]string)
+	for _, tp := range d.old.Types {
+		oldTypes[tp.NewTypeName] = tp.Type
+	}
+	newTypes := make(map[string]string)
+	for _, tp := range d.new.Types {
+		newTypes[tp.NewTypeName] = tp.Type
+		path := "types." + tp.NewTypeName
+		old, ok := oldTypes[tp.NewTypeName]
+		if !ok {
+			d.add(ABIChange{Kind: ABIChangeAdded, Category: "type", Path: path, New: tp.Type})
+			continue
+		}
+		if old != tp.Type {
+			d.add(d.typeChange("type", path, old, tp.Type))
+		}
+	}
+	for _, tp := range d.old.Types {
+		if _, ok := newTypes[tp.NewTypeName]; !ok {
+			d.add(d.removedType("type", "types."+tp.NewTypeName, tp.NewTypeName))
+		}
+	}
+}
+
+func (d *abiDiffer) diffVariants() {
+	oldVariants := make(map[string]*VariantDef)
+	for i := range d.old.Variants {
+		oldVariants[d.old.Variants[i].Name] = &d.old.Variants[i]
+	}
+	newVariants := make(map[string]*VariantDef)
+	for i := range d.new.Variants {
+		v := &d.new.Variants[i]
+		newVariants[v.Name] = v
+		path := "variants." + v.Name
+		old, ok := oldVariants[v.Name]
+		if !ok {
+			d.add(ABIChange{Kind: ABIChangeAdded, Category: "variant", Path: path, New: strings.Join(v.Types, ",")})
+			continue
+		}
+		c := ABIChange{Kind: ABIChangeChanged, Category: "variant", Path: path, Old: strings.Join(old.Types, ","), New: strings.Join(v.Types, ",")}
+		if c.Old == c.New {
+			continue
+		}
+		if len(v.Types) < len(old.Types) {
+			c.Breaking = true
+			c.Reason = "variant alternatives removed"
+			d.add(c)
+			continue
+		}
+		for j := range old.Types {
+			if abiTypeLayout(d.old, old.Types[j]) != abiTypeLayout(d.new, v.Types[j]) {
+				c.Breaking = true
+				c.Reason = fmt.Sprintf("variant alternative %d changed", j)
+				break
+			}
+		}
+		if !c.Breaking {
+			if len(v.Types) > len(old.Types) {
+				c.Reason = "variant alternatives appended"
+			} else {
+				c.Reason = "variant alternatives renamed, binary layout unchanged"
+			}
+		}
+		d.add(c)
+	}
+	for i := range d.old.Variants {
+		v := &d.old.Variants[i]
+		if _, ok := newVariants[v.Name]; !ok {
+			d.add(d.removedType("variant", "variants."+v.Name, v.Name))
+		}
+	}
+}
+
+// typeChange classifies a change of the type referenced by an action, table
+// or alias by comparing the binary layouts of both types.
+func (d *abiDiffer) typeChange(category, path, oldType, newType string) ABIChange {
+	c := ABIChange{Kind: ABIChangeChanged, Category: category, Path: path, Old: oldType, New: newType}
+	oldLayout := abiTypeLayout(d.old, oldType)
+	newLayout := abiTypeLayout(d.new, newType)
+	if oldLayout == newLayout {
+		c.Reason = "type renamed, binary layout unchanged"
+	} else {
+		c.Breaking = true
+		c.Reason = "binary layout changed"
+	}
+	return c
+}
+
+// removedType is only breaking when the new ABI still refers to the type.
+func (d *abiDiffer) removedType(category, path, name string) ABIChange {
+	c := ABIChange{Kind: ABIChangeRemoved, Category: category, Path: path, Old: name}
+	if strings.HasPrefix(abiTypeLayout(d.new, name), "unknown:") && abiReferencesType(d.new, name) {
+		c.Breaking = true
+		c.Reason = "type is still referenced"
+	} else {
+		c.Reason = "type no longer referenced"
+	}
+	return c
+}
+
+// extensionBlocker returns why data of type name is not always at the end
+// of the packed data in abi, empty if it is. A binary extension appended to
+// the type can only be detected at the end of the data: the type must be an
+// action or table type, an alias of one, or the trailing field of such a
+// struct, and not an element of an array, an optional or a variant.
+func extensionBlocker(abi *ABI, name string, visited map[string]bool) string {
+	if visited[name] {
+		return ""
+	}
+	visited[name] = true
+	for _, tp := range abi.Types {
+		switch typ := strings.TrimSuffix(tp.Type, "$"); {
+		case typ == name:
+			if blocker := extensionBlocker(abi, tp.NewTypeName, visited); blocker != "" {
+				return blocker
+			}
+		case abiTypeName(typ) == name:
+			return fmt.Sprintf("%s is used as %s by type %s", name, tp.Type, tp.NewTypeName)
+		}
+	}
+	for i := range abi.Structs {
+		st := &abi.Structs[i]
+		if st.Base == name {
+			if len(st.Fields) > 0 {
+				return fmt.Sprintf("%s is the base of struct %s", name, st.Name)
+			}
+			if blocker := extensionBlocker(abi, st.Name, visited); blocker != "" {
+				return blocker
+			}
+		}
+		for j, f := range st.Fields {
+			switch typ := strings.TrimSuffix(f.Type, "$"); {
+			case typ == name && j < len(st.Fields)-1:
+				return fmt.Sprintf("%s is followed by other fields in struct %s", name, st.Name)
+			case typ == name:
+				if blocker := extensionBlocker(abi, st.Name, visited); blocker != "" {
+					return blocker
+				}
+			case abiTypeName(typ) == name:
+				return fmt.Sprintf("%s is used as %s by field %s.%s", name, f.Type, st.Name, f.Name)
+			}
+		}
+	}
+	for _, v := range abi.Variants {
+		for _, typ := range v.Types {
+			if abiTypeName(typ) == name {
+				return fmt.Sprintf("%s is an alternative of variant %s", name, v.Name)
+			}
+		}
+	}
+	return ""
+}
+
+// abiTypeName strips the binary extension, optional and array suffixes of typ
+func abiTypeName(typ string) string {
+	for {
+		switch {
+		case strings.HasSuffix(typ, "$"), strings.HasSuffix(typ, "?"):
+			typ = typ[:len(typ)-1]
+		case strings.HasSuffix(typ, "]") && strings.LastIndex(typ, "[") > 0:
+			typ = typ[:strings.LastIndex(typ, "[")]
+		default:
+			return typ
+		}
+	}
+}
+
+func abiReferencesType(abi *ABI, name string) bool {
+	refers := func(typ string) bool {
+		return strings.TrimRight(typ, "[]?$") == name
+	}
+	for _, tp := range abi.Types {
+		if refers(tp.Type) {
+			return true
+		}
+	}
+	for _, s := range abi.Structs {
+		if s.Base == name {
+			return true
+		}
+		for _, f := range s.Fields {
+			if refers(f.Type) {
+				return true
+			}
+		}
+	}
+	for _, v := range abi.Variants {
+		for _, typ := range v.Types {
+			if refers(typ) {
+				return true
+			}
+		}
+	}
+	for _, a := range abi.Actions {
+		if a.Type == name {
+			return true
+		}
+	}
+	for _, tb := range abi.Tables {
+		if tb.Type == name {
+			return true
+		}
+	}
+	return false
+}
+
+// abiTypeLayout returns a canonical description of the binary encoding of
+// typ with aliases resolved, base structs flattened and field names dropped,
+// two types with equal layouts are binary compatible.
+func abiTypeLayout(abi *ABI, typ string) string {
+	return abiTypeLayoutImpl(abi, typ, map[string]bool{}, 0)
+}
+
+func abiTypeLayoutImpl(abi *ABI, typ string, stack map[string]bool, depth int) string {
+	if depth > maxAbiTypeDepth {
+		return "unknown:" + typ
+	}
+	if strings.HasSuffix(typ, "$") {
+		return abiTypeLayoutImpl(abi, strings.TrimSuffix(typ, "$"), stack, depth+1) + "$"
+	}
+	if strings.HasSuffix(typ, "[]") {
+		return abiTypeLayoutImpl(abi, strings.TrimSuffix(typ, "[]"), stack, depth+1) + "[]"
+	}
+	if strings.HasSuffix(typ, "?") {
+		return abiTypeLayoutImpl(abi, strings.TrimSuffix(typ, "?"), stack, depth+1) + "?"
+	}
+	if _, ok := gBaseTypes[typ]; ok {
+		return typ
+	}
+	for i := range abi.Types {
+		if abi.Types[i].NewTypeName == typ {
+			return abiTypeLayoutImpl(abi, abi.Types[i].Type, stack, depth+1)
+		}
+	}
+	for i := range abi.Variants {
+		v := &abi.Variants[i]
+		if v.Name == typ {
+			types := make([]string, 0, len(v.Types))
+			for _, tp := range v.Types {
+				types = append(types, abiTypeLayoutImpl(abi, tp, stack, depth+1))
+			}
+			return "variant<" + strings.Join(types, ",") + ">"
+		}
+	}
+	for i := range abi.Structs {
+		s := &abi.Structs[i]
+		if s.Name != typ {
+			continue
+		}
+		// recursive structs can only refer to themselves through arrays or optionals
+		if stack[typ] {
+			return "@" + typ
+		}
+		stack[typ] = true
+		defer delete(stack, typ)
+		fields := make([]string, 0, len(s.Fields)+1)
+		if s.Base != "" {
+			base := abiTypeLayoutImpl(abi, s.Base, stack, depth+1)
+			if base = strings.TrimSuffix(strings.TrimPrefix(base, "{"), "}"); base != "" {
+				fields = append(fields, base)
+			}
+		}
+		for _, f := range s.Fields {
+			fields = append(fields, abiTypeLayoutImpl(abi, f.Type, stack, depth+1))
+		}
+		return "{" + strings.Join(fields, ",") + "}"
+	}
+	return "unknown:" + typ
+}
+
+// DiffContractABI compares the cached ABI of contractName with newAbi,
+// it is meant to be run before deploying a contract upgrade.
+func (t *ABISerializer) DiffContractABI(contractName string, newAbi []byte) (*ABIDiff, error) {
+	oldAbi, ok := t.getABI(contractName)
+	if !ok {
+		return nil, newErrorf("contract not found %s", contractName)
+	}
+	abi := &ABI{}
+	if err := json.Unmarshal(newAbi, abi); err != nil {
+		return nil, newError(err)
+	}
+	return DiffABI(oldAbi, abi), nil
+}
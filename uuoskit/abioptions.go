@@ -0,0 +1,112 @@
+package uuoskit
+
+import (
+	"strconv"
+
+	secp256k1 "github.com/armoniax/go-secp256k1"
+	"github.com/iancoleman/orderedmap"
+)
+
+type VariantFormat int
+
+const (
+	VariantArray  VariantFormat = iota // ["type", value]
+	VariantObject                      // {"type": "type", "value": value}
+)
+
+type PublicKeyFormat int
+
+const (
+	PublicKeyLegacy PublicKeyFormat = iota // LegacyKeyPrefix followed by base58, e.g. AM6MRy...
+	PublicKeyK1                            // PUB_K1_6MRy...
+)
+
+type AssetFormat int
+
+const (
+	AssetString AssetFormat = iota // "1.0000 EOS"
+	AssetObject                    // {"amount": 10000, "precision": 4, "symbol": "EOS"}
+)
+
+type SymbolFormat int
+
+const (
+	SymbolCodeFirst      SymbolFormat = iota // "EOS,4"
+	SymbolPrecisionFirst                     // "4,EOS" as nodeos renders it
+)
+
+const defaultLegacyKeyPrefix = "AM"
+
+// UnpackOptions controls the JSON produced by the ABI unpacker. The zero
+// value produces the historical output. The packer accepts every format, so
+// unpacked values can be packed again whatever the options.
+type UnpackOptions struct {
+	// Int64AsString renders int64 and uint64 as strings, JavaScript numbers
+	// can not hold them exactly.
+	Int64AsString bool
+	// DecimalInt128 renders int128 and uint128 as decimal strings instead of
+	// 0x prefixed hex.
+	DecimalInt128 bool
+	// DecimalFloat128 renders float128 as decimal strings instead of 0x
+	// prefixed little endian hex.
+	DecimalFloat128 bool
+	VariantFormat   VariantFormat
+	PublicKeyFormat PublicKeyFormat
+	// LegacyKeyPrefix is used with PublicKeyLegacy, it defaults to AM.
+	LegacyKeyPrefix string
+	AssetFormat     AssetFormat
+	SymbolFormat    SymbolFormat
+}
+
+func (o *UnpackOptions) int64Value(v int64) interface{} {
+	if o.Int64AsString {
+		return strconv.FormatInt(v, 10)
+	}
+	return v
+}
+
+func (o *UnpackOptions) uint64Value(v uint64) interface{} {
+	if o.Int64AsString {
+		return strconv.FormatUint(v, 10)
+	}
+	return v
+}
+
+func (o *UnpackOptions) publicKey(pub *secp256k1.PublicKey) string {
+	if o.PublicKeyFormat == PublicKeyK1 {
+		return pub.String()
+	}
+	legacy := pub.StringAM()
+	if o.LegacyKeyPrefix == "" || o.LegacyKeyPrefix == defaultLegacyKeyPrefix {
+		return legacy
+	}
+	return o.LegacyKeyPrefix + legacy[len(defaultLegacyKeyPrefix):]
+}
+
+func (o *UnpackOptions) symbol(sym *Symbol) string {
+	if o.SymbolFormat == SymbolPrecisionFirst {
+		return sym.String()
+	}
+	return sym.CodeString() + "," + strconv.Itoa(int(sym.Precision()))
+}
+
+func (o *UnpackOptions) asset(a *Asset) interface{} {
+	if o.AssetFormat != AssetObject {
+		return a.String()
+	}
+	m := orderedmap.New()
+	m.Set("amount", o.int64Value(a.Amount))
+	m.Set("precision", a.Symbol.Precision())
+	m.Set("symbol", a.Symbol.CodeString())
+	return m
+}
+
+func (o *UnpackOptions) variant(typ string, value interface{}) interface{} {
+	if o.VariantFormat != VariantObject {
+		return []interface{}{typ, value}
+	}
+	m := orderedmap.New()
+	m.Set("type", typ)
+	m.Set("value", value)
+	return m
+}
@@ -2,27 +2,134 @@ package uuoskit
 
 import (
 	"encoding/json"
+	"io"
+	"strings"
+	"sync"
 
 	"github.com/iancoleman/orderedmap"
 )
 
+// ABISerializer is safe for concurrent use, loaded ABIs are treated as
+// read only once they are set.
 type ABISerializer struct {
+	mu             sync.RWMutex
 	contractAbiMap map[string]*ABI
+	compiledAbiMap map[string]*CompiledABI
 	contractName   string
+	validateABI    bool
+	profile        *ChainProfile
+	unpackOptions  UnpackOptions
+	decoderOptions DecoderOptions
 }
 
 func NewABISerializer() *ABISerializer {
+	return NewABISerializerWithProfile(DefaultChainProfile())
+}
+
+// NewABISerializerWithProfile loads the default ABIs of profile and renders
+// public keys with its key prefix.
+func NewABISerializerWithProfile(profile *ChainProfile) *ABISerializer {
 	serializer := &ABISerializer{}
 	serializer.contractAbiMap = make(map[string]*ABI)
-	serializer.SetContractABI("eosio.token", []byte(eosioTokenAbi))
+	serializer.compiledAbiMap = make(map[string]*CompiledABI)
+	serializer.profile = profile
+	serializer.unpackOptions = profile.UnpackOptions()
+	for account, abi := range profile.ABIs {
+		serializer.SetContractABI(account, []byte(abi))
+	}
 	return serializer
 }
 
+// SetValidateABI enables rejecting ABIs with validation errors in SetContractABI.
+func (t *ABISerializer) SetValidateABI(validate bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.validateABI = validate
+}
+
+// GetChainProfile returns the profile the serializer was created with
+func (t *ABISerializer) GetChainProfile() *ChainProfile {
+	return t.profile
+}
+
+// SetUnpackOptions controls the JSON produced by the unpacker for all
+// cached and future ABIs. An empty LegacyKeyPrefix takes the key prefix of
+// the chain profile.
+func (t *ABISerializer) SetUnpackOptions(opts UnpackOptions) {
+	if opts.LegacyKeyPrefix == "" {
+		opts.LegacyKeyPrefix = t.profile.KeyPrefix
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.unpackOptions = opts
+	t.applyOptions()
+}
+
+func (t *ABISerializer) GetUnpackOptions() UnpackOptions {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.unpackOptions
+}
+
+// SetDecoderOptions sets the limits applied when decoding binary data, for
+// all cached and future ABIs.
+func (t *ABISerializer) SetDecoderOptions(opts DecoderOptions) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.decoderOptions = opts
+	t.applyOptions()
+}
+
+func (t *ABISerializer) GetDecoderOptions() DecoderOptions {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.decoderOptions.withDefaults()
+}
+
+// SetDecimalInt128 makes the unpacker render int128 and uint128 values as
+// decimal strings instead of 0x prefixed hex, for all cached and future ABIs.
+func (t *ABISerializer) SetDecimalInt128(decimal bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.unpackOptions.DecimalInt128 = decimal
+	t.applyOptions()
+}
+
+// SetDecimalFloat128 makes the unpacker render float128 values as decimal
+// strings instead of 0x prefixed hex, for all cached and future ABIs.
+func (t *ABISerializer) SetDecimalFloat128(decimal bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.unpackOptions.DecimalFloat128 = decimal
+	t.applyOptions()
+}
+
+// applyOptions replaces every cached ABI with a copy carrying the current
+// unpack and decoder options, the old ABI may still be in use by other
+// goroutines. t.mu must be held for writing.
+func (t *ABISerializer) applyOptions() {
+	for name, abi := range t.contractAbiMap {
+		c := *abi
+		c.opts = t.unpackOptions
+		c.decoderOpts = t.decoderOptions
+		t.contractAbiMap[name] = &c
+		delete(t.compiledAbiMap, name)
+	}
+}
+
+func (t *ABISerializer) getABI(contractName string) (*ABI, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	abi, ok := t.contractAbiMap[contractName]
+	return abi, ok
+}
+
 func (t *ABISerializer) SetContractABI(contractName string, abi []byte) error {
 	if len(abi) == 0 {
-		if _, ok := t.contractAbiMap[contractName]; ok {
-			delete(t.contractAbiMap, contractName)
-		}
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		delete(t.compiledAbiMap, contractName)
+		delete(t.contractAbiMap, contractName)
 		return nil
 	}
 	abiObj := &ABI{}
@@ -31,17 +138,45 @@ func (t *ABISerializer) SetContractABI(contractName string, abi []byte) error {
 		return newError(err)
 	}
 
+	t.mu.RLock()
+	validate := t.validateABI
+	t.mu.RUnlock()
+	if validate {
+		if err := checkABI(abiObj); err != nil {
+			return err
+		}
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	abiObj.opts = t.unpackOptions
+	abiObj.decoderOpts = t.decoderOptions
+	delete(t.compiledAbiMap, contractName)
 	t.contractAbiMap[contractName] = abiObj
 	return nil
 }
 
+func checkABI(abi *ABI) error {
+	diags := abi.Validate()
+	if !HasABIErrors(diags) {
+		return nil
+	}
+	errs := make([]string, 0, len(diags))
+	for _, d := range diags {
+		if d.Severity == ABIDiagnosticError {
+			errs = append(errs, d.Path+": "+d.Message)
+		}
+	}
+	return newErrorf("invalid abi: %s", strings.Join(errs, "; "))
+}
+
 func (t *ABISerializer) IsAbiCached(contractName string) bool {
-	_, ok := t.contractAbiMap[contractName]
+	_, ok := t.getABI(contractName)
 	return ok
 }
 
 func (t *ABISerializer) PackActionArgs(contractName, actionName string, args string) ([]byte, error) {
-	if abi, ok := t.contractAbiMap[contractName]; ok {
+	if abi, ok := t.getABI(contractName); ok {
 		actionTypeName := abi.GetActionStructType(actionName)
 		return abi.PackAbiType(actionTypeName, args)
 	} else {
@@ -50,7 +185,7 @@ func (t *ABISerializer) PackActionArgs(contractName, actionName string, args str
 }
 
 func (t *ABISerializer) UnpackActionArgs(contractName string, actionName string, packedValue []byte) ([]byte, error) {
-	abi, ok := t.contractAbiMap[contractName]
+	abi, ok := t.getABI(contractName)
 	if !ok {
 		return nil, newErrorf("contract not found %s", contractName)
 	}
@@ -59,7 +194,7 @@ func (t *ABISerializer) UnpackActionArgs(contractName string, actionName string,
 	if actionType == "" {
 		return nil, newErrorf("unknown action %s::%s", contractName, actionName)
 	}
-	dec := NewDecoder(packedValue)
+	dec := abi.newDecoder(packedValue, actionType)
 	result := orderedmap.New()
 	err := abi.UnpackAbiStruct(dec, actionType, result)
 	if err != nil {
@@ -72,8 +207,31 @@ func (t *ABISerializer) UnpackActionArgs(contractName string, actionName string,
 	return bs, nil
 }
 
+// UnpackActionResult decodes the return value of actionName with the type
+// declared in the action_results of the ABI
+func (t *ABISerializer) UnpackActionResult(contractName string, actionName string, packedValue []byte) ([]byte, error) {
+	abi, ok := t.getABI(contractName)
+	if !ok {
+		return nil, newErrorf("contract not found %s", contractName)
+	}
+	resultType := abi.GetActionResultType(actionName)
+	if resultType == "" {
+		return nil, newErrorf("no action result for %s::%s", contractName, actionName)
+	}
+	dec := abi.newDecoder(packedValue, resultType)
+	v, err := abi.UnpackAbiValue(dec, resultType)
+	if err != nil {
+		return nil, newError(err)
+	}
+	bs, err := json.Marshal(v)
+	if err != nil {
+		return nil, newError(err)
+	}
+	return bs, nil
+}
+
 func (t *ABISerializer) PackAbiType(contractName, abiType string, args string) ([]byte, error) {
-	abi, ok := t.contractAbiMap[contractName]
+	abi, ok := t.getABI(contractName)
 	if !ok {
 		return nil, newErrorf("contract not found %s", contractName)
 	}
@@ -81,13 +239,59 @@ func (t *ABISerializer) PackAbiType(contractName, abiType string, args string) (
 }
 
 func (t *ABISerializer) UnpackAbiType(contractName, abiName string, packedValue []byte) ([]byte, error) {
-	abi, ok := t.contractAbiMap[contractName]
+	abi, ok := t.getABI(contractName)
 	if !ok {
 		return nil, newErrorf("contract not found %s", contractName)
 	}
 	return abi.UnpackAbiType(abiName, packedValue)
 }
 
+// GetCompiledABI returns the compiled form of the ABI of contractName,
+// the ABI is compiled on first use and cached until it is replaced.
+func (t *ABISerializer) GetCompiledABI(contractName string) (*CompiledABI, error) {
+	t.mu.RLock()
+	c, ok := t.compiledAbiMap[contractName]
+	abi, abiOk := t.contractAbiMap[contractName]
+	t.mu.RUnlock()
+	if ok {
+		return c, nil
+	}
+	if !abiOk {
+		return nil, newErrorf("contract not found %s", contractName)
+	}
+	c, err := abi.Compile()
+	if err != nil {
+		return nil, err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	// the ABI may have been replaced while compiling
+	if t.contractAbiMap[contractName] == abi {
+		t.compiledAbiMap[contractName] = c
+	}
+	return c, nil
+}
+
+// UnpackActionArgsTo writes the JSON encoding of the action arguments to w
+// without building an intermediate result.
+func (t *ABISerializer) UnpackActionArgsTo(w io.Writer, contractName string, actionName string, packedValue []byte) error {
+	c, err := t.GetCompiledABI(contractName)
+	if err != nil {
+		return err
+	}
+	return c.DecodeActionJSON(w, actionName, packedValue)
+}
+
+// UnpackAbiTypeTo writes the JSON encoding of packedValue decoded as abiName to w.
+func (t *ABISerializer) UnpackAbiTypeTo(w io.Writer, contractName, abiName string, packedValue []byte) error {
+	c, err := t.GetCompiledABI(contractName)
+	if err != nil {
+		return err
+	}
+	return c.DecodeJSON(w, abiName, packedValue)
+}
+
 func (t *ABISerializer) PackABI(strABI string) ([]byte, error) {
 	abi := &ABI{}
 	err := json.Unmarshal([]byte(strABI), abi)
@@ -175,11 +379,21 @@ func (t *ABISerializer) PackABI(strABI string) ([]byte, error) {
 		}
 	}
 
+	// action_results is a binary extension
+	if len(abi.ActionResults) > 0 {
+		enc.PackVarUint32(uint32(len(abi.ActionResults)))
+		for i := range abi.ActionResults {
+			a := &abi.ActionResults[i]
+			enc.PackName(NewName(a.Name))
+			enc.PackString(a.ResultType)
+		}
+	}
+
 	return enc.Bytes(), nil
 }
 
 func (t *ABISerializer) UnpackABI(rawAbi []byte) (string, error) {
-	dec := NewDecoder(rawAbi)
+	dec := NewDecoderWithOptions(rawAbi, t.GetDecoderOptions())
 	abi := &ABI{}
 	abi.Types = []ABIType{}
 	abi.Structs = []ABIStruct{}
@@ -397,6 +611,24 @@ func (t *ABISerializer) UnpackABI(rawAbi []byte) (string, error) {
 		abi.Variants = append(abi.Variants, v)
 	}
 
+	if !dec.IsEnd() {
+		length, err = dec.UnpackVarUint32()
+		if err != nil {
+			return "", err
+		}
+		for ; length > 0; length -= 1 {
+			name, err := dec.UnpackName()
+			if err != nil {
+				return "", err
+			}
+			resultType, err := dec.UnpackString()
+			if err != nil {
+				return "", err
+			}
+			abi.ActionResults = append(abi.ActionResults, ABIActionResult{name.String(), resultType})
+		}
+	}
+
 	ret, err := json.Marshal(abi)
 	if err != nil {
 		return "", err
@@ -0,0 +1,386 @@
+package uuoskit
+
+import (
+	"fmt"
+	"strings"
+)
+
+type ABIDiagnosticSeverity int
+
+const (
+	ABIDiagnosticError ABIDiagnosticSeverity = iota
+	ABIDiagnosticWarning
+)
+
+func (s ABIDiagnosticSeverity) String() string {
+	if s == ABIDiagnosticWarning {
+		return "warning"
+	}
+	return "error"
+}
+
+// ABIDiagnostic describes a single problem found by ABI.Validate.
+// Path points at the offending element, e.g. structs[2].fields[1].type
+type ABIDiagnostic struct {
+	Severity ABIDiagnosticSeverity `json:"severity"`
+	Path     string                `json:"path"`
+	Message  string                `json:"message"`
+}
+
+func (d ABIDiagnostic) String() string {
+	return fmt.Sprintf("%s: %s: %s", d.Severity, d.Path, d.Message)
+}
+
+const maxAbiTypeDepth = 32
+
+type abiValidator struct {
+	abi      *ABI
+	types    map[string]string
+	structs  map[string]*ABIStruct
+	variants map[string]*VariantDef
+	diags    []ABIDiagnostic
+}
+
+func (v *abiValidator) errorf(path string, format string, args ...interface{}) {
+	v.diags = append(v.diags, ABIDiagnostic{ABIDiagnosticError, path, fmt.Sprintf(format, args...)})
+}
+
+func (v *abiValidator) warnf(path string, format string, args ...interface{}) {
+	v.diags = append(v.diags, ABIDiagnostic{ABIDiagnosticWarning, path, fmt.Sprintf(format, args...)})
+}
+
+// Validate checks the ABI for problems that would otherwise only surface
+// while packing or unpacking: unresolved types, circular aliases and base
+// structs, duplicate definitions, invalid action or table names and
+// misplaced `$`/`?` suffixes. An empty result means the ABI is valid.
+func (t *ABI) Validate() []ABIDiagnostic {
+	v := &abiValidator{
+		abi:      t,
+		types:    make(map[string]string),
+		structs:  make(map[string]*ABIStruct),
+		variants: make(map[string]*VariantDef),
+	}
+	v.collect()
+	v.checkTypes()
+	v.checkStructs()
+	v.checkVariants()
+	v.checkActions()
+	v.checkTables()
+	v.checkMisc()
+	return v.diags
+}
+
+// HasABIErrors reports whether diags contains at least one error,
+// warnings are ignored.
+func HasABIErrors(diags []ABIDiagnostic) bool {
+	for i := range diags {
+		if diags[i].Severity == ABIDiagnosticError {
+			return true
+		}
+	}
+	return false
+}
+
+func (v *abiValidator) defineType(path string, name string) bool {
+	if name == "" {
+		v.errorf(path, "empty type name")
+		return false
+	}
+	if strings.ContainsAny(name, "?$[]") {
+		v.errorf(path, "type name %s must not contain `?`, `$` or `[]`", name)
+		return false
+	}
+	if _, ok := gBaseTypes[name]; ok {
+		v.errorf(path, "type %s redefines a built-in type", name)
+		return false
+	}
+	if _, ok := v.types[name]; ok {
+		v.errorf(path, "duplicate type %s", name)
+		return false
+	}
+	if _, ok := v.structs[name]; ok {
+		v.errorf(path, "duplicate type %s", name)
+		return false
+	}
+	if _, ok := v.variants[name]; ok {
+		v.errorf(path, "duplicate type %s", name)
+		return false
+	}
+	return true
+}
+
+func (v *abiValidator) collect() {
+	for i := range v.abi.Types {
+		tp := &v.abi.Types[i]
+		if v.defineType(fmt.Sprintf("types[%d].new_type_name", i), tp.NewTypeName) {
+			v.types[tp.NewTypeName] = tp.Type
+		}
+	}
+	for i := range v.abi.Structs {
+		s := &v.abi.Structs[i]
+		if v.defineType(fmt.Sprintf("structs[%d].name", i), s.Name) {
+			v.structs[s.Name] = s
+		}
+	}
+	for i := range v.abi.Variants {
+		vd := &v.abi.Variants[i]
+		if v.defineType(fmt.Sprintf("variants[%d].name", i), vd.Name) {
+			v.variants[vd.Name] = vd
+		}
+	}
+}
+
+// resolveAlias follows typedefs until a non alias type is reached,
+// it returns false on circular definitions.
+func (v *abiValidator) resolveAlias(typ string) (string, bool) {
+	seen := map[string]bool{}
+	for {
+		next, ok := v.types[typ]
+		if !ok {
+			return typ, true
+		}
+		if seen[typ] {
+			return typ, false
+		}
+		seen[typ] = true
+		typ = next
+	}
+}
+
+// checkType verifies that typ resolves to a known type. `$` is never
+// accepted here, callers strip the binary extension suffix of struct fields.
+func (v *abiValidator) checkType(path string, typ string) {
+	v.checkTypeDepth(path, typ, 0)
+}
+
+func (v *abiValidator) checkTypeDepth(path string, typ string, depth int) {
+	if depth > maxAbiTypeDepth {
+		v.errorf(path, "type %s is nested too deeply or circular", typ)
+		return
+	}
+	if typ == "" {
+		v.errorf(path, "empty type")
+		return
+	}
+	if strings.Contains(typ, "$") {
+		v.errorf(path, "misplaced `$` in type %s, binary extensions are only allowed as the last suffix of a struct field", typ)
+		return
+	}
+
+	inner := typ
+	for {
+		if elem, _, ok := splitArrayType(inner); ok {
+			inner = elem
+		} else if strings.HasSuffix(inner, "?") {
+			inner = strings.TrimSuffix(inner, "?")
+			if strings.HasSuffix(inner, "?") {
+				v.errorf(path, "misplaced `?` in type %s, optional of optional is not allowed", typ)
+				return
+			}
+		} else {
+			break
+		}
+	}
+	if strings.ContainsAny(inner, "?[]") {
+		v.errorf(path, "misplaced `?` or `[]` in type %s", typ)
+		return
+	}
+	v.checkBaseType(path, typ, inner, depth)
+}
+
+func (v *abiValidator) checkBaseType(path string, typ string, inner string, depth int) {
+	resolved, ok := v.resolveAlias(inner)
+	if !ok {
+		// reported by checkTypes
+		return
+	}
+	if resolved != inner {
+		// the alias target may itself carry suffixes, e.g. "tokens" => "asset[]"
+		if strings.ContainsAny(resolved, "?[]$") {
+			v.checkTypeDepth(path, resolved, depth+1)
+			return
+		}
+	}
+	if _, ok := gBaseTypes[resolved]; ok {
+		return
+	}
+	if _, ok := v.structs[resolved]; ok {
+		return
+	}
+	if _, ok := v.variants[resolved]; ok {
+		return
+	}
+	v.errorf(path, "unknown type %s", typ)
+}
+
+func (v *abiValidator) checkTypes() {
+	for i := range v.abi.Types {
+		tp := &v.abi.Types[i]
+		path := fmt.Sprintf("types[%d].type", i)
+		if _, ok := v.resolveAlias(tp.NewTypeName); !ok {
+			v.errorf(path, "circular type definition %s", tp.NewTypeName)
+			continue
+		}
+		v.checkType(path, tp.Type)
+	}
+}
+
+func (v *abiValidator) checkStructs() {
+	for i := range v.abi.Structs {
+		s := &v.abi.Structs[i]
+		path := fmt.Sprintf("structs[%d]", i)
+
+		if s.Base != "" {
+			v.checkStructBase(path+".base", s)
+		}
+
+		fieldNames := make(map[string]bool)
+		extension := false
+		for j := range s.Fields {
+			f := &s.Fields[j]
+			fieldPath := fmt.Sprintf("%s.fields[%d]", path, j)
+			if f.Name == "" {
+				v.errorf(fieldPath+".name", "empty field name in struct %s", s.Name)
+			} else if fieldNames[f.Name] {
+				v.errorf(fieldPath+".name", "duplicate field %s in struct %s", f.Name, s.Name)
+			}
+			fieldNames[f.Name] = true
+
+			typ := f.Type
+			if strings.HasSuffix(typ, "$") {
+				typ = strings.TrimSuffix(typ, "$")
+				extension = true
+			} else if extension {
+				v.errorf(fieldPath+".type", "field %s must be a binary extension since it follows one", f.Name)
+			}
+			v.checkType(fieldPath+".type", typ)
+		}
+	}
+}
+
+func (v *abiValidator) checkStructBase(path string, s *ABIStruct) {
+	seen := map[string]bool{s.Name: true}
+	current := s
+	for current.Base != "" {
+		base, ok := v.resolveAlias(current.Base)
+		if !ok {
+			return
+		}
+		next, ok := v.structs[base]
+		if !ok {
+			v.errorf(path, "base %s of struct %s is not a struct", current.Base, current.Name)
+			return
+		}
+		if seen[next.Name] {
+			v.errorf(path, "circular base struct in %s", s.Name)
+			return
+		}
+		seen[next.Name] = true
+		current = next
+	}
+}
+
+func (v *abiValidator) checkVariants() {
+	for i := range v.abi.Variants {
+		vd := &v.abi.Variants[i]
+		path := fmt.Sprintf("variants[%d]", i)
+		if len(vd.Types) == 0 {
+			v.errorf(path+".types", "variant %s has no types", vd.Name)
+		}
+		if len(vd.Types) > 256 {
+			v.errorf(path+".types", "variant %s has more than 256 types", vd.Name)
+		}
+		seen := make(map[string]bool)
+		for j, typ := range vd.Types {
+			typePath := fmt.Sprintf("%s.types[%d]", path, j)
+			if seen[typ] {
+				v.warnf(typePath, "duplicate type %s in variant %s", typ, vd.Name)
+			}
+			seen[typ] = true
+			v.checkType(typePath, typ)
+		}
+	}
+}
+
+func (v *abiValidator) checkStructType(path string, typ string, owner string) {
+	if typ == "" {
+		v.errorf(path, "empty type for %s", owner)
+		return
+	}
+	resolved, ok := v.resolveAlias(typ)
+	if !ok {
+		return
+	}
+	if _, ok := v.structs[resolved]; !ok {
+		v.errorf(path, "type %s of %s is not a struct", typ, owner)
+	}
+}
+
+func (v *abiValidator) checkActions() {
+	names := make(map[string]bool)
+	for i := range v.abi.Actions {
+		a := &v.abi.Actions[i]
+		path := fmt.Sprintf("actions[%d]", i)
+		if !IsNameValid(a.Name) || a.Name == "" {
+			v.errorf(path+".name", "invalid action name %q", a.Name)
+		} else if names[a.Name] {
+			v.errorf(path+".name", "duplicate action %s", a.Name)
+		}
+		names[a.Name] = true
+		v.checkStructType(path+".type", a.Type, "action "+a.Name)
+	}
+	results := make(map[string]bool)
+	for i := range v.abi.ActionResults {
+		r := &v.abi.ActionResults[i]
+		path := fmt.Sprintf("action_results[%d]", i)
+		if !names[r.Name] {
+			v.errorf(path+".name", "result of unknown action %q", r.Name)
+		} else if results[r.Name] {
+			v.errorf(path+".name", "duplicate result of action %s", r.Name)
+		}
+		results[r.Name] = true
+		v.checkType(path+".result_type", r.ResultType)
+	}
+}
+
+func (v *abiValidator) checkTables() {
+	names := make(map[string]bool)
+	for i := range v.abi.Tables {
+		tb := &v.abi.Tables[i]
+		path := fmt.Sprintf("tables[%d]", i)
+		if !IsNameValid(tb.Name) || tb.Name == "" {
+			v.errorf(path+".name", "invalid table name %q", tb.Name)
+		} else if names[tb.Name] {
+			v.errorf(path+".name", "duplicate table %s", tb.Name)
+		}
+		names[tb.Name] = true
+		v.checkStructType(path+".type", tb.Type, "table "+tb.Name)
+		if len(tb.KeyNames) != len(tb.KeyTypes) {
+			v.warnf(path+".key_names", "table %s has %d key names but %d key types", tb.Name, len(tb.KeyNames), len(tb.KeyTypes))
+		}
+	}
+}
+
+func (v *abiValidator) checkMisc() {
+	if !strings.HasPrefix(v.abi.Version, "eosio::abi/1.") {
+		v.warnf("version", "unsupported abi version %q", v.abi.Version)
+	}
+
+	ids := make(map[string]bool)
+	for i := range v.abi.RicardianClauses {
+		c := &v.abi.RicardianClauses[i]
+		if ids[c.Id] {
+			v.warnf(fmt.Sprintf("ricardian_clauses[%d].id", i), "duplicate ricardian clause %s", c.Id)
+		}
+		ids[c.Id] = true
+	}
+
+	codes := make(map[uint64]bool)
+	for i := range v.abi.ErrorMessages {
+		e := &v.abi.ErrorMessages[i]
+		if codes[e.ErrorCode] {
+			v.errorf(fmt.Sprintf("error_messages[%d].error_code", i), "duplicate error code %d", e.ErrorCode)
+		}
+		codes[e.ErrorCode] = true
+	}
+}
@@ -11,16 +11,27 @@ type PermissionLevel struct {
 
 func (t *PermissionLevel) Pack() []byte {
 	enc := NewEncoder(16)
-	enc.Pack(&t.Actor)
-	enc.Pack(&t.Permission)
+	t.PackTo(enc)
 	return enc.GetBytes()
 }
 
+func (t *PermissionLevel) PackTo(enc *Encoder) {
+	enc.PackName(t.Actor)
+	enc.PackName(t.Permission)
+}
+
 func (t *PermissionLevel) Unpack(data []byte) (int, error) {
-	dec := NewDecoder(data)
-	dec.Unpack(&t.Actor)
-	dec.Unpack(&t.Permission)
-	return dec.Pos(), nil
+	return unpackBytes(data, t)
+}
+
+func (t *PermissionLevel) UnpackFrom(dec *Decoder) error {
+	if _, err := dec.Unpack(&t.Actor); err != nil {
+		return err
+	}
+	if _, err := dec.Unpack(&t.Permission); err != nil {
+		return err
+	}
+	return nil
 }
 
 func (t *PermissionLevel) Size() int {
@@ -86,15 +97,17 @@ func (a *Action) SetData(data []byte) {
 }
 
 func (a *Action) Pack() []byte {
-	enc := NewEncoder(a.Size())
+	return packBytes(a)
+}
+
+func (a *Action) PackTo(enc *Encoder) {
 	enc.PackName(a.Account)
 	enc.PackName(a.Name)
 	enc.PackLength(len(a.Authorization))
-	for _, v := range a.Authorization {
-		enc.Pack(&v)
+	for i := range a.Authorization {
+		a.Authorization[i].PackTo(enc)
 	}
-	enc.Pack(([]byte)(a.Data))
-	return enc.GetBytes()
+	enc.PackBytes(a.Data)
 	// buf := []byte{}
 	// buf = append(buf, PackUint64(a.Account)...)
 	// buf = append(buf, PackUint64(a.Name)...)
@@ -109,19 +122,30 @@ func (a *Action) Pack() []byte {
 }
 
 func (a *Action) Unpack(b []byte) (int, error) {
-	dec := NewDecoder(b)
-	dec.Unpack(&a.Account)
-	dec.Unpack(&a.Name)
-	length, err := dec.UnpackLength()
+	return unpackBytes(b, a)
+}
+
+func (a *Action) UnpackFrom(dec *Decoder) error {
+	if _, err := dec.Unpack(&a.Account); err != nil {
+		return err
+	}
+	if _, err := dec.Unpack(&a.Name); err != nil {
+		return err
+	}
+	length, err := dec.unpackCount(16)
 	if err != nil {
-		return 0, err
+		return err
 	}
 	a.Authorization = make([]PermissionLevel, length)
 	for i := 0; i < length; i++ {
-		dec.Unpack(&a.Authorization[i])
+		if _, err := dec.Unpack(&a.Authorization[i]); err != nil {
+			return err
+		}
+	}
+	if _, err := dec.Unpack(&a.Data); err != nil {
+		return err
 	}
-	dec.Unpack(&a.Data)
-	return dec.Pos(), nil
+	return nil
 }
 
 func (a *Action) Size() int {
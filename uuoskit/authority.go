@@ -0,0 +1,187 @@
+package uuoskit
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+
+	secp256k1 "github.com/armoniax/go-secp256k1"
+)
+
+type KeyWeight struct {
+	Key    secp256k1.PublicKey
+	Weight uint16
+}
+
+type keyWeightJson struct {
+	Key    string `json:"key"`
+	Weight uint16 `json:"weight"`
+}
+
+// MarshalJSON renders the key in the PUB_K1_ format
+func (t KeyWeight) MarshalJSON() ([]byte, error) {
+	return json.Marshal(keyWeightJson{t.Key.String(), t.Weight})
+}
+
+func (t *KeyWeight) UnmarshalJSON(b []byte) error {
+	var v keyWeightJson
+	if err := json.Unmarshal(b, &v); err != nil {
+		return newError(err)
+	}
+	pub, err := ParsePublicKey(v.Key)
+	if err != nil {
+		return err
+	}
+	t.Key = *pub
+	t.Weight = v.Weight
+	return nil
+}
+
+type PermissionLevelWeight struct {
+	Permission PermissionLevel `json:"permission"`
+	Weight     uint16          `json:"weight"`
+}
+
+type WaitWeight struct {
+	WaitSec uint32 `json:"wait_sec"`
+	Weight  uint16 `json:"weight"`
+}
+
+// Authority is the authority struct of the system contract. The chain
+// rejects authorities whose keys, accounts or waits are not sorted, Sort
+// puts them in the required order.
+type Authority struct {
+	Threshold uint32                  `json:"threshold"`
+	Keys      []KeyWeight             `json:"keys"`
+	Accounts  []PermissionLevelWeight `json:"accounts"`
+	Waits     []WaitWeight            `json:"waits"`
+}
+
+// NewKeyAuthority returns an authority satisfied by a single key
+func NewKeyAuthority(pub *secp256k1.PublicKey) Authority {
+	return Authority{Threshold: 1, Keys: []KeyWeight{{*pub, 1}}}
+}
+
+// NewAccountAuthority returns an authority satisfied by a single permission
+func NewAccountAuthority(actor Name, permission Name) Authority {
+	return Authority{
+		Threshold: 1,
+		Accounts:  []PermissionLevelWeight{{PermissionLevel{Actor: actor, Permission: permission}, 1}},
+	}
+}
+
+func lessPermissionLevel(a, b *PermissionLevel) bool {
+	if a.Actor != b.Actor {
+		return a.Actor.N < b.Actor.N
+	}
+	return a.Permission.N < b.Permission.N
+}
+
+func (t *Authority) Sort() {
+	sort.SliceStable(t.Keys, func(i, j int) bool {
+		return bytes.Compare(t.Keys[i].Key.Data[:], t.Keys[j].Key.Data[:]) < 0
+	})
+	sort.SliceStable(t.Accounts, func(i, j int) bool {
+		return lessPermissionLevel(&t.Accounts[i].Permission, &t.Accounts[j].Permission)
+	})
+	sort.SliceStable(t.Waits, func(i, j int) bool {
+		return t.Waits[i].WaitSec < t.Waits[j].WaitSec
+	})
+}
+
+func (t *Authority) keyIndex(pub *secp256k1.PublicKey) int {
+	for i := range t.Keys {
+		if t.Keys[i].Key.Data == pub.Data {
+			return i
+		}
+	}
+	return -1
+}
+
+// AddKey adds pub with weight and keeps the keys sorted
+func (t *Authority) AddKey(pub *secp256k1.PublicKey, weight uint16) error {
+	if t.keyIndex(pub) >= 0 {
+		return newErrorf("key %s already in authority", pub.String())
+	}
+	t.Keys = append(t.Keys, KeyWeight{*pub, weight})
+	t.Sort()
+	return nil
+}
+
+// RemoveKey removes pub, the threshold is left unchanged
+func (t *Authority) RemoveKey(pub *secp256k1.PublicKey) error {
+	i := t.keyIndex(pub)
+	if i < 0 {
+		return newErrorf("key %s not in authority", pub.String())
+	}
+	t.Keys = append(t.Keys[:i:i], t.Keys[i+1:]...)
+	return nil
+}
+
+// Validate applies the checks of the chain: sorted unique entries, non
+// zero weights and a threshold the weights can reach.
+func (t *Authority) Validate() error {
+	if t.Threshold == 0 {
+		return newErrorf("authority threshold must be positive")
+	}
+	total := uint64(0)
+	for i := range t.Keys {
+		if t.Keys[i].Weight == 0 {
+			return newErrorf("authority key weight must be positive")
+		}
+		if i > 0 && bytes.Compare(t.Keys[i-1].Key.Data[:], t.Keys[i].Key.Data[:]) >= 0 {
+			return newErrorf("authority keys must be sorted and unique")
+		}
+		total += uint64(t.Keys[i].Weight)
+	}
+	for i := range t.Accounts {
+		if t.Accounts[i].Weight == 0 {
+			return newErrorf("authority account weight must be positive")
+		}
+		if i > 0 && !lessPermissionLevel(&t.Accounts[i-1].Permission, &t.Accounts[i].Permission) {
+			return newErrorf("authority accounts must be sorted and unique")
+		}
+		total += uint64(t.Accounts[i].Weight)
+	}
+	for i := range t.Waits {
+		if t.Waits[i].Weight == 0 {
+			return newErrorf("authority wait weight must be positive")
+		}
+		if i > 0 && t.Waits[i-1].WaitSec > t.Waits[i].WaitSec {
+			return newErrorf("authority waits must be sorted")
+		}
+		total += uint64(t.Waits[i].Weight)
+	}
+	if total < uint64(t.Threshold) {
+		return newErrorf("authority threshold %d can not be reached", t.Threshold)
+	}
+	return nil
+}
+
+func (t *Authority) Pack() []byte {
+	enc := NewEncoder(t.Size())
+	enc.PackUint32(t.Threshold)
+	enc.PackLength(len(t.Keys))
+	for i := range t.Keys {
+		enc.PackPublicKey(&t.Keys[i].Key)
+		enc.PackUint16(t.Keys[i].Weight)
+	}
+	enc.PackLength(len(t.Accounts))
+	for i := range t.Accounts {
+		enc.Pack(&t.Accounts[i].Permission)
+		enc.PackUint16(t.Accounts[i].Weight)
+	}
+	enc.PackLength(len(t.Waits))
+	for i := range t.Waits {
+		enc.PackUint32(t.Waits[i].WaitSec)
+		enc.PackUint16(t.Waits[i].Weight)
+	}
+	return enc.GetBytes()
+}
+
+func (t *Authority) Size() int {
+	return 4 +
+		PackedVarUint32Length(uint32(len(t.Keys))) + len(t.Keys)*(PublicKeySize+2) +
+		PackedVarUint32Length(uint32(len(t.Accounts))) + len(t.Accounts)*(16+2) +
+		PackedVarUint32Length(uint32(len(t.Waits))) + len(t.Waits)*(4+2)
+}
@@ -0,0 +1,274 @@
+package uuoskit
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"math/big"
+	"strings"
+)
+
+// Int128, Uint128 and Uint256 are stored little endian in two's complement,
+// the same way they are packed on chain.
+
+// leToBig converts little endian bytes to a big.Int
+func leToBig(b []byte, signed bool) *big.Int {
+	be := make([]byte, len(b))
+	for i := range b {
+		be[len(b)-1-i] = b[i]
+	}
+	n := new(big.Int).SetBytes(be)
+	if signed && b[len(b)-1]&0x80 != 0 {
+		n.Sub(n, new(big.Int).Lsh(big.NewInt(1), uint(len(b)*8)))
+	}
+	return n
+}
+
+// bigToLE writes v into dst as little endian bytes, it fails if v does not
+// fit in len(dst) bytes.
+func bigToLE(v *big.Int, dst []byte, signed bool) error {
+	bits := uint(len(dst) * 8)
+	min, max := new(big.Int), new(big.Int).Lsh(big.NewInt(1), bits)
+	if signed {
+		max.Rsh(max, 1)
+		min.Neg(max)
+	}
+	if v.Cmp(min) < 0 || v.Cmp(max) >= 0 {
+		return newErrorf("value %s out of range for %d bit integer", v.String(), bits)
+	}
+	n := v
+	if v.Sign() < 0 {
+		n = new(big.Int).Lsh(big.NewInt(1), bits)
+		n.Add(n, v)
+	}
+	be := n.Bytes()
+	for i := range dst {
+		dst[i] = 0
+	}
+	for i := range be {
+		dst[i] = be[len(be)-1-i]
+	}
+	return nil
+}
+
+// parseBigInt parses a decimal value or a 0x prefixed big endian hex value,
+// the latter being the raw two's complement bits as produced by the ABI
+// unpacker.
+func parseBigInt(s string, size int, signed bool) (*big.Int, error) {
+	if strings.HasPrefix(s, "0x") {
+		bs, err := hex.DecodeString(s[2:])
+		if err != nil {
+			return nil, newError(err)
+		}
+		if len(bs) == 0 || len(bs) > size {
+			return nil, newErrorf("invalid %d bit hex value: %s", size*8, s)
+		}
+		le := make([]byte, size)
+		for i := range bs {
+			le[i] = bs[len(bs)-1-i]
+		}
+		return leToBig(le, signed), nil
+	}
+	n, ok := new(big.Int).SetString(s, 10)
+	if !ok {
+		return nil, newErrorf("invalid %d bit integer: %s", size*8, s)
+	}
+	return n, nil
+}
+
+// unmarshalBigInt accepts a JSON number or a string holding either a
+// decimal or a 0x prefixed hex value.
+func unmarshalBigInt(b []byte, dst []byte, signed bool) error {
+	s := string(b)
+	if strings.HasPrefix(s, "\"") {
+		if err := json.Unmarshal(b, &s); err != nil {
+			return newError(err)
+		}
+	}
+	n, err := parseBigInt(s, len(dst), signed)
+	if err != nil {
+		return err
+	}
+	return bigToLE(n, dst, signed)
+}
+
+func NewInt128(v *big.Int) (Int128, error) {
+	n := Int128{}
+	err := n.SetBigInt(v)
+	return n, err
+}
+
+func (n *Int128) SetBigInt(v *big.Int) error {
+	return bigToLE(v, n[:], true)
+}
+
+func (n Int128) BigInt() *big.Int {
+	return leToBig(n[:], true)
+}
+
+func (n *Int128) SetInt64(v int64) {
+	bigToLE(big.NewInt(v), n[:], true)
+}
+
+// SetString parses a decimal or a 0x prefixed hex value
+func (n *Int128) SetString(s string) error {
+	v, err := parseBigInt(s, 16, true)
+	if err != nil {
+		return err
+	}
+	return n.SetBigInt(v)
+}
+
+func (n Int128) String() string {
+	return n.BigInt().String()
+}
+
+func (n Int128) MarshalJSON() ([]byte, error) {
+	return json.Marshal(n.String())
+}
+
+func (n *Int128) UnmarshalJSON(b []byte) error {
+	return unmarshalBigInt(b, n[:], true)
+}
+
+func (n Int128) Cmp(b Int128) int {
+	return n.BigInt().Cmp(b.BigInt())
+}
+
+func (n Int128) Add(b Int128) (Int128, error) {
+	return NewInt128(new(big.Int).Add(n.BigInt(), b.BigInt()))
+}
+
+func (n Int128) Sub(b Int128) (Int128, error) {
+	return NewInt128(new(big.Int).Sub(n.BigInt(), b.BigInt()))
+}
+
+func (n Int128) Mul(b Int128) (Int128, error) {
+	return NewInt128(new(big.Int).Mul(n.BigInt(), b.BigInt()))
+}
+
+// Div truncates toward zero like C++ integer division
+func (n Int128) Div(b Int128) (Int128, error) {
+	d := b.BigInt()
+	if d.Sign() == 0 {
+		return Int128{}, newErrorf("Int128.Div: divide by zero")
+	}
+	return NewInt128(new(big.Int).Quo(n.BigInt(), d))
+}
+
+func NewUint128(v *big.Int) (Uint128, error) {
+	n := Uint128{}
+	err := n.SetBigInt(v)
+	return n, err
+}
+
+func (n *Uint128) SetBigInt(v *big.Int) error {
+	return bigToLE(v, n[:], false)
+}
+
+func (n Uint128) BigInt() *big.Int {
+	return leToBig(n[:], false)
+}
+
+// SetString parses a decimal or a 0x prefixed hex value
+func (n *Uint128) SetString(s string) error {
+	v, err := parseBigInt(s, 16, false)
+	if err != nil {
+		return err
+	}
+	return n.SetBigInt(v)
+}
+
+func (n Uint128) String() string {
+	return n.BigInt().String()
+}
+
+func (n Uint128) MarshalJSON() ([]byte, error) {
+	return json.Marshal(n.String())
+}
+
+func (n *Uint128) UnmarshalJSON(b []byte) error {
+	return unmarshalBigInt(b, n[:], false)
+}
+
+func (n Uint128) Cmp(b Uint128) int {
+	return n.BigInt().Cmp(b.BigInt())
+}
+
+func (n Uint128) Add(b Uint128) (Uint128, error) {
+	return NewUint128(new(big.Int).Add(n.BigInt(), b.BigInt()))
+}
+
+func (n Uint128) Sub(b Uint128) (Uint128, error) {
+	return NewUint128(new(big.Int).Sub(n.BigInt(), b.BigInt()))
+}
+
+func (n Uint128) Mul(b Uint128) (Uint128, error) {
+	return NewUint128(new(big.Int).Mul(n.BigInt(), b.BigInt()))
+}
+
+func (n Uint128) Div(b Uint128) (Uint128, error) {
+	d := b.BigInt()
+	if d.Sign() == 0 {
+		return Uint128{}, newErrorf("Uint128.Div: divide by zero")
+	}
+	return NewUint128(new(big.Int).Quo(n.BigInt(), d))
+}
+
+func NewUint256(v *big.Int) (Uint256, error) {
+	n := Uint256{}
+	err := n.SetBigInt(v)
+	return n, err
+}
+
+func (n *Uint256) SetBigInt(v *big.Int) error {
+	return bigToLE(v, n[:], false)
+}
+
+func (n Uint256) BigInt() *big.Int {
+	return leToBig(n[:], false)
+}
+
+// SetString parses a decimal or a 0x prefixed hex value
+func (n *Uint256) SetString(s string) error {
+	v, err := parseBigInt(s, 32, false)
+	if err != nil {
+		return err
+	}
+	return n.SetBigInt(v)
+}
+
+func (n Uint256) String() string {
+	return n.BigInt().String()
+}
+
+func (n Uint256) MarshalJSON() ([]byte, error) {
+	return json.Marshal(n.String())
+}
+
+func (n *Uint256) UnmarshalJSON(b []byte) error {
+	return unmarshalBigInt(b, n[:], false)
+}
+
+func (n Uint256) Cmp(b Uint256) int {
+	return n.BigInt().Cmp(b.BigInt())
+}
+
+func (n Uint256) Add(b Uint256) (Uint256, error) {
+	return NewUint256(new(big.Int).Add(n.BigInt(), b.BigInt()))
+}
+
+func (n Uint256) Sub(b Uint256) (Uint256, error) {
+	return NewUint256(new(big.Int).Sub(n.BigInt(), b.BigInt()))
+}
+
+func (n Uint256) Mul(b Uint256) (Uint256, error) {
+	return NewUint256(new(big.Int).Mul(n.BigInt(), b.BigInt()))
+}
+
+func (n Uint256) Div(b Uint256) (Uint256, error) {
+	d := b.BigInt()
+	if d.Sign() == 0 {
+		return Uint256{}, newErrorf("Uint256.Div: divide by zero")
+	}
+	return NewUint256(new(big.Int).Quo(n.BigInt(), d))
+}
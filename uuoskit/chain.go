@@ -1,6 +1,9 @@
 package uuoskit
 
-import "encoding/json"
+import (
+	"encoding/json"
+	"sync"
+)
 
 // {'server_version': '6d383cb1',
 //  'chain_id': '9b1605a3f7f14995641c6b19413841c26ca86747f054241951a298b556160674',
@@ -48,14 +51,81 @@ func NewChainInfo(info []byte) (*ChainInfo, error) {
 	return chainInfo, nil
 }
 
+// ChainContext is safe for concurrent use, PackedTxs should only be
+// accessed through AddPackedTx, GetPackedTx and RemovePackedTx.
 type ChainContext struct {
+	mu            sync.RWMutex
+	Profile       *ChainProfile
 	ABISerializer *ABISerializer
-	PackedTxs     []*PackedTransaction
+	// Wallet shares the keys of the global wallet and uses Profile
+	Wallet    *Wallet
+	PackedTxs []*PackedTransaction
 }
 
+const maxPackedTxs = 1024
+
 func NewChainContext() *ChainContext {
+	return NewChainContextWithProfile(DefaultChainProfile())
+}
+
+func NewChainContextWithProfile(profile *ChainProfile) *ChainContext {
 	return &ChainContext{
-		ABISerializer: NewABISerializer(),
-		PackedTxs:     make([]*PackedTransaction, 0, 1024),
+		Profile:       profile,
+		ABISerializer: NewABISerializerWithProfile(profile),
+		Wallet:        GetWallet().WithChainProfile(profile),
+		PackedTxs:     make([]*PackedTransaction, 0, maxPackedTxs),
+	}
+}
+
+// AddPackedTx stores packedTx in the first free slot and returns its index,
+// -1 is returned if all slots are in use.
+func (ctx *ChainContext) AddPackedTx(packedTx *PackedTransaction) int {
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
+	for i := 0; i < len(ctx.PackedTxs); i++ {
+		if ctx.PackedTxs[i] == nil {
+			ctx.PackedTxs[i] = packedTx
+			return i
+		}
+	}
+
+	if len(ctx.PackedTxs) >= maxPackedTxs {
+		return -1
+	}
+
+	ctx.PackedTxs = append(ctx.PackedTxs, packedTx)
+	return len(ctx.PackedTxs) - 1
+}
+
+func (ctx *ChainContext) GetPackedTx(index int) (*PackedTransaction, error) {
+	ctx.mu.RLock()
+	defer ctx.mu.RUnlock()
+	if index < 0 || index >= len(ctx.PackedTxs) {
+		return nil, newErrorf("invalid transaction index %d", index)
+	}
+
+	if ctx.PackedTxs[index] == nil {
+		return nil, newErrorf("transaction at index %d is nil!", index)
+	}
+	return ctx.PackedTxs[index], nil
+}
+
+func (ctx *ChainContext) RemovePackedTx(index int) error {
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
+	if index < 0 || index >= len(ctx.PackedTxs) {
+		return newErrorf("bad transaction index %d", index)
+	}
+	ctx.PackedTxs[index] = nil
+	return nil
+}
+
+// SignPackedTx signs the transaction at index with the key of pubKey in
+// ctx.Wallet.
+func (ctx *ChainContext) SignPackedTx(index int, pubKey string) (string, error) {
+	packedTx, err := ctx.GetPackedTx(index)
+	if err != nil {
+		return "", err
 	}
+	return packedTx.SignWithWallet(ctx.Wallet, pubKey)
 }
@@ -1,23 +1,42 @@
 package uuoskit
 
 import (
+	"encoding/hex"
 	"encoding/json"
 	"io/ioutil"
 	"log"
+	"net/http"
 	"time"
 )
 
 type ChainApi struct {
 	rpc           *Rpc
+	Profile       *ChainProfile
 	ABISerializer *ABISerializer
+	// Wallet shares the keys of the global wallet and uses Profile
+	Wallet *Wallet
 }
 
 func NewChainApi(rpcUrl string) *ChainApi {
+	return NewChainApiWithProfile(rpcUrl, DefaultChainProfile())
+}
+
+func NewChainApiWithProfile(rpcUrl string, profile *ChainProfile) *ChainApi {
 	rpc := NewRpc(rpcUrl)
-	chainApi := &ChainApi{rpc: rpc, ABISerializer: NewABISerializer()}
+	chainApi := &ChainApi{
+		rpc:           rpc,
+		Profile:       profile,
+		ABISerializer: NewABISerializerWithProfile(profile),
+		Wallet:        GetWallet().WithChainProfile(profile),
+	}
 	return chainApi
 }
 
+// SetTransport sets the transport of the requests sent to the node
+func (api *ChainApi) SetTransport(transport http.RoundTripper) {
+	api.rpc.SetTransport(transport)
+}
+
 func (api *ChainApi) GetAccount(name string) (JsonValue, error) {
 	return api.rpc.GetAccount(&GetAccountArgs{AccountName: name})
 }
@@ -51,6 +70,45 @@ func (api *ChainApi) GetTableRows(
 	return api.rpc.GetTableRows(&args)
 }
 
+// decodeTableRows reads the rows of table between lower and upper in
+// binary, decodes them as abiType with the ABI cached as abiContract and
+// calls fn with the JSON of each row.
+func (api *ChainApi) decodeTableRows(abiContract string, code string, scope string, table string, abiType string, lower string, upper string, fn func(row []byte) error) error {
+	for {
+		r, err := api.GetTableRows(false, code, scope, table, lower, upper, 100, "", 0, false, false)
+		if err != nil {
+			return err
+		}
+		rows, err := r.GetArray("rows")
+		if err != nil {
+			return newErrorf("get_table_rows %s: %s", table, r.String())
+		}
+		for _, row := range rows {
+			s, ok := row.GetStringValue()
+			if !ok {
+				return newErrorf("get_table_rows %s: row is not hex", table)
+			}
+			packed, err := hex.DecodeString(s)
+			if err != nil {
+				return newError(err)
+			}
+			data, err := api.ABISerializer.UnpackAbiType(abiContract, abiType, packed)
+			if err != nil {
+				return err
+			}
+			if err := fn(data); err != nil {
+				return err
+			}
+		}
+		more, _ := r.GetBool("more")
+		nextKey, _ := r.GetString("next_key")
+		if !more || nextKey == "" || len(rows) == 0 {
+			return nil
+		}
+		lower = nextKey
+	}
+}
+
 func (api *ChainApi) DeployContract(account, codeFile string, abiFile string) error {
 	code, err := ioutil.ReadFile(codeFile)
 	if err != nil {
@@ -77,7 +135,7 @@ func (api *ChainApi) DeployContract(account, codeFile string, abiFile string) er
 	tx.SetReferenceBlock(chainInfo.LastIrreversibleBlockID)
 
 	action := NewAction(
-		NewName("eosio"),
+		api.Profile.SystemAccount,
 		NewName("setcode"),
 		[]PermissionLevel{{NewName(account), NewName("active")}},
 		NewName(account),
@@ -88,7 +146,7 @@ func (api *ChainApi) DeployContract(account, codeFile string, abiFile string) er
 	tx.AddAction(action)
 
 	action = NewAction(
-		NewName("eosio"),
+		api.Profile.SystemAccount,
 		NewName("setabi"),
 		[]PermissionLevel{{NewName(account), NewName("active")}},
 		NewName(account), //account
@@ -102,7 +160,7 @@ func (api *ChainApi) DeployContract(account, codeFile string, abiFile string) er
 
 	args := GetRequiredKeysArgs{
 		Transaction:   tx,
-		AvailableKeys: GetWallet().GetPublicKeys(),
+		AvailableKeys: api.Wallet.GetPublicKeys(),
 	}
 	r, err := api.rpc.GetRequiredKeys(&args)
 	if err != nil {
@@ -111,7 +169,7 @@ func (api *ChainApi) DeployContract(account, codeFile string, abiFile string) er
 
 	for i := range r.RequiredKeys {
 		pub := r.RequiredKeys[i]
-		_, err = packedTx.Sign(pub)
+		_, err = packedTx.SignWithWallet(api.Wallet, pub)
 		if err != nil {
 			return newError(err)
 		}
@@ -123,7 +181,7 @@ func (api *ChainApi) DeployContract(account, codeFile string, abiFile string) er
 	}
 
 	if _, err := r2.Get("error"); err == nil {
-		if msg, err := r2.Get("error", "details", 0, "message"); err == nil {
+		if msg, err := r2.GetString("error", "details", 0, "message"); err == nil {
 			log.Println(msg)
 			if msg == "contract is already running this version of code" {
 				return nil
@@ -141,7 +199,7 @@ func (api *ChainApi) DeployContract(account, codeFile string, abiFile string) er
 func (api *ChainApi) getRequiredKeys(actions []Action) ([]string, error) {
 	args := GetRequiredKeysArgs{
 		Transaction:   NewTransaction(0),
-		AvailableKeys: GetWallet().GetPublicKeys(),
+		AvailableKeys: api.Wallet.GetPublicKeys(),
 	}
 	for i := range actions {
 		a := actions[i]
@@ -173,10 +231,12 @@ func (api *ChainApi) PushAction(action *Action) (JsonValue, error) {
 	return api.PushActions([]*Action{action})
 }
 
-func (api *ChainApi) PushActions(actions []*Action) (JsonValue, error) {
+// newTransaction returns a transaction of actions referencing the last
+// irreversible block, and the chain id.
+func (api *ChainApi) newTransaction(actions []*Action) (*Transaction, string, error) {
 	chainInfo, err := api.rpc.GetInfo()
 	if err != nil {
-		return JsonValue{}, err
+		return nil, "", err
 	}
 
 	expiration := int(time.Now().Unix()) + 60
@@ -186,8 +246,14 @@ func (api *ChainApi) PushActions(actions []*Action) (JsonValue, error) {
 		a := actions[i]
 		tx.AddAction(a)
 	}
+	return tx, chainInfo.ChainID, nil
+}
 
-	chainId := chainInfo.ChainID
+func (api *ChainApi) PushActions(actions []*Action) (JsonValue, error) {
+	tx, chainId, err := api.newTransaction(actions)
+	if err != nil {
+		return JsonValue{}, err
+	}
 
 	//FIXME: hardcode public key
 	packedTx := NewPackedTransaction(tx)
@@ -200,7 +266,7 @@ func (api *ChainApi) PushActions(actions []*Action) (JsonValue, error) {
 
 	for i := range pubKeys {
 		pub := pubKeys[i]
-		_, err = packedTx.Sign(pub)
+		_, err = packedTx.SignWithWallet(api.Wallet, pub)
 		if err != nil {
 			return JsonValue{}, err
 		}
@@ -211,12 +277,100 @@ func (api *ChainApi) PushActions(actions []*Action) (JsonValue, error) {
 		return JsonValue{}, err
 	}
 	if _, err := r2.Get("error"); err == nil {
-		msg, err := r2.Get("error", "details", 0, "message")
+		msg, err := r2.GetString("error", "details", 0, "message")
 		if err == nil {
-			return r2, newErrorf(msg.(string))
+			return r2, newErrorf("%s", msg)
 		}
 		log.Println(err)
 		return r2, newErrorf("push_transaction error")
 	}
 	return r2, nil
 }
+
+// CreateAccountResult is returned by ChainApi.CreateAccount
+type CreateAccountResult struct {
+	// OwnerKey and ActiveKey hold the generated key pairs in the CreateKey
+	// format, they are nil when the authority was given. Generated private
+	// keys are not imported into the wallet, callers that sign for the new
+	// account import them with Wallet.Import.
+	OwnerKey    map[string]string
+	ActiveKey   map[string]string
+	Transaction JsonValue
+	Account     JsonValue
+}
+
+func (api *ChainApi) generateKey() (map[string]string, *Authority) {
+	priv := GeneratePrivateKey()
+	pub := priv.GetPublicKey()
+	auth := NewKeyAuthority(pub)
+	return map[string]string{
+		"private": priv.String(),
+		"public":  api.Profile.PublicKeyString(pub),
+	}, &auth
+}
+
+// CreateAccount creates name with newaccount, buys ram bytes of RAM for it
+// with buyrambytes and stakes net and cpu with delegatebw, all in a single
+// transaction authorized by creator@active. A nil authority gets a newly
+// generated key, zero ram or zero net and cpu skip the matching action.
+// Stakes are not transferred, creator keeps ownership of them.
+// The account is looked up after the transaction to verify it exists.
+func (api *ChainApi) CreateAccount(creator string, name string, ownerAuth *Authority, activeAuth *Authority, ram uint32, cpu Asset, net Asset) (*CreateAccountResult, error) {
+	_creator, err := ParseName(creator)
+	if err != nil {
+		return nil, err
+	}
+	_name, err := ParseName(name)
+	if err != nil {
+		return nil, err
+	}
+	if err := CheckNewAccountName(api.Profile.SystemAccount, _creator, _name); err != nil {
+		return nil, err
+	}
+
+	result := &CreateAccountResult{}
+	if ownerAuth == nil {
+		result.OwnerKey, ownerAuth = api.generateKey()
+	}
+	if activeAuth == nil {
+		result.ActiveKey, activeAuth = api.generateKey()
+	}
+	if err := ownerAuth.Validate(); err != nil {
+		return nil, err
+	}
+	if err := activeAuth.Validate(); err != nil {
+		return nil, err
+	}
+
+	if cpu.Symbol.Value == 0 {
+		cpu.Symbol = api.Profile.CoreSymbol
+	}
+	if net.Symbol.Value == 0 {
+		net.Symbol = api.Profile.CoreSymbol
+	}
+
+	perms := []PermissionLevel{{_creator, NewName("active")}}
+	actions := []*Action{
+		NewAction(api.Profile.SystemAccount, NewName("newaccount"), perms, _creator, _name, ownerAuth, activeAuth),
+	}
+	if ram != 0 {
+		actions = append(actions, NewAction(api.Profile.SystemAccount, NewName("buyrambytes"), perms, _creator, _name, ram))
+	}
+	if cpu.Amount != 0 || net.Amount != 0 {
+		actions = append(actions, NewAction(api.Profile.SystemAccount, NewName("delegatebw"), perms, _creator, _name, &net, &cpu, false))
+	}
+
+	result.Transaction, err = api.PushActions(actions)
+	if err != nil {
+		return result, err
+	}
+
+	result.Account, err = api.GetAccount(name)
+	if err != nil {
+		return result, err
+	}
+	if accountName, err := result.Account.GetString("account_name"); err != nil || accountName != name {
+		return result, newErrorf("account %s not found after creation", name)
+	}
+	return result, nil
+}
@@ -1,50 +1,576 @@
 package uuoskit
 
-import "testing"
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
 
-func TestChainApi(t *testing.T) {
-	GetWallet().Import("test", "5JRYimgLBrRLCBAcjHUWCYRv3asNedTYYzVgmiU4q2ZVxMBiJXL")
-	api := NewChainApi("https://testnode.uuos.network:8443")
-	a, _ := api.GetAccount("eosio")
-	v, _ := a.Get("created")
-	t.Logf("++%v\n", v)
-	v, _ = a.Get("last_code_update")
-	t.Logf("++%v\n", v)
+	"github.com/stretchr/testify/assert"
+)
+
+const testCreatorPub = "AM6AjF6hvF7GSuSd4sCgfPKq5uWaXvGM2aQtEUCwmEHygQaqxBSV"
+
+// testNode answers the chain api calls used by ChainApi, pushed
+// transactions are recorded, get_account answers from accounts and
+// get_table_rows from tables, keyed by code/scope/table. The transaction
+// dry-run endpoints answer from traces, keyed by endpoint.
+type testNode struct {
+	*httptest.Server
+	pushed   []Transaction
+	accounts map[string]string
+	tables   map[string][]testRow
+	traces   map[string]string
+	onPush   func(tx *Transaction)
+}
+
+// testRow is a table row packed in hex with its primary key
+type testRow struct {
+	key  Name
+	data string
 }
 
-func TestPushAction(t *testing.T) {
-	GetWallet().Import("test", "5JRYimgLBrRLCBAcjHUWCYRv3asNedTYYzVgmiU4q2ZVxMBiJXL")
-	api := NewChainApi("https://testnode.uuos.network:8443")
-	action := NewAction(NewName("eosio.token"),
-		NewName("transfer"),
-		[]PermissionLevel{{NewName("helloworld11"), NewName("active")}},
-		NewName("helloworld11"),
-		NewName("eosio.token"),
-		NewAsset(1000, NewSymbol("EOS", 4)),
-		"transfer from alice")
-	r, err := api.PushAction(action)
-	if err != nil {
-		t.Error(err)
+func newTestNode(t *testing.T) *testNode {
+	assert.Nil(t, GetWallet().Import("test", "5JRYimgLBrRLCBAcjHUWCYRv3asNedTYYzVgmiU4q2ZVxMBiJXL"))
+	node := &testNode{accounts: map[string]string{}, tables: map[string][]testRow{}, traces: map[string]string{}}
+	node.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/chain/get_info":
+			w.Write([]byte(`{"chain_id":"9b1605a3f7f14995641c6b19413841c26ca86747f054241951a298b556160674",` +
+				`"last_irreversible_block_id":"005a50c451107fd4d94493f152d832a6420aa7945d51974dca56b2a1f3dfe5fe"}`))
+		case "/v1/chain/get_required_keys":
+			w.Write([]byte(fmt.Sprintf(`{"required_keys":["%s"]}`, testCreatorPub)))
+		case "/v1/chain/push_transaction":
+			var packed struct {
+				Signatures []string `json:"signatures"`
+				PackedTrx  Bytes    `json:"packed_trx"`
+			}
+			assert.Nil(t, json.NewDecoder(r.Body).Decode(&packed))
+			assert.Equal(t, 1, len(packed.Signatures))
+			tx := Transaction{}
+			_, err := tx.Unpack(packed.PackedTrx)
+			assert.Nil(t, err)
+			node.pushed = append(node.pushed, tx)
+			if node.onPush != nil {
+				node.onPush(&tx)
+			}
+			w.Write([]byte(`{"transaction_id":"00","processed":{}}`))
+		case "/v1/chain/compute_transaction", "/v1/chain/send_read_only_transaction":
+			var args struct {
+				Transaction struct {
+					Signatures []string `json:"signatures"`
+					PackedTrx  Bytes    `json:"packed_trx"`
+				} `json:"transaction"`
+			}
+			assert.Nil(t, json.NewDecoder(r.Body).Decode(&args))
+			assert.Equal(t, 0, len(args.Transaction.Signatures))
+			assert.NotEqual(t, 0, len(args.Transaction.PackedTrx))
+			if trace, ok := node.traces[strings.TrimPrefix(r.URL.Path, "/v1/chain/")]; ok {
+				w.Write([]byte(trace))
+			} else {
+				w.Write([]byte(`{"code":404,"message":"Not Found","error":{"code":0,"name":"exception","what":"unknown"}}`))
+			}
+		case "/v1/chain/get_account":
+			var args GetAccountArgs
+			assert.Nil(t, json.NewDecoder(r.Body).Decode(&args))
+			if account, ok := node.accounts[args.AccountName]; ok {
+				w.Write([]byte(account))
+			} else {
+				w.Write([]byte(`{"code":500,"message":"Internal Service Error","error":{"name":"unknown_key_exception"}}`))
+			}
+		case "/v1/chain/get_table_rows":
+			var args GetTableRowsArgs
+			assert.Nil(t, json.NewDecoder(r.Body).Decode(&args))
+			assert.False(t, args.Json)
+			rows := []string{}
+			for _, row := range node.tables[args.Code+"/"+args.Scope+"/"+args.Table] {
+				if args.LowerBound != "" && row.key.N < NewName(args.LowerBound).N ||
+					args.UpperBound != "" && row.key.N > NewName(args.UpperBound).N {
+					continue
+				}
+				rows = append(rows, row.data)
+			}
+			b, _ := json.Marshal(map[string]interface{}{"rows": rows, "more": false, "next_key": ""})
+			w.Write(b)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	return node
+}
+
+func TestCreateAccount(t *testing.T) {
+	node := newTestNode(t)
+	defer node.Close()
+	node.onPush = func(tx *Transaction) {
+		for _, a := range tx.Actions {
+			if a.Name == NewName("newaccount") {
+				var name Name
+				name.Unpack(a.Data[8:])
+				node.accounts[name.String()] = fmt.Sprintf(`{"account_name":"%s"}`, name.String())
+			}
+		}
 	}
 
-	if false {
-		t.Log(r)
+	api := NewChainApiWithProfile(node.URL, EOSProfile())
+	s := NewABISerializer()
+	assert.Nil(t, s.SetContractABI("eosio", []byte(testSystemAbi)))
+
+	net, err := ParseAsset("1.0000 EOS")
+	assert.Nil(t, err)
+	r, err := api.CreateAccount("helloworld11", "newaccount11", nil, nil, 8192, Asset{}, *net)
+	assert.Nil(t, err)
+	assert.NotNil(t, r.OwnerKey)
+	assert.NotNil(t, r.ActiveKey)
+	assert.True(t, strings.HasPrefix(r.OwnerKey["public"], "EOS"))
+	assert.NotEqual(t, r.OwnerKey["private"], r.ActiveKey["private"])
+	_, err = GetWallet().GetPrivateKey(r.ActiveKey["public"])
+	assert.NotNil(t, err)
+	assert.Equal(t, 1, len(node.pushed))
+
+	actions := node.pushed[0].Actions
+	assert.Equal(t, 3, len(actions))
+	expected := []string{"newaccount", "buyrambytes", "delegatebw"}
+	for i, a := range actions {
+		assert.Equal(t, "eosio", a.Account.String())
+		assert.Equal(t, expected[i], a.Name.String())
+		assert.Equal(t, []PermissionLevel{{NewName("helloworld11"), NewName("active")}}, a.Authorization)
+	}
+	args, err := s.UnpackActionArgs("eosio", "newaccount", actions[0].Data)
+	assert.Nil(t, err)
+	assert.Equal(t, fmt.Sprintf(`{"creator":"helloworld11","name":"newaccount11",`+
+		`"owner":{"threshold":1,"keys":[{"key":"%s","weight":1}],"accounts":[],"waits":[]},`+
+		`"active":{"threshold":1,"keys":[{"key":"%s","weight":1}],"accounts":[],"waits":[]}}`,
+		"AM"+r.OwnerKey["public"][3:], "AM"+r.ActiveKey["public"][3:]), string(args))
+	args, err = s.UnpackActionArgs("eosio", "buyrambytes", actions[1].Data)
+	assert.Nil(t, err)
+	assert.Equal(t, `{"payer":"helloworld11","receiver":"newaccount11","bytes":8192}`, string(args))
+	args, err = s.UnpackActionArgs("eosio", "delegatebw", actions[2].Data)
+	assert.Nil(t, err)
+	assert.Equal(t, `{"from":"helloworld11","receiver":"newaccount11","stake_net_quantity":"1.0000 EOS",`+
+		`"stake_cpu_quantity":"0.0000 EOS","transfer":false}`, string(args))
+
+	// given authorities, no ram and no stake
+	owner := NewAccountAuthority(NewName("helloworld11"), NewName("active"))
+	r, err = api.CreateAccount("helloworld11", "newaccount12", &owner, &owner, 0, Asset{}, Asset{})
+	assert.Nil(t, err)
+	assert.Nil(t, r.OwnerKey)
+	assert.Nil(t, r.ActiveKey)
+	assert.Equal(t, 1, len(node.pushed[1].Actions))
+
+	_, err = api.CreateAccount("helloworld11", "bad.name", &owner, &owner, 0, Asset{}, Asset{})
+	assert.NotNil(t, err)
+	bad := Authority{Threshold: 2, Accounts: owner.Accounts}
+	_, err = api.CreateAccount("helloworld11", "newaccount13", &bad, &owner, 0, Asset{}, Asset{})
+	assert.NotNil(t, err)
+	assert.Equal(t, 2, len(node.pushed))
+}
+
+const testSystemAbi = `{
+	"version": "eosio::abi/1.1",
+	"structs": [
+		{"name": "permission_level", "base": "", "fields": [
+			{"name": "actor", "type": "name"}, {"name": "permission", "type": "name"}]},
+		{"name": "key_weight", "base": "", "fields": [
+			{"name": "key", "type": "public_key"}, {"name": "weight", "type": "uint16"}]},
+		{"name": "permission_level_weight", "base": "", "fields": [
+			{"name": "permission", "type": "permission_level"}, {"name": "weight", "type": "uint16"}]},
+		{"name": "wait_weight", "base": "", "fields": [
+			{"name": "wait_sec", "type": "uint32"}, {"name": "weight", "type": "uint16"}]},
+		{"name": "authority", "base": "", "fields": [
+			{"name": "threshold", "type": "uint32"}, {"name": "keys", "type": "key_weight[]"},
+			{"name": "accounts", "type": "permission_level_weight[]"}, {"name": "waits", "type": "wait_weight[]"}]},
+		{"name": "newaccount", "base": "", "fields": [
+			{"name": "creator", "type": "name"}, {"name": "name", "type": "name"},
+			{"name": "owner", "type": "authority"}, {"name": "active", "type": "authority"}]},
+		{"name": "buyrambytes", "base": "", "fields": [
+			{"name": "payer", "type": "name"}, {"name": "receiver", "type": "name"}, {"name": "bytes", "type": "uint32"}]},
+		{"name": "delegatebw", "base": "", "fields": [
+			{"name": "from", "type": "name"}, {"name": "receiver", "type": "name"},
+			{"name": "stake_net_quantity", "type": "asset"}, {"name": "stake_cpu_quantity", "type": "asset"},
+			{"name": "transfer", "type": "bool"}]},
+		{"name": "updateauth", "base": "", "fields": [
+			{"name": "account", "type": "name"}, {"name": "permission", "type": "name"},
+			{"name": "parent", "type": "name"}, {"name": "auth", "type": "authority"}]},
+		{"name": "linkauth", "base": "", "fields": [
+			{"name": "account", "type": "name"}, {"name": "code", "type": "name"},
+			{"name": "type", "type": "name"}, {"name": "requirement", "type": "name"}]},
+		{"name": "unlinkauth", "base": "", "fields": [
+			{"name": "account", "type": "name"}, {"name": "code", "type": "name"}, {"name": "type", "type": "name"}]}
+	],
+	"actions": [
+		{"name": "newaccount", "type": "newaccount", "ricardian_contract": ""},
+		{"name": "buyrambytes", "type": "buyrambytes", "ricardian_contract": ""},
+		{"name": "delegatebw", "type": "delegatebw", "ricardian_contract": ""},
+		{"name": "updateauth", "type": "updateauth", "ricardian_contract": ""},
+		{"name": "linkauth", "type": "linkauth", "ricardian_contract": ""},
+		{"name": "unlinkauth", "type": "unlinkauth", "ricardian_contract": ""}
+	]
+}`
+
+func TestPermissionWorkflows(t *testing.T) {
+	node := newTestNode(t)
+	defer node.Close()
+	node.accounts["alice"] = fmt.Sprintf(`{"account_name":"alice","permissions":[
+		{"perm_name":"active","parent":"owner","required_auth":{"threshold":1,
+			"keys":[{"key":"%[1]s","weight":1}],
+			"accounts":[{"permission":{"actor":"bob","permission":"active"},"weight":1}],"waits":[]}},
+		{"perm_name":"owner","parent":"","required_auth":{"threshold":1,
+			"keys":[{"key":"%[1]s","weight":1}],"accounts":[],"waits":[]}}]}`, "EOS"+testCreatorPub[2:])
+
+	api := NewChainApiWithProfile(node.URL, EOSProfile())
+	s := NewABISerializer()
+	assert.Nil(t, s.SetContractABI("eosio", []byte(testSystemAbi)))
+	lastAction := func(name string, permission string) string {
+		tx := node.pushed[len(node.pushed)-1]
+		assert.Equal(t, 1, len(tx.Actions))
+		a := tx.Actions[0]
+		assert.Equal(t, name, a.Name.String())
+		assert.Equal(t, []PermissionLevel{{NewName("alice"), NewName(permission)}}, a.Authorization)
+		r, err := s.UnpackActionArgs("eosio", name, a.Data)
+		assert.Nil(t, err)
+		return string(r)
+	}
+
+	perm, err := api.GetPermission("alice", "active")
+	assert.Nil(t, err)
+	assert.Equal(t, "owner", perm.Parent.String())
+	assert.Equal(t, testCreatorPub, perm.RequiredAuth.Keys[0].Key.StringAM())
+	_, err = api.GetPermission("alice", "claim")
+	assert.NotNil(t, err)
+	_, err = api.GetPermissions("nobody")
+	assert.NotNil(t, err)
+
+	// keys are kept sorted
+	pub2 := GeneratePrivateKey().GetPublicKey()
+	keys := fmt.Sprintf(`{"key":"%s","weight":1},{"key":"%s","weight":1}`, testCreatorPub, pub2.StringAM())
+	pub1, _ := ParsePublicKey(testCreatorPub)
+	if bytes.Compare(pub2.Data[:], pub1.Data[:]) < 0 {
+		keys = fmt.Sprintf(`{"key":"%s","weight":1},{"key":"%s","weight":1}`, pub2.StringAM(), testCreatorPub)
+	}
+	_, err = api.AddPermissionKey("alice", "active", api.Profile.PublicKeyString(pub2), 1)
+	assert.Nil(t, err)
+	assert.Equal(t, `{"account":"alice","permission":"active","parent":"owner","auth":{"threshold":1,`+
+		`"keys":[`+keys+`],"accounts":[{"permission":{"actor":"bob","permission":"active"},"weight":1}],"waits":[]}}`,
+		lastAction("updateauth", "active"))
+	_, err = api.AddPermissionKey("alice", "active", testCreatorPub, 1)
+	assert.NotNil(t, err)
+
+	_, err = api.RemovePermissionKey("alice", "active", testCreatorPub)
+	assert.Nil(t, err)
+	assert.Equal(t, `{"account":"alice","permission":"active","parent":"owner","auth":{"threshold":1,`+
+		`"keys":[],"accounts":[{"permission":{"actor":"bob","permission":"active"},"weight":1}],"waits":[]}}`,
+		lastAction("updateauth", "active"))
+	// owner would be left without any weight
+	pushed := len(node.pushed)
+	_, err = api.RemovePermissionKey("alice", "owner", testCreatorPub)
+	assert.NotNil(t, err)
+	_, err = api.RemovePermissionKey("alice", "active", api.Profile.PublicKeyString(pub2))
+	assert.NotNil(t, err)
+
+	pub1Weight := KeyWeight{Key: *pub1, Weight: 1}
+	pub2Weight := KeyWeight{Key: *pub2, Weight: 1}
+	_, err = api.CreatePermission("alice", "transfer", "", 2, []KeyWeight{pub1Weight, pub2Weight}, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, `{"account":"alice","permission":"transfer","parent":"active","auth":{"threshold":2,`+
+		`"keys":[`+keys+`],"accounts":[],"waits":[]}}`, lastAction("updateauth", "active"))
+	_, err = api.CreatePermission("alice", "active", "owner", 1, []KeyWeight{pub1Weight}, nil)
+	assert.NotNil(t, err)
+	_, err = api.CreatePermission("alice", "transfer", "missing", 1, []KeyWeight{pub1Weight}, nil)
+	assert.NotNil(t, err)
+	_, err = api.CreatePermission("alice", "transfer", "", 3, []KeyWeight{pub1Weight, pub2Weight}, nil)
+	assert.NotNil(t, err)
+	assert.Equal(t, pushed+1, len(node.pushed))
+
+	// a permission outside of active is updated with owner
+	_, err = api.SetPermission("alice", "claim", "owner", NewAccountAuthority(NewName("bob"), NewName("active")))
+	assert.Nil(t, err)
+	assert.Equal(t, `{"account":"alice","permission":"claim","parent":"owner","auth":{"threshold":1,`+
+		`"keys":[],"accounts":[{"permission":{"actor":"bob","permission":"active"},"weight":1}],"waits":[]}}`,
+		lastAction("updateauth", "owner"))
+
+	_, err = api.LinkAuth("alice", "eosio.token", "transfer", "active")
+	assert.Nil(t, err)
+	assert.Equal(t, `{"account":"alice","code":"eosio.token","type":"transfer","requirement":"active"}`,
+		lastAction("linkauth", "active"))
+	_, err = api.LinkAuth("alice", "eosio.token", "transfer", "claim")
+	assert.NotNil(t, err)
+	_, err = api.UnlinkAuth("alice", "eosio.token", "transfer")
+	assert.Nil(t, err)
+	assert.Equal(t, `{"account":"alice","code":"eosio.token","type":"transfer"}`, lastAction("unlinkauth", "active"))
+}
+
+func TestMsigProposal(t *testing.T) {
+	node := newTestNode(t)
+	defer node.Close()
+	api := NewChainApiWithProfile(node.URL, EOSProfile())
+	s := api.ABISerializer
+	lastAction := func(name string, actor string) string {
+		tx := node.pushed[len(node.pushed)-1]
+		assert.Equal(t, 1, len(tx.Actions))
+		a := tx.Actions[0]
+		assert.Equal(t, "eosio.msig", a.Account.String())
+		assert.Equal(t, name, a.Name.String())
+		assert.Equal(t, actor, a.Authorization[0].Actor.String())
+		r, err := s.UnpackActionArgs("eosio.msig", name, a.Data)
+		assert.Nil(t, err)
+		return string(r)
 	}
 
-	strAction := `
-	{
-		"from": "helloworld11",
-		"to": "eosio.token",
-		"quantity": "0.1000 EOS",
-		"memo": "transfer from alice"
+	transfer, err := s.PackActionArgs("eosio.token", "transfer",
+		`{"from":"treasury","to":"bob","quantity":"1.0000 EOS","memo":"payroll"}`)
+	assert.Nil(t, err)
+	treasury := []PermissionLevel{{NewName("treasury"), NewName("active")}}
+	tx := NewTransaction(3600)
+	tx.AddAction(&Action{Account: NewName("eosio.token"), Name: NewName("transfer"), Authorization: treasury, Data: transfer})
+
+	requested := []PermissionLevel{{NewName("alice"), NewName("active")}, {NewName("bob"), NewName("active")}}
+	_, err = api.Propose("alice", "payroll", requested, tx)
+	assert.Nil(t, err)
+	args := lastAction("propose", "alice")
+	assert.True(t, strings.HasPrefix(args, `{"proposer":"alice","proposal_name":"payroll","requested":[`+
+		`{"actor":"alice","permission":"active"},{"actor":"bob","permission":"active"}],"trx":{"expiration":`), args)
+	_, err = api.Propose("alice", "payroll", nil, tx)
+	assert.NotNil(t, err)
+
+	_, err = api.Approve("alice", "payroll", PermissionLevel{NewName("bob"), NewName("active")})
+	assert.Nil(t, err)
+	assert.Equal(t, `{"proposer":"alice","proposal_name":"payroll","level":{"actor":"bob","permission":"active"}}`,
+		lastAction("approve", "bob"))
+	_, err = api.Unapprove("alice", "payroll", PermissionLevel{NewName("bob"), NewName("active")})
+	assert.Nil(t, err)
+	assert.Equal(t, `{"proposer":"alice","proposal_name":"payroll","level":{"actor":"bob","permission":"active"}}`,
+		lastAction("unapprove", "bob"))
+	_, err = api.Cancel("alice", "payroll", "alice")
+	assert.Nil(t, err)
+	assert.Equal(t, `{"proposer":"alice","proposal_name":"payroll","canceler":"alice"}`, lastAction("cancel", "alice"))
+	_, err = api.Exec("alice", "payroll", "bob")
+	assert.Nil(t, err)
+	assert.Equal(t, `{"proposer":"alice","proposal_name":"payroll","executer":"bob"}`, lastAction("exec", "bob"))
+
+	// rows are packed through the msig ABI as nodeos returns them with json=false
+	packRow := func(abiType string, v string) string {
+		b, err := s.PackAbiType("eosio.msig", abiType, v)
+		assert.Nil(t, err)
+		return hex.EncodeToString(b)
+	}
+	packedTx := hex.EncodeToString(tx.Pack())
+	node.tables["eosio.msig/alice/proposal"] = []testRow{
+		{NewName("other"), packRow("proposal", `{"proposal_name":"other","packed_transaction":"`+packedTx+`"}`)},
+		{NewName("payroll"), packRow("proposal", `{"proposal_name":"payroll","packed_transaction":"`+packedTx+`"}`)},
 	}
-	`
-	r, err = api.PushActionWithArgs("eosio.token", "transfer", strAction, "helloworld11", "active")
-	if err != nil {
-		t.Error(err)
+	node.tables["eosio.msig/alice/approvals2"] = []testRow{
+		{NewName("payroll"), packRow("approvals_info", `{"version":1,"proposal_name":"payroll",`+
+			`"requested_approvals":[{"level":{"actor":"alice","permission":"active"},"time":"1970-01-01T00:00:00.000"}],`+
+			`"provided_approvals":[{"level":{"actor":"bob","permission":"active"},"time":"2022-01-02T03:04:05.000"}]}`)},
 	}
 
-	if false {
-		t.Log(r)
+	proposals, err := api.GetProposals("alice")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(proposals))
+	p, err := api.GetProposal("alice", "payroll")
+	assert.Nil(t, err)
+	assert.Equal(t, "alice", p.Proposer.String())
+	assert.Equal(t, "payroll", p.ProposalName.String())
+	assert.Nil(t, p.EarliestExecTime)
+	assert.Equal(t, tx.Actions, p.Transaction.Actions)
+	_, err = api.GetProposal("alice", "missing")
+	assert.NotNil(t, err)
+
+	decoded, err := api.DecodeProposal(p)
+	assert.Nil(t, err)
+	data, err := decoded.Get("actions", 0, "data")
+	assert.Nil(t, err)
+	assert.Equal(t, `{"from":"treasury","to":"bob","quantity":"1.0000 EOS","memo":"payroll"}`, data.String())
+	hexData, err := decoded.GetString("actions", 0, "hex_data")
+	assert.Nil(t, err)
+	assert.Equal(t, hex.EncodeToString(transfer), hexData)
+
+	approvals, err := api.GetProposalApprovals("alice", "payroll")
+	assert.Nil(t, err)
+	assert.Equal(t, uint8(1), approvals.Version)
+	assert.Equal(t, 1, len(approvals.RequestedApprovals))
+	assert.Equal(t, "bob", approvals.ProvidedApprovals[0].Level.Actor.String())
+	assert.Equal(t, "2022-01-02T03:04:05.000", approvals.ProvidedApprovals[0].Time.String())
+	_, err = api.GetProposalApprovals("alice", "other")
+	assert.NotNil(t, err)
+}
+
+func TestSimulate(t *testing.T) {
+	node := newTestNode(t)
+	defer node.Close()
+	api := NewChainApiWithProfile(node.URL, EOSProfile())
+	transfer := NewAction(NewName("eosio.token"), NewName("transfer"),
+		[]PermissionLevel{{NewName("alice"), NewName("active")}}, NewName("alice"), NewName("bob"))
+
+	node.traces["compute_transaction"] = `{"transaction_id":"1234","processed":{"elapsed":215,` +
+		`"receipt":{"status":"executed","cpu_usage_us":310,"net_usage_words":16},"except":null,"action_traces":[` +
+		`{"act":{"account":"eosio.token","name":"transfer"},"account_ram_deltas":[{"account":"bob","delta":240}]},` +
+		`{"act":{"account":"eosio.token","name":"transfer"},"account_ram_deltas":[{"account":"bob","delta":-112},{"account":"alice","delta":8}]}]}}`
+	r, err := api.Simulate([]*Action{transfer})
+	assert.Nil(t, err)
+	assert.Equal(t, "", r.Error)
+	assert.Equal(t, "1234", r.TransactionId)
+	assert.Equal(t, uint64(310), r.CpuUsageUs)
+	assert.Equal(t, uint64(16), r.NetUsageWords)
+	assert.Equal(t, int64(215), r.Elapsed)
+	assert.Equal(t, map[string]int64{"bob": 128, "alice": 8}, r.RamDeltas)
+	assert.Equal(t, 0, len(node.pushed))
+
+	node.traces["compute_transaction"] = `{"transaction_id":"1234","processed":{"receipt":null,"except":{` +
+		`"code":3050003,"name":"eosio_assert_message_exception","message":"eosio_assert_message assertion failure",` +
+		`"stack":[{"format":"assertion failure with message: ${s}","data":{"s":"overdrawn balance"}}]},"action_traces":[]}}`
+	r, err = api.Simulate([]*Action{transfer})
+	assert.Nil(t, err)
+	assert.Equal(t, "assertion failure with message: overdrawn balance", r.Error)
+
+	node.traces["compute_transaction"] = `{"code":500,"message":"Internal Service Error","error":{"code":3090003,` +
+		`"name":"unsatisfied_authorization","what":"Provided keys, permissions, and delays do not satisfy declared authorizations",` +
+		`"details":[{"message":"transaction declares authority that is not satisfied"}]}}`
+	r, err = api.Simulate([]*Action{transfer})
+	assert.Nil(t, err)
+	assert.Equal(t, "transaction declares authority that is not satisfied", r.Error)
+
+	// nodes without compute_transaction
+	delete(node.traces, "compute_transaction")
+	node.traces["send_read_only_transaction"] = `{"transaction_id":"5678","processed":{"receipt":{"cpu_usage_us":20,"net_usage_words":0}}}`
+	_, err = api.Simulate([]*Action{transfer})
+	assert.Equal(t, ErrComputeTransactionUnsupported, err)
+	r, err = api.SimulateReadOnly([]*Action{transfer})
+	assert.Nil(t, err)
+	assert.Equal(t, "5678", r.TransactionId)
+	assert.Equal(t, uint64(20), r.CpuUsageUs)
+}
+
+func TestResources(t *testing.T) {
+	node := newTestNode(t)
+	defer node.Close()
+	api := NewChainApiWithProfile(node.URL, EOSProfile())
+	s := NewABISerializer()
+	assert.Nil(t, s.SetContractABI("eosio", []byte(eosioSystemTablesAbi)))
+	packRow := func(abiType string, v string) []testRow {
+		b, err := s.PackAbiType("eosio", abiType, v)
+		assert.Nil(t, err)
+		return []testRow{{NewName(""), hex.EncodeToString(b)}}
+	}
+
+	node.tables["eosio/eosio/rammarket"] = packRow("exchange_state", `{"supply":"10000000000.0000 RAMCORE",`+
+		`"base":{"balance":"68719476736 RAM","weight":0.5},`+
+		`"quote":{"balance":"1000000.0000 EOS","weight":0.5}}`)
+	m, err := api.GetRamMarket()
+	assert.Nil(t, err)
+	assert.Equal(t, "0.0149 EOS", m.RamPrice().String())
+	assert.Equal(t, "15.3356 EOS", m.BuyRamBytesCost(1024*1024).String())
+	bytes, err := m.BuyRamBytes(Asset{10000, NewSymbol("EOS", 4)})
+	assert.Nil(t, err)
+	assert.Equal(t, int64(68375), bytes)
+	_, err = m.BuyRamBytes(Asset{10000, NewSymbol("UUOS", 4)})
+	assert.NotNil(t, err)
+	assert.Equal(t, "0.0148 EOS", m.SellRam(1024).String())
+
+	node.tables["eosio/eosio/global"] = packRow("eosio_global_state", `{"max_block_net_usage":1048576,`+
+		`"target_block_net_usage_pct":1000,"max_transaction_net_usage":524288,"base_per_transaction_net_usage":12,`+
+		`"net_usage_leeway":500,"context_free_discount_net_usage_num":20,"context_free_discount_net_usage_den":100,`+
+		`"max_block_cpu_usage":200000,"target_block_cpu_usage_pct":1000,"max_transaction_cpu_usage":150000,`+
+		`"min_transaction_cpu_usage":100,"max_transaction_lifetime":3600,"deferred_trx_expiration_window":600,`+
+		`"max_transaction_delay":3888000,"max_inline_action_size":524288,"max_inline_action_depth":4,`+
+		`"max_authority_depth":6,"max_ram_size":"68719476736","total_ram_bytes_reserved":"68719476000",`+
+		`"total_ram_stake":"1000000","last_producer_schedule_update":"2022-01-01T00:00:00.000",`+
+		`"last_pervote_bucket_fill":"2022-01-01T00:00:00.000","pervote_bucket":0,"perblock_bucket":0,`+
+		`"total_unpaid_blocks":0,"total_activated_stake":"150000000000","thresh_activated_stake_time":"2022-01-01T00:00:00.000",`+
+		`"last_producer_schedule_size":21,"total_producer_vote_weight":0,"last_name_close":"2022-01-01T00:00:00.000"}`)
+	g, err := api.GetGlobalState()
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(736), g.FreeRam())
+	assert.Equal(t, int64(150000000000), g.TotalActivatedStake)
+
+	now := time.Now()
+	resource := func(utilization int64, adjusted int64, timestamp time.Time) string {
+		ts, err := NewTimePointSec(timestamp)
+		assert.Nil(t, err)
+		return fmt.Sprintf(`{"version":0,"weight":1000000,"weight_ratio":1000000000000000,`+
+			`"assumed_stake_weight":1,"initial_weight_ratio":1000000000000000,"target_weight_ratio":1000000000000000,`+
+			`"initial_timestamp":"2022-01-01T00:00:00","target_timestamp":"2022-01-01T00:00:00","exponent":2,`+
+			`"decay_secs":86400,"min_price":"0.0000 EOS","max_price":"100.0000 EOS","utilization":%d,`+
+			`"adjusted_utilization":%d,"utilization_timestamp":"%s"}`, utilization, adjusted, ts.String())
 	}
+	node.tables["eosio/eosio/powup.state"] = packRow("powerup_state", `{"version":0,"net":`+resource(0, 0, now)+
+		`,"cpu":`+resource(0, 20000, now)+`,"powerup_days":1,"min_powerup_fee":"0.0001 EOS"}`)
+	state, err := api.GetPowerUpState()
+	assert.Nil(t, err)
+	fee, err := state.Fee(PowerUpFraction/100, 0, now)
+	assert.Nil(t, err)
+	assert.Equal(t, "0.0050 EOS", fee.String())
+	// cpu is charged at the adjusted utilization until it decays
+	fee, err = state.Fee(PowerUpFraction/100, PowerUpFraction/100, now)
+	assert.Nil(t, err)
+	assert.Equal(t, "0.0250 EOS", fee.String())
+	fee, err = state.Fee(PowerUpFraction/100, PowerUpFraction/100, now.Add(1000*24*time.Hour))
+	assert.Nil(t, err)
+	assert.Equal(t, "0.0100 EOS", fee.String())
+	_, err = state.Fee(0, 0, now)
+	assert.NotNil(t, err)
+	_, err = state.Fee(PowerUpFraction+1, 0, now)
+	assert.NotNil(t, err)
+	state.Net.Utilization = 1
+	_, err = state.Fee(PowerUpFraction, 0, now)
+	assert.NotNil(t, err)
+
+	node.accounts["alice"] = `{"account_name":"alice","ram_quota":8192,"ram_usage":3000,` +
+		`"net_weight":"10000","cpu_weight":"20000",` +
+		`"net_limit":{"used":"100","available":"900","max":"1000"},"cpu_limit":{"used":10,"available":90,"max":100}}`
+	r, err := api.GetAccountResources("alice")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(5192), r.RamAvailable())
+	assert.Equal(t, ResourceLimit{100, 900, 1000}, r.Net)
+	assert.Equal(t, ResourceLimit{10, 90, 100}, r.Cpu)
+	assert.Equal(t, int64(20000), r.CpuWeight)
+	_, err = api.GetAccountResources("nobody")
+	assert.NotNil(t, err)
+}
+
+var testViewsAbi = `{
+	"version": "eosio::abi/1.2",
+	"structs": [
+		{"name": "getbalance", "base": "", "fields": [{"name": "owner", "type": "name"}]},
+		{"name": "balance_result", "base": "", "fields": [
+			{"name": "owner", "type": "name"},
+			{"name": "balance", "type": "asset"}
+		]}
+	],
+	"actions": [{"name": "getbalance", "type": "getbalance", "ricardian_contract": ""}],
+	"tables": [],
+	"variants": [],
+	"action_results": [{"name": "getbalance", "result_type": "balance_result"}]
+}`
+
+func TestReadOnly(t *testing.T) {
+	node := newTestNode(t)
+	defer node.Close()
+	api := NewChainApiWithProfile(node.URL, EOSProfile())
+	assert.Nil(t, api.ABISerializer.SetContractABI("views", []byte(testViewsAbi)))
+
+	ret, err := api.ABISerializer.PackAbiType("views", "balance_result", `{"owner":"alice","balance":"1.0000 EOS"}`)
+	assert.Nil(t, err)
+	node.traces["send_read_only_transaction"] = fmt.Sprintf(`{"transaction_id":"1234","processed":{"except":null,`+
+		`"action_traces":[{"act":{"account":"views","name":"getbalance","authorization":[]},`+
+		`"return_value_hex_data":"%s"}]}}`, hex.EncodeToString(ret))
+	r, err := api.ReadOnly("views", "getbalance", `{"owner":"alice"}`)
+	assert.Nil(t, err)
+	assert.Equal(t, `{"owner":"alice","balance":"1.0000 EOS"}`, r.String())
+	assert.Equal(t, 0, len(node.pushed))
+
+	_, err = api.ReadOnly("views", "missing", `{}`)
+	assert.NotNil(t, err)
+
+	node.traces["send_read_only_transaction"] = `{"transaction_id":"1234","processed":{"except":{` +
+		`"code":3050003,"stack":[{"format":"assertion failure with message: ${s}","data":{"s":"unknown owner"}}]}}}`
+	_, err = api.ReadOnly("views", "getbalance", `{"owner":"bob"}`)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "unknown owner")
 }
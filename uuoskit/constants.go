@@ -215,3 +215,197 @@ var gBaseTypes = map[string]bool{
 	"asset":                true,
 	"extended_asset":       true,
 }
+
+var eosioMsigAbi = `
+{
+    "version": "eosio::abi/1.2",
+    "types": [],
+    "structs": [
+        {"name": "permission_level", "base": "", "fields": [
+            {"name": "actor", "type": "name"},
+            {"name": "permission", "type": "name"}
+        ]},
+        {"name": "action", "base": "", "fields": [
+            {"name": "account", "type": "name"},
+            {"name": "name", "type": "name"},
+            {"name": "authorization", "type": "permission_level[]"},
+            {"name": "data", "type": "bytes"}
+        ]},
+        {"name": "extension", "base": "", "fields": [
+            {"name": "type", "type": "uint16"},
+            {"name": "data", "type": "bytes"}
+        ]},
+        {"name": "transaction_header", "base": "", "fields": [
+            {"name": "expiration", "type": "time_point_sec"},
+            {"name": "ref_block_num", "type": "uint16"},
+            {"name": "ref_block_prefix", "type": "uint32"},
+            {"name": "max_net_usage_words", "type": "varuint32"},
+            {"name": "max_cpu_usage_ms", "type": "uint8"},
+            {"name": "delay_sec", "type": "varuint32"}
+        ]},
+        {"name": "transaction", "base": "transaction_header", "fields": [
+            {"name": "context_free_actions", "type": "action[]"},
+            {"name": "actions", "type": "action[]"},
+            {"name": "transaction_extensions", "type": "extension[]"}
+        ]},
+        {"name": "propose", "base": "", "fields": [
+            {"name": "proposer", "type": "name"},
+            {"name": "proposal_name", "type": "name"},
+            {"name": "requested", "type": "permission_level[]"},
+            {"name": "trx", "type": "transaction"}
+        ]},
+        {"name": "approve", "base": "", "fields": [
+            {"name": "proposer", "type": "name"},
+            {"name": "proposal_name", "type": "name"},
+            {"name": "level", "type": "permission_level"},
+            {"name": "proposal_hash", "type": "checksum256$"}
+        ]},
+        {"name": "unapprove", "base": "", "fields": [
+            {"name": "proposer", "type": "name"},
+            {"name": "proposal_name", "type": "name"},
+            {"name": "level", "type": "permission_level"}
+        ]},
+        {"name": "cancel", "base": "", "fields": [
+            {"name": "proposer", "type": "name"},
+            {"name": "proposal_name", "type": "name"},
+            {"name": "canceler", "type": "name"}
+        ]},
+        {"name": "exec", "base": "", "fields": [
+            {"name": "proposer", "type": "name"},
+            {"name": "proposal_name", "type": "name"},
+            {"name": "executer", "type": "name"}
+        ]},
+        {"name": "invalidate", "base": "", "fields": [
+            {"name": "account", "type": "name"}
+        ]},
+        {"name": "proposal", "base": "", "fields": [
+            {"name": "proposal_name", "type": "name"},
+            {"name": "packed_transaction", "type": "bytes"},
+            {"name": "earliest_exec_time", "type": "time_point?$"}
+        ]},
+        {"name": "approval", "base": "", "fields": [
+            {"name": "level", "type": "permission_level"},
+            {"name": "time", "type": "time_point"}
+        ]},
+        {"name": "approvals_info", "base": "", "fields": [
+            {"name": "version", "type": "uint8"},
+            {"name": "proposal_name", "type": "name"},
+            {"name": "requested_approvals", "type": "approval[]"},
+            {"name": "provided_approvals", "type": "approval[]"}
+        ]},
+        {"name": "old_approvals_info", "base": "", "fields": [
+            {"name": "proposal_name", "type": "name"},
+            {"name": "requested_approvals", "type": "permission_level[]"},
+            {"name": "provided_approvals", "type": "permission_level[]"}
+        ]},
+        {"name": "invalidation", "base": "", "fields": [
+            {"name": "account", "type": "name"},
+            {"name": "last_invalidation_time", "type": "time_point"}
+        ]}
+    ],
+    "actions": [
+        {"name": "approve", "type": "approve", "ricardian_contract": ""},
+        {"name": "cancel", "type": "cancel", "ricardian_contract": ""},
+        {"name": "exec", "type": "exec", "ricardian_contract": ""},
+        {"name": "invalidate", "type": "invalidate", "ricardian_contract": ""},
+        {"name": "propose", "type": "propose", "ricardian_contract": ""},
+        {"name": "unapprove", "type": "unapprove", "ricardian_contract": ""}
+    ],
+    "tables": [
+        {"name": "approvals", "index_type": "i64", "key_names": [], "key_types": [], "type": "old_approvals_info"},
+        {"name": "approvals2", "index_type": "i64", "key_names": [], "key_types": [], "type": "approvals_info"},
+        {"name": "invals", "index_type": "i64", "key_names": [], "key_types": [], "type": "invalidation"},
+        {"name": "proposal", "index_type": "i64", "key_names": [], "key_types": [], "type": "proposal"}
+    ],
+    "ricardian_clauses": [],
+    "variants": [],
+    "action_results": []
+}
+`
+
+// eosioSystemTablesAbi describes the eosio.system tables read by the
+// resource helpers
+var eosioSystemTablesAbi = `
+{
+    "version": "eosio::abi/1.2",
+    "types": [],
+    "structs": [
+        {"name": "connector", "base": "", "fields": [
+            {"name": "balance", "type": "asset"},
+            {"name": "weight", "type": "float64"}
+        ]},
+        {"name": "exchange_state", "base": "", "fields": [
+            {"name": "supply", "type": "asset"},
+            {"name": "base", "type": "connector"},
+            {"name": "quote", "type": "connector"}
+        ]},
+        {"name": "blockchain_parameters", "base": "", "fields": [
+            {"name": "max_block_net_usage", "type": "uint64"},
+            {"name": "target_block_net_usage_pct", "type": "uint32"},
+            {"name": "max_transaction_net_usage", "type": "uint32"},
+            {"name": "base_per_transaction_net_usage", "type": "uint32"},
+            {"name": "net_usage_leeway", "type": "uint32"},
+            {"name": "context_free_discount_net_usage_num", "type": "uint32"},
+            {"name": "context_free_discount_net_usage_den", "type": "uint32"},
+            {"name": "max_block_cpu_usage", "type": "uint32"},
+            {"name": "target_block_cpu_usage_pct", "type": "uint32"},
+            {"name": "max_transaction_cpu_usage", "type": "uint32"},
+            {"name": "min_transaction_cpu_usage", "type": "uint32"},
+            {"name": "max_transaction_lifetime", "type": "uint32"},
+            {"name": "deferred_trx_expiration_window", "type": "uint32"},
+            {"name": "max_transaction_delay", "type": "uint32"},
+            {"name": "max_inline_action_size", "type": "uint32"},
+            {"name": "max_inline_action_depth", "type": "uint16"},
+            {"name": "max_authority_depth", "type": "uint16"}
+        ]},
+        {"name": "eosio_global_state", "base": "blockchain_parameters", "fields": [
+            {"name": "max_ram_size", "type": "uint64"},
+            {"name": "total_ram_bytes_reserved", "type": "uint64"},
+            {"name": "total_ram_stake", "type": "int64"},
+            {"name": "last_producer_schedule_update", "type": "block_timestamp_type"},
+            {"name": "last_pervote_bucket_fill", "type": "time_point"},
+            {"name": "pervote_bucket", "type": "int64"},
+            {"name": "perblock_bucket", "type": "int64"},
+            {"name": "total_unpaid_blocks", "type": "uint32"},
+            {"name": "total_activated_stake", "type": "int64"},
+            {"name": "thresh_activated_stake_time", "type": "time_point"},
+            {"name": "last_producer_schedule_size", "type": "uint16"},
+            {"name": "total_producer_vote_weight", "type": "float64"},
+            {"name": "last_name_close", "type": "block_timestamp_type"}
+        ]},
+        {"name": "powerup_state_resource", "base": "", "fields": [
+            {"name": "version", "type": "uint8"},
+            {"name": "weight", "type": "int64"},
+            {"name": "weight_ratio", "type": "int64"},
+            {"name": "assumed_stake_weight", "type": "int64"},
+            {"name": "initial_weight_ratio", "type": "int64"},
+            {"name": "target_weight_ratio", "type": "int64"},
+            {"name": "initial_timestamp", "type": "time_point_sec"},
+            {"name": "target_timestamp", "type": "time_point_sec"},
+            {"name": "exponent", "type": "float64"},
+            {"name": "decay_secs", "type": "uint32"},
+            {"name": "min_price", "type": "asset"},
+            {"name": "max_price", "type": "asset"},
+            {"name": "utilization", "type": "int64"},
+            {"name": "adjusted_utilization", "type": "int64"},
+            {"name": "utilization_timestamp", "type": "time_point_sec"}
+        ]},
+        {"name": "powerup_state", "base": "", "fields": [
+            {"name": "version", "type": "uint8"},
+            {"name": "net", "type": "powerup_state_resource"},
+            {"name": "cpu", "type": "powerup_state_resource"},
+            {"name": "powerup_days", "type": "uint32"},
+            {"name": "min_powerup_fee", "type": "asset"}
+        ]}
+    ],
+    "actions": [],
+    "tables": [
+        {"name": "rammarket", "index_type": "i64", "key_names": [], "key_types": [], "type": "exchange_state"},
+        {"name": "global", "index_type": "i64", "key_names": [], "key_types": [], "type": "eosio_global_state"},
+        {"name": "powup.state", "index_type": "i64", "key_names": [], "key_types": [], "type": "powerup_state"}
+    ],
+    "ricardian_clauses": [],
+    "variants": [],
+    "action_results": []
+}
+`
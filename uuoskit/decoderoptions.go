@@ -0,0 +1,141 @@
+package uuoskit
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// default limits of DecoderOptions, MAX_ARRAY_ALLOC_SIZE and
+// MAX_NUM_ARRAY_ELEMENTS of fc
+const (
+	maxArrayAllocSize = 10 * 1024 * 1024
+	maxArrayLength    = 1024 * 1024
+)
+
+// DecoderOptions bounds the resources a Decoder spends on malformed or
+// hostile input. Zero fields take the defaults.
+type DecoderOptions struct {
+	// MaxAllocSize bounds the length of bytes and strings, it defaults to
+	// 10 MiB.
+	MaxAllocSize int
+	// MaxArrayLength bounds the element count of arrays, it defaults to
+	// 1048576.
+	MaxArrayLength int
+	// MaxDepth bounds the nesting of ABI types, it defaults to 32.
+	MaxDepth int
+}
+
+// DefaultDecoderOptions returns the limits used by NewDecoder
+func DefaultDecoderOptions() DecoderOptions {
+	return DecoderOptions{
+		MaxAllocSize:   maxArrayAllocSize,
+		MaxArrayLength: maxArrayLength,
+		MaxDepth:       maxAbiTypeDepth,
+	}
+}
+
+func (o DecoderOptions) withDefaults() DecoderOptions {
+	if o.MaxAllocSize <= 0 {
+		o.MaxAllocSize = maxArrayAllocSize
+	}
+	if o.MaxArrayLength <= 0 {
+		o.MaxArrayLength = maxArrayLength
+	}
+	if o.MaxDepth <= 0 {
+		o.MaxDepth = maxAbiTypeDepth
+	}
+	return o
+}
+
+// DecodeError is returned by Decoder for malformed input. Offset is the
+// position in the decoded data where decoding failed and Path the ABI field
+// being decoded, e.g. transfer.quantity or auth.keys[1].key, empty outside
+// of ABI types.
+type DecodeError struct {
+	Offset  int
+	Path    string
+	Message string
+}
+
+func (e *DecodeError) Error() string {
+	if e.Path == "" {
+		return fmt.Sprintf("%s at %d", e.Message, e.Offset)
+	}
+	return fmt.Sprintf("%s: %s at %d", e.Path, e.Message, e.Offset)
+}
+
+// decodePathElem is a struct field, or an array element if name is empty.
+// Indexes are formatted only when an error is reported.
+type decodePathElem struct {
+	name  string
+	index int
+}
+
+func (dec *Decoder) pushField(name string) {
+	dec.path = append(dec.path, decodePathElem{name: name})
+}
+
+func (dec *Decoder) pushIndex(index int) {
+	dec.path = append(dec.path, decodePathElem{index: index})
+}
+
+func (dec *Decoder) popPath() {
+	dec.path = dec.path[:len(dec.path)-1]
+}
+
+func (dec *Decoder) pathString() string {
+	var b strings.Builder
+	for i, elem := range dec.path {
+		if elem.name == "" {
+			b.WriteString("[" + strconv.Itoa(elem.index) + "]")
+			continue
+		}
+		if i > 0 {
+			b.WriteByte('.')
+		}
+		b.WriteString(elem.name)
+	}
+	return b.String()
+}
+
+// errorAt returns a DecodeError at position pos of the decoder
+func (dec *Decoder) errorAt(pos int, format string, args ...interface{}) error {
+	return &DecodeError{
+		Offset:  dec.base + pos,
+		Path:    dec.pathString(),
+		Message: fmt.Sprintf(format, args...),
+	}
+}
+
+func (dec *Decoder) errorf(format string, args ...interface{}) error {
+	return dec.errorAt(dec.pos, format, args...)
+}
+
+// rebase makes a DecodeError of a decoder started at the current position
+// relative to this decoder, other errors are returned as is
+func (dec *Decoder) rebase(err error) error {
+	e, ok := err.(*DecodeError)
+	if !ok {
+		return err
+	}
+	path := dec.pathString()
+	if e.Path != "" && path != "" {
+		path += "." + e.Path
+	} else if e.Path != "" {
+		path = e.Path
+	}
+	return &DecodeError{Offset: dec.base + dec.pos + e.Offset, Path: path, Message: e.Message}
+}
+
+// checkCount fails when an array of count elements packed to at least
+// minSize bytes each exceeds MaxArrayLength or the remaining bytes
+func (dec *Decoder) checkCount(pos int, count int, minSize int) error {
+	if count > dec.opts.MaxArrayLength {
+		return dec.errorAt(pos, "array length %d exceeds the limit of %d", count, dec.opts.MaxArrayLength)
+	}
+	if count*minSize > len(dec.buf)-dec.pos {
+		return dec.errorAt(pos, "array of %d elements exceeds the %d remaining bytes", count, len(dec.buf)-dec.pos)
+	}
+	return nil
+}
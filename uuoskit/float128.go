@@ -0,0 +1,190 @@
+package uuoskit
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"math/big"
+	"strings"
+)
+
+// Float128 holds an IEEE 754 binary128 value in little endian byte order:
+// 1 sign bit, 15 exponent bits and 112 explicit significand bits.
+
+const (
+	float128Prec     = 113
+	float128Bias     = 16383
+	float128MinExp   = 1 - float128Bias // exponent of the smallest normal
+	float128MaxExp   = float128Bias
+	float128ExpMask  = 0x7fff
+	float128Subshift = float128Prec - 1 - float128MinExp // scales the smallest subnormal to 1
+)
+
+func (n *Float128) bits() (hi uint64, lo uint64) {
+	return binary.LittleEndian.Uint64(n[8:]), binary.LittleEndian.Uint64(n[:8])
+}
+
+func (n *Float128) setBits(hi uint64, lo uint64) {
+	binary.LittleEndian.PutUint64(n[8:], hi)
+	binary.LittleEndian.PutUint64(n[:8], lo)
+}
+
+func (n Float128) IsNaN() bool {
+	hi, lo := n.bits()
+	return (hi>>48)&float128ExpMask == float128ExpMask && (hi<<16 != 0 || lo != 0)
+}
+
+// SetNaN sets n to the canonical quiet NaN
+func (n *Float128) SetNaN() {
+	n.setBits(0x7fff800000000000, 0)
+}
+
+// SetBigFloat sets n to f rounded to the nearest binary128 value, ties to
+// even. Values too large for binary128 become infinities.
+func (n *Float128) SetBigFloat(f *big.Float) {
+	var sign uint64
+	if f.Signbit() {
+		sign = 1 << 63
+	}
+	if f.IsInf() {
+		n.setBits(sign|uint64(float128ExpMask)<<48, 0)
+		return
+	}
+	if f.Sign() == 0 {
+		n.setBits(sign, 0)
+		return
+	}
+
+	abs := new(big.Float).SetPrec(f.Prec()).Abs(f)
+	// abs = 1.x * 2^exp
+	exp := abs.MantExp(nil) - 1
+	prec := float128Prec
+	if exp < float128MinExp {
+		// subnormals lose one bit of precision per step below the smallest normal
+		prec -= float128MinExp - exp
+	}
+	if prec < 1 {
+		// below half of the smallest subnormal rounds to zero, above to the
+		// smallest subnormal, exactly half is a tie and rounds to even zero
+		half := new(big.Float).SetMantExp(big.NewFloat(1), -float128Subshift-1)
+		if prec == 0 && abs.Cmp(half) > 0 {
+			n.setBits(sign, 1)
+		} else {
+			n.setBits(sign, 0)
+		}
+		return
+	}
+
+	r := new(big.Float).SetMode(big.ToNearestEven).SetPrec(uint(prec)).Set(abs)
+	exp = r.MantExp(nil) - 1
+	if exp > float128MaxExp {
+		n.setBits(sign|uint64(float128ExpMask)<<48, 0)
+		return
+	}
+
+	var biased uint64
+	var significand *big.Int
+	if exp >= float128MinExp {
+		biased = uint64(exp + float128Bias)
+		significand, _ = new(big.Float).SetMantExp(r, float128Prec-1-exp).Int(nil)
+		// drop the implicit leading bit
+		significand.SetBit(significand, float128Prec-1, 0)
+	} else {
+		significand, _ = new(big.Float).SetMantExp(r, float128Subshift).Int(nil)
+	}
+
+	lo := new(big.Int).And(significand, new(big.Int).SetUint64(^uint64(0))).Uint64()
+	hi := new(big.Int).Rsh(significand, 64).Uint64()
+	n.setBits(sign|biased<<48|hi, lo)
+}
+
+// BigFloat returns n as an exact big.Float, it fails for NaN which
+// big.Float can not represent.
+func (n Float128) BigFloat() (*big.Float, error) {
+	if n.IsNaN() {
+		return nil, newErrorf("float128 NaN can not be converted to big.Float")
+	}
+	hi, lo := n.bits()
+	neg := hi>>63 != 0
+	biased := int((hi >> 48) & float128ExpMask)
+	f := new(big.Float).SetPrec(float128Prec)
+	if biased == float128ExpMask {
+		return f.SetInf(neg), nil
+	}
+
+	significand := new(big.Int).SetUint64(hi & (1<<48 - 1))
+	significand.Lsh(significand, 64)
+	significand.Or(significand, new(big.Int).SetUint64(lo))
+	exp := -float128Subshift
+	if biased != 0 {
+		significand.SetBit(significand, float128Prec-1, 1)
+		exp = biased - float128Bias - (float128Prec - 1)
+	}
+	f.SetInt(significand)
+	f.SetMantExp(f, exp)
+	if neg {
+		f.Neg(f)
+	}
+	return f, nil
+}
+
+// SetString parses a decimal or hexadecimal floating point number, `inf`,
+// `-inf` and `nan` are accepted as well.
+func (n *Float128) SetString(s string) error {
+	switch strings.ToLower(s) {
+	case "nan", "-nan":
+		n.SetNaN()
+		return nil
+	}
+	// parse with extra precision so that rounding to binary128 happens once
+	f, _, err := big.ParseFloat(s, 0, 512, big.ToNearestEven)
+	if err != nil {
+		return newErrorf("invalid float128 value: %s", s)
+	}
+	n.SetBigFloat(f)
+	return nil
+}
+
+// String returns the shortest decimal that converts back to the same value
+func (n Float128) String() string {
+	if n.IsNaN() {
+		return "nan"
+	}
+	f, _ := n.BigFloat()
+	if f.IsInf() {
+		if f.Signbit() {
+			return "-inf"
+		}
+		return "inf"
+	}
+	return f.Text('g', -1)
+}
+
+func (n Float128) MarshalJSON() ([]byte, error) {
+	return json.Marshal(n.String())
+}
+
+// UnmarshalJSON accepts a JSON number or a string holding a number, `inf`,
+// `nan` or the 0x prefixed little endian hex produced by the ABI unpacker.
+// The latter is recognized by its 32 hex digits without `.` or `p`.
+func (n *Float128) UnmarshalJSON(b []byte) error {
+	s := string(b)
+	if strings.HasPrefix(s, "\"") {
+		if err := json.Unmarshal(b, &s); err != nil {
+			return newError(err)
+		}
+	}
+	return n.parse(s)
+}
+
+func (n *Float128) parse(s string) error {
+	if strings.HasPrefix(s, "0x") && len(s) == 34 && !strings.ContainsAny(s, ".pP") {
+		bs, err := hex.DecodeString(s[2:])
+		if err != nil {
+			return newError(err)
+		}
+		copy(n[:], bs)
+		return nil
+	}
+	return n.SetString(s)
+}
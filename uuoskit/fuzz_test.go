@@ -0,0 +1,584 @@
+package uuoskit
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fuzzAbi has a field of every kind of type expression and of the built-in
+// types whose every binary value can be packed again, node and v are
+// recursive.
+var fuzzAbi = `{
+	"version": "eosio::abi/1.1",
+	"types": [{"new_type_name": "account_name", "type": "name"}],
+	"structs": [
+		{"name": "header", "base": "", "fields": [{"name": "id", "type": "uint64"}]},
+		{"name": "node", "base": "", "fields": [
+			{"name": "value", "type": "int32"},
+			{"name": "children", "type": "node[]"}
+		]},
+		{"name": "all", "base": "header", "fields": [
+			{"name": "b", "type": "bool"},
+			{"name": "i8", "type": "int8"},
+			{"name": "u8", "type": "uint8"},
+			{"name": "i16", "type": "int16"},
+			{"name": "u16", "type": "uint16"},
+			{"name": "i32", "type": "int32"},
+			{"name": "u32", "type": "uint32"},
+			{"name": "i64", "type": "int64"},
+			{"name": "u64", "type": "uint64"},
+			{"name": "i128", "type": "int128"},
+			{"name": "u128", "type": "uint128"},
+			{"name": "vi", "type": "varint32"},
+			{"name": "vu", "type": "varuint32"},
+			{"name": "f64", "type": "float64"},
+			{"name": "n", "type": "account_name"},
+			{"name": "by", "type": "bytes"},
+			{"name": "s", "type": "string"},
+			{"name": "c256", "type": "checksum256"},
+			{"name": "opt", "type": "string?"},
+			{"name": "arr", "type": "uint16[]"},
+			{"name": "fixed", "type": "uint8[2]"},
+			{"name": "var", "type": "v"},
+			{"name": "tree", "type": "node"},
+			{"name": "ext", "type": "uint32$"}
+		]}
+	],
+	"actions": [{"name": "all", "type": "all", "ricardian_contract": ""}],
+	"tables": [],
+	"ricardian_clauses": [],
+	"variants": [{"name": "v", "types": ["int8", "string", "node", "v[]"]}]
+}`
+
+var fuzzAllValue = `{
+	"id": 1, "b": true, "i8": -1, "u8": 255, "i16": -300, "u16": 300,
+	"i32": -70000, "u32": 70000, "i64": -5000000000, "u64": 5000000000,
+	"i128": "0x0000000000000000000000000000007f", "u128": "0x000000000000000000000000000000ff",
+	"vi": -64, "vu": 128, "f64": 1.5,
+	"n": "alice", "by": "00ff", "s": "hello", "c256": "` + hex.EncodeToString(make([]byte, 32)) + `",
+	"opt": null, "arr": [1, 2, 3], "fixed": [7, 8],
+	"var": ["v[]", [["int8", 1], ["string", "x"]]],
+	"tree": {"value": 1, "children": [{"value": 2, "children": []}]},
+	"ext": 9
+}`
+
+func newFuzzSerializer(f *testing.F) *ABISerializer {
+	s := NewABISerializer()
+	if err := s.SetContractABI("fuzz", []byte(fuzzAbi)); err != nil {
+		f.Fatal(err)
+	}
+	return s
+}
+
+func FuzzDecoder(f *testing.F) {
+	f.Add([]byte{0, 1, 2, 3, 4, 5, 6, 7, 8, 9})
+	f.Add([]byte{7, 0xff, 0xff, 0xff, 0xff, 0x0f})
+	f.Add([]byte{5, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		dec := NewDecoder(data)
+		for !dec.IsEnd() {
+			op, _ := dec.UnpackUint8()
+			var err error
+			switch op % 12 {
+			case 0:
+				_, err = dec.UnpackBool()
+			case 1:
+				_, err = dec.UnpackUint16()
+			case 2:
+				_, err = dec.UnpackUint32()
+			case 3:
+				_, err = dec.UnpackUint64()
+			case 4:
+				_, err = dec.UnpackVarInt32()
+			case 5:
+				_, err = dec.UnpackVarUint32()
+			case 6:
+				_, err = dec.UnpackString()
+			case 7:
+				_, err = dec.UnpackBytes()
+			case 8:
+				_, err = dec.UnpackLength()
+			case 9:
+				_, err = dec.UnpackName()
+			case 10:
+				var a Asset
+				_, err = dec.Unpack(&a)
+			case 11:
+				_, err = dec.UnpackAction()
+			}
+			if err != nil {
+				break
+			}
+		}
+		if dec.Pos() > len(data) {
+			t.Fatalf("position %d beyond %d bytes", dec.Pos(), len(data))
+		}
+	})
+}
+
+func FuzzTransactionUnpack(f *testing.F) {
+	tx := NewTransaction(1641000000)
+	tx.RefBlockNum = 1
+	tx.RefBlockPrefix = 2
+	tx.AddAction(NewAction(NewName("eosio.token"), NewName("transfer"),
+		[]PermissionLevel{{NewName("alice"), NewName("active")}},
+		NewName("alice"), NewName("bob"), NewAsset(10000, NewSymbol("EOS", 4)), "memo"))
+	f.Add(tx.Pack())
+	f.Add([]byte{})
+	f.Add(bytes.Repeat([]byte{0xff}, 32))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		tx := &Transaction{}
+		n, err := tx.Unpack(data)
+		if err != nil {
+			return
+		}
+		if n > len(data) {
+			t.Fatalf("unpacked %d of %d bytes", n, len(data))
+		}
+		packed := tx.Pack()
+		tx2 := &Transaction{}
+		n, err = tx2.Unpack(packed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, len(packed), n)
+		assert.Equal(t, packed, tx2.Pack())
+	})
+}
+
+func FuzzUnpackABI(f *testing.F) {
+	s := newFuzzSerializer(f)
+	for _, abi := range []string{fuzzAbi, eosioTokenAbi, eosioMsigAbi} {
+		binABI, err := s.PackABI(abi)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(binABI)
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		abi, err := s.UnpackABI(data)
+		if err != nil {
+			return
+		}
+		binABI, err := s.PackABI(abi)
+		if err != nil {
+			t.Fatal(err)
+		}
+		abi2, err := s.UnpackABI(binABI)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, abi, abi2)
+	})
+}
+
+func FuzzUnpackAbiType(f *testing.F) {
+	s := newFuzzSerializer(f)
+	packed, err := s.PackAbiType("fuzz", "all", fuzzAllValue)
+	if err != nil {
+		f.Fatal(err)
+	}
+	f.Add(packed)
+	f.Add(packed[:len(packed)-4])
+	f.Add([]byte{})
+	compiled, err := s.GetCompiledABI("fuzz")
+	if err != nil {
+		f.Fatal(err)
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		value, err := s.UnpackAbiType("fuzz", "all", data)
+		_, err2 := compiled.AppendJSON(nil, "all", data)
+		if (err == nil) != (err2 == nil) {
+			t.Fatalf("UnpackAbiType: %v, CompiledABI: %v", err, err2)
+		}
+		if err != nil {
+			return
+		}
+		repacked, err := s.PackAbiType("fuzz", "all", string(value))
+		if err != nil {
+			t.Fatalf("%s: %v", value, err)
+		}
+		value2, err := s.UnpackAbiType("fuzz", "all", repacked)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, string(value), string(value2))
+	})
+}
+
+// abiGenerator builds random ABIs and random values of their types
+type abiGenerator struct {
+	rnd      *rand.Rand
+	abi      ABI
+	aliases  map[string]string
+	structs  map[string]*ABIStruct
+	variants map[string]*VariantDef
+}
+
+var generatedBuiltins = []string{
+	"bool", "int8", "uint8", "int16", "uint16", "int32", "uint32", "int64", "uint64",
+	"int128", "uint128", "varint32", "varuint32", "float32", "float64",
+	"time_point", "time_point_sec", "block_timestamp_type", "name", "bytes", "string",
+	"checksum256", "symbol", "symbol_code", "asset", "extended_asset",
+}
+
+func newAbiGenerator(seed int64) *abiGenerator {
+	g := &abiGenerator{
+		rnd:      rand.New(rand.NewSource(seed)),
+		aliases:  map[string]string{},
+		structs:  map[string]*ABIStruct{},
+		variants: map[string]*VariantDef{},
+	}
+	g.abi.Version = "eosio::abi/1.1"
+	g.abi.Types = []ABIType{}
+	g.abi.Actions = []ABIAction{}
+	g.abi.Tables = []ABITable{}
+	g.abi.RicardianClauses = []ClausePair{}
+	g.abi.Variants = []VariantDef{}
+	return g
+}
+
+func (g *abiGenerator) builtin() string {
+	return generatedBuiltins[g.rnd.Intn(len(generatedBuiltins))]
+}
+
+// typeExpr returns a type expression over the builtins and the types
+// declared so far
+func (g *abiGenerator) typeExpr() string {
+	var typ string
+	switch n := g.rnd.Intn(10); {
+	case n < 2 && len(g.abi.Structs) > 0:
+		typ = g.abi.Structs[g.rnd.Intn(len(g.abi.Structs))].Name
+	case n < 3 && len(g.abi.Variants) > 0:
+		typ = g.abi.Variants[g.rnd.Intn(len(g.abi.Variants))].Name
+	case n < 4 && len(g.abi.Types) > 0:
+		typ = g.abi.Types[g.rnd.Intn(len(g.abi.Types))].NewTypeName
+	default:
+		typ = g.builtin()
+	}
+	switch g.rnd.Intn(6) {
+	case 0:
+		typ += "?"
+	case 1:
+		typ += "[]"
+	case 2:
+		typ += "[2]"
+	}
+	return typ
+}
+
+// generate declares aliases, variants and structs, the last struct is the
+// one to pack and ends with a binary extension
+func (g *abiGenerator) generate() string {
+	for i := 0; i < 6; i++ {
+		switch g.rnd.Intn(3) {
+		case 0:
+			name := fmt.Sprintf("alias%d", i)
+			g.abi.Types = append(g.abi.Types, ABIType{NewTypeName: name, Type: g.builtin()})
+		case 1:
+			v := VariantDef{Name: fmt.Sprintf("variant%d", i)}
+			for j := g.rnd.Intn(3) + 1; j > 0; j-- {
+				typ := g.typeExpr()
+				if !strings.HasSuffix(typ, "?") {
+					v.Types = append(v.Types, typ)
+				}
+			}
+			if len(v.Types) == 0 {
+				v.Types = append(v.Types, "uint8")
+			}
+			g.abi.Variants = append(g.abi.Variants, v)
+		default:
+			g.abi.Structs = append(g.abi.Structs, g.newStruct(fmt.Sprintf("struct%d", i)))
+		}
+	}
+	top := g.newStruct("top")
+	if len(g.abi.Structs) > 0 && g.rnd.Intn(2) == 0 {
+		top.Base = g.abi.Structs[g.rnd.Intn(len(g.abi.Structs))].Name
+	}
+	top.Fields = append(top.Fields, ABIStructField{Name: "ext", Type: g.builtin() + "$"})
+	g.abi.Structs = append(g.abi.Structs, top)
+
+	for i := range g.abi.Types {
+		g.aliases[g.abi.Types[i].NewTypeName] = g.abi.Types[i].Type
+	}
+	for i := range g.abi.Structs {
+		g.structs[g.abi.Structs[i].Name] = &g.abi.Structs[i]
+	}
+	for i := range g.abi.Variants {
+		g.variants[g.abi.Variants[i].Name] = &g.abi.Variants[i]
+	}
+	return "top"
+}
+
+func (g *abiGenerator) newStruct(name string) ABIStruct {
+	s := ABIStruct{Name: name, Fields: []ABIStructField{}}
+	for j := g.rnd.Intn(4); j > 0; j-- {
+		s.Fields = append(s.Fields, ABIStructField{Name: fmt.Sprintf("%s_f%d", name, j), Type: g.typeExpr()})
+	}
+	return s
+}
+
+func (g *abiGenerator) hex(n int) string {
+	b := make([]byte, n)
+	g.rnd.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func (g *abiGenerator) symbolCode() string {
+	code := make([]byte, g.rnd.Intn(7)+1)
+	for i := range code {
+		code[i] = byte('A' + g.rnd.Intn(26))
+	}
+	return string(code)
+}
+
+func (g *abiGenerator) asset() string {
+	amount := g.rnd.Int63n(1<<62) - 1<<61
+	return NewAsset(amount, NewSymbol(g.symbolCode(), g.rnd.Intn(maxAssetPrecision+1))).String()
+}
+
+func (g *abiGenerator) value(typ string) interface{} {
+	if strings.HasSuffix(typ, "?") {
+		if g.rnd.Intn(2) == 0 {
+			return nil
+		}
+		return g.value(strings.TrimSuffix(typ, "?"))
+	}
+	if inner, size, ok := splitArrayType(typ); ok {
+		if size < 0 {
+			size = g.rnd.Intn(3)
+		}
+		arr := make([]interface{}, size)
+		for i := range arr {
+			arr[i] = g.value(inner)
+		}
+		return arr
+	}
+	if base, ok := g.aliases[typ]; ok {
+		return g.value(base)
+	}
+	if v, ok := g.variants[typ]; ok {
+		member := v.Types[g.rnd.Intn(len(v.Types))]
+		return []interface{}{member, g.value(member)}
+	}
+	if s, ok := g.structs[typ]; ok {
+		m := map[string]interface{}{}
+		if s.Base != "" {
+			m = g.value(s.Base).(map[string]interface{})
+		}
+		for _, f := range s.Fields {
+			if strings.HasSuffix(f.Type, "$") {
+				if g.rnd.Intn(2) == 0 {
+					m[f.Name] = g.value(strings.TrimSuffix(f.Type, "$"))
+				}
+				continue
+			}
+			m[f.Name] = g.value(f.Type)
+		}
+		return m
+	}
+
+	r := g.rnd
+	switch typ {
+	case "bool":
+		return r.Intn(2) == 0
+	case "int8":
+		return int8(r.Uint32())
+	case "uint8":
+		return uint8(r.Uint32())
+	case "int16":
+		return int16(r.Uint32())
+	case "uint16":
+		return uint16(r.Uint32())
+	case "int32", "varint32":
+		return int32(r.Uint32())
+	case "uint32", "varuint32":
+		return r.Uint32()
+	case "int64":
+		return int64(r.Uint64())
+	case "uint64":
+		return r.Uint64()
+	case "int128", "uint128":
+		return "0x" + g.hex(16)
+	case "float32":
+		return json.Number(strconv.FormatFloat(float64(float32(r.NormFloat64()*1e6)), 'g', -1, 32))
+	case "float64":
+		return json.Number(strconv.FormatFloat(r.NormFloat64()*1e6, 'g', -1, 64))
+	case "time_point":
+		return TimePoint{uint64(r.Int63n(253402300799000)) * 1000}.String()
+	case "time_point_sec":
+		return TimePointSec{r.Uint32()}.String()
+	case "block_timestamp_type":
+		return BlockTimestampType{r.Uint32()}.String()
+	case "name":
+		name := make([]byte, r.Intn(12)+1)
+		for i := range name {
+			name[i] = "12345abcdefghijklmnopqrstuvwxyz"[r.Intn(31)]
+		}
+		return string(name)
+	case "bytes":
+		return g.hex(r.Intn(8))
+	case "string":
+		chars := []rune(`ab "\é€`)
+		s := make([]rune, r.Intn(8))
+		for i := range s {
+			s[i] = chars[r.Intn(len(chars))]
+		}
+		return string(s)
+	case "checksum256":
+		return g.hex(32)
+	case "symbol":
+		return fmt.Sprintf("%d,%s", r.Intn(maxAssetPrecision+1), g.symbolCode())
+	case "symbol_code":
+		return g.symbolCode()
+	case "asset":
+		return g.asset()
+	case "extended_asset":
+		return map[string]interface{}{"quantity": g.asset(), "contract": g.value("name")}
+	}
+	panic("no generator for " + typ)
+}
+
+// TestGeneratedAbiRoundTrip checks that unpacking then packing again gives
+// back the same bytes, for random ABIs and random values of their types
+func TestGeneratedAbiRoundTrip(t *testing.T) {
+	for seed := int64(0); seed < 200; seed++ {
+		g := newAbiGenerator(seed)
+		typ := g.generate()
+		strABI, err := json.Marshal(&g.abi)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		s := NewABISerializer()
+		binABI, err := s.PackABI(string(strABI))
+		if !assert.Nil(t, err, "seed %d", seed) {
+			continue
+		}
+		unpackedABI, err := s.UnpackABI(binABI)
+		if !assert.Nil(t, err, "seed %d", seed) {
+			continue
+		}
+		binABI2, err := s.PackABI(unpackedABI)
+		assert.Nil(t, err, "seed %d", seed)
+		assert.Equal(t, binABI, binABI2, "seed %d", seed)
+		if !assert.Nil(t, s.SetContractABI("gen", []byte(unpackedABI)), "seed %d: %s", seed, unpackedABI) {
+			continue
+		}
+		compiled, err := s.GetCompiledABI("gen")
+		if !assert.Nil(t, err, "seed %d", seed) {
+			continue
+		}
+
+		for i := 0; i < 10; i++ {
+			value, err := json.Marshal(g.value(typ))
+			if err != nil {
+				t.Fatal(err)
+			}
+			packed, err := s.PackAbiType("gen", typ, string(value))
+			if !assert.Nil(t, err, "seed %d: %s: %v", seed, value, err) {
+				continue
+			}
+			unpacked, err := s.UnpackAbiType("gen", typ, packed)
+			if !assert.Nil(t, err, "seed %d: %s: %v", seed, value, err) {
+				continue
+			}
+			repacked, err := s.PackAbiType("gen", typ, string(unpacked))
+			assert.Nil(t, err, "seed %d: %s", seed, unpacked)
+			assert.Equal(t, packed, repacked, "seed %d: %s", seed, value)
+			decoded, err := compiled.AppendJSON(nil, typ, packed)
+			assert.Nil(t, err, "seed %d", seed)
+			assert.Equal(t, string(unpacked), string(decoded), "seed %d", seed)
+		}
+	}
+}
+
+func TestDecoderLimits(t *testing.T) {
+	// truncated and overlong varints
+	_, err := NewDecoder([]byte{0x80, 0x80}).UnpackVarUint32()
+	assert.NotNil(t, err)
+	_, err = NewDecoder([]byte{0x80, 0x80, 0x80, 0x80, 0x80, 0x00}).UnpackVarUint32()
+	assert.NotNil(t, err)
+	_, err = NewDecoder([]byte{}).UnpackLength()
+	assert.NotNil(t, err)
+	v, err := NewDecoder([]byte{0xff, 0xff, 0xff, 0xff, 0x0f}).UnpackVarUint32()
+	assert.Nil(t, err)
+	assert.Equal(t, VarUint32(0xffffffff), v)
+
+	// declared lengths over the limit or the remaining bytes
+	_, err = NewDecoder(PackVarUint32(maxArrayAllocSize + 1)).UnpackLength()
+	assert.NotNil(t, err)
+	_, err = NewDecoder(append(PackVarUint32(1000), 1, 2, 3)).UnpackBytes()
+	assert.NotNil(t, err)
+
+	tx := &Transaction{}
+	data := make([]byte, 4+2+4+1+1+1)
+	data = append(data, PackVarUint32(maxArrayAllocSize)...)
+	_, err = tx.Unpack(data)
+	assert.NotNil(t, err)
+
+	action := &Action{}
+	data = make([]byte, 16)
+	data = append(data, PackVarUint32(1<<20)...)
+	_, err = action.Unpack(data)
+	assert.NotNil(t, err)
+
+	s := NewABISerializer()
+	_, err = s.UnpackABI([]byte{0})
+	assert.NotNil(t, err)
+	_, err = s.UnpackABI(append([]byte{0}, PackVarUint32(maxArrayAllocSize)...))
+	assert.NotNil(t, err)
+}
+
+func TestAbiDepthLimits(t *testing.T) {
+	s := NewABISerializer()
+	assert.Nil(t, s.SetContractABI("fuzz", []byte(fuzzAbi)))
+
+	// a node nested deeper than maxAbiTypeDepth
+	var data []byte
+	for i := 0; i < 2*maxAbiTypeDepth; i++ {
+		data = append(data, 0, 0, 0, 0, 1)
+	}
+	data = append(data, 0, 0, 0, 0, 0)
+	_, err := s.UnpackAbiType("fuzz", "node", data)
+	assert.NotNil(t, err)
+	compiled, err := s.GetCompiledABI("fuzz")
+	assert.Nil(t, err)
+	_, err = compiled.AppendJSON(nil, "node", data)
+	assert.NotNil(t, err)
+
+	// more children than remaining bytes
+	_, err = s.UnpackAbiType("fuzz", "node", []byte{0, 0, 0, 0, 0xff, 0xff, 0x03})
+	assert.NotNil(t, err)
+
+	// structs with a cyclic base
+	s.SetValidateABI(false)
+	assert.Nil(t, s.SetContractABI("cyclic", []byte(`{
+		"version": "eosio::abi/1.1",
+		"structs": [
+			{"name": "a", "base": "b", "fields": []},
+			{"name": "b", "base": "a", "fields": []},
+			{"name": "empty", "base": "", "fields": [{"name": "x", "type": "uint8$"}]},
+			{"name": "many", "base": "", "fields": [{"name": "items", "type": "empty[]"}]}
+		]
+	}`)))
+	_, err = s.UnpackAbiType("cyclic", "a", []byte{})
+	assert.NotNil(t, err)
+	_, err = s.PackAbiType("cyclic", "a", `{}`)
+	assert.NotNil(t, err)
+	_, err = s.GetCompiledABI("cyclic")
+	assert.NotNil(t, err)
+
+	// arrays of a type that packs to nothing are not bound by the size
+	v, err := s.UnpackAbiType("cyclic", "many", PackVarUint32(3))
+	assert.Nil(t, err)
+	assert.Equal(t, `{"items":[{},{},{}]}`, string(v))
+}
@@ -0,0 +1,592 @@
+package uuoskit
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type JsonKind int
+
+const (
+	JsonNull JsonKind = iota
+	JsonBool
+	JsonNumber
+	JsonString
+	JsonArray
+	JsonObject
+)
+
+func (k JsonKind) String() string {
+	switch k {
+	case JsonNull:
+		return "null"
+	case JsonBool:
+		return "bool"
+	case JsonNumber:
+		return "number"
+	case JsonString:
+		return "string"
+	case JsonArray:
+		return "array"
+	case JsonObject:
+		return "object"
+	}
+	return "unknown"
+}
+
+const maxJsonDepth = 512
+
+var (
+	ErrJsonKeyNotFound     = errors.New("key not found")
+	ErrJsonIndexOutOfRange = errors.New("index out of range")
+	ErrJsonTypeMismatch    = errors.New("type mismatch")
+	ErrJsonInvalidKey      = errors.New("invalid key type")
+)
+
+// JsonPathError is returned by the JsonValue accessors. Err is one of the
+// ErrJson* values or the error of a failed conversion, use errors.Is to
+// tell them apart.
+type JsonPathError struct {
+	Path string
+	Err  error
+}
+
+func (e *JsonPathError) Error() string {
+	if e.Path == "" {
+		return e.Err.Error()
+	}
+	return e.Path + ": " + e.Err.Error()
+}
+
+func (e *JsonPathError) Unwrap() error {
+	return e.Err
+}
+
+// JsonValue is a JSON document that keeps strings, numbers, bools and null
+// apart. Numbers keep their literal text so that 64 bit and larger integers
+// survive a round trip, objects keep the order of their keys.
+type JsonValue struct {
+	kind JsonKind
+	// bool value
+	b bool
+	// string value or the literal text of a number
+	s    string
+	arr  []JsonValue
+	obj  map[string]JsonValue
+	keys []string
+}
+
+// NewJsonValue converts a Go value to a JsonValue. Supported are nil, bool,
+// string, json.Number, Go integers and floats, *big.Int, JsonValue,
+// []JsonValue, map[string]JsonValue, []interface{} and
+// map[string]interface{}. It panics on other types, see SetValue.
+func NewJsonValue(value interface{}) JsonValue {
+	retValue := JsonValue{}
+	if err := retValue.SetValue(value); err != nil {
+		panic(err)
+	}
+	return retValue
+}
+
+func NewJsonString(s string) JsonValue {
+	return JsonValue{kind: JsonString, s: s}
+}
+
+func NewJsonBool(b bool) JsonValue {
+	return JsonValue{kind: JsonBool, b: b}
+}
+
+// NewJsonNumber creates a number from its literal text, e.g. "1.5e3"
+func NewJsonNumber(n string) (JsonValue, error) {
+	if !isJsonNumber(n) {
+		return JsonValue{}, newErrorf("invalid json number %q", n)
+	}
+	return JsonValue{kind: JsonNumber, s: n}, nil
+}
+
+func NewJsonArray(values ...JsonValue) JsonValue {
+	if values == nil {
+		values = []JsonValue{}
+	}
+	return JsonValue{kind: JsonArray, arr: values}
+}
+
+// NewJsonObject creates an empty object, use Set to add members
+func NewJsonObject() JsonValue {
+	return JsonValue{kind: JsonObject, obj: make(map[string]JsonValue)}
+}
+
+// ParseJsonValue parses a complete JSON document
+func ParseJsonValue(data []byte) (JsonValue, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	v, err := decodeJsonValue(dec, 0)
+	if err != nil {
+		return JsonValue{}, err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return JsonValue{}, newErrorf("invalid json: trailing data after value")
+	}
+	return v, nil
+}
+
+func isJsonNumber(s string) bool {
+	return json.Valid([]byte(s)) && s != "" && (s[0] == '-' || (s[0] >= '0' && s[0] <= '9'))
+}
+
+func (b *JsonValue) SetValue(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*b = JsonValue{}
+	case JsonValue:
+		*b = v
+	case bool:
+		*b = NewJsonBool(v)
+	case string:
+		*b = NewJsonString(v)
+	case json.Number:
+		n, err := NewJsonNumber(string(v))
+		if err != nil {
+			return err
+		}
+		*b = n
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		*b = JsonValue{kind: JsonNumber, s: fmt.Sprintf("%d", v)}
+	case float32:
+		*b = JsonValue{kind: JsonNumber, s: strconv.FormatFloat(float64(v), 'g', -1, 32)}
+	case float64:
+		*b = JsonValue{kind: JsonNumber, s: strconv.FormatFloat(v, 'g', -1, 64)}
+	case *big.Int:
+		*b = JsonValue{kind: JsonNumber, s: v.String()}
+	case []JsonValue:
+		*b = NewJsonArray(v...)
+	case map[string]JsonValue:
+		obj := NewJsonObject()
+		for _, k := range sortedKeys(v) {
+			obj.Set(k, v[k])
+		}
+		*b = obj
+	case []interface{}:
+		arr := make([]JsonValue, len(v))
+		for i := range v {
+			if err := arr[i].SetValue(v[i]); err != nil {
+				return err
+			}
+		}
+		*b = NewJsonArray(arr...)
+	case map[string]interface{}:
+		obj := NewJsonObject()
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			sub := JsonValue{}
+			if err := sub.SetValue(v[k]); err != nil {
+				return err
+			}
+			obj.Set(k, sub)
+		}
+		*b = obj
+	default:
+		return newErrorf("unsupported json value type %T", value)
+	}
+	return nil
+}
+
+func sortedKeys(m map[string]JsonValue) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// GetValue returns nil, bool, json.Number, string, []JsonValue or
+// map[string]JsonValue depending on the kind of the value.
+func (b JsonValue) GetValue() interface{} {
+	switch b.kind {
+	case JsonBool:
+		return b.b
+	case JsonNumber:
+		return json.Number(b.s)
+	case JsonString:
+		return b.s
+	case JsonArray:
+		return b.arr
+	case JsonObject:
+		return b.obj
+	}
+	return nil
+}
+
+func (b JsonValue) Kind() JsonKind {
+	return b.kind
+}
+
+func (b JsonValue) IsNull() bool {
+	return b.kind == JsonNull
+}
+
+// Len returns the number of elements of an array or members of an object
+func (b JsonValue) Len() int {
+	if b.kind == JsonArray {
+		return len(b.arr)
+	}
+	return len(b.keys)
+}
+
+// Keys returns the member names of an object in document order
+func (b JsonValue) Keys() []string {
+	return b.keys
+}
+
+// Set adds or replaces a member of an object, a null value becomes an
+// empty object first.
+func (b *JsonValue) Set(key string, value JsonValue) error {
+	if b.kind == JsonNull {
+		*b = NewJsonObject()
+	}
+	if b.kind != JsonObject {
+		return &JsonPathError{"", ErrJsonTypeMismatch}
+	}
+	if _, ok := b.obj[key]; !ok {
+		b.keys = append(b.keys, key)
+	}
+	b.obj[key] = value
+	return nil
+}
+
+// Append adds elements to an array, a null value becomes an empty array
+// first.
+func (b *JsonValue) Append(values ...JsonValue) error {
+	if b.kind == JsonNull {
+		*b = NewJsonArray()
+	}
+	if b.kind != JsonArray {
+		return &JsonPathError{"", ErrJsonTypeMismatch}
+	}
+	b.arr = append(b.arr, values...)
+	return nil
+}
+
+// GetStringValue returns the string of a string value
+func (b JsonValue) GetStringValue() (string, bool) {
+	if b.kind != JsonString {
+		return "", false
+	}
+	return b.s, true
+}
+
+func appendJsonPath(path string, key interface{}) string {
+	switch k := key.(type) {
+	case string:
+		if path == "" {
+			return k
+		}
+		return path + "." + k
+	case int:
+		return path + "[" + strconv.Itoa(k) + "]"
+	}
+	return path
+}
+
+// Get follows a path of object keys (string) and array indexes (int)
+func (b JsonValue) Get(keys ...interface{}) (JsonValue, error) {
+	value := b
+	path := ""
+	for _, key := range keys {
+		path = appendJsonPath(path, key)
+		switch k := key.(type) {
+		case string:
+			if value.kind != JsonObject {
+				return JsonValue{}, &JsonPathError{path, fmt.Errorf("%w: %s is not an object", ErrJsonTypeMismatch, value.kind)}
+			}
+			sub, ok := value.obj[k]
+			if !ok {
+				return JsonValue{}, &JsonPathError{path, ErrJsonKeyNotFound}
+			}
+			value = sub
+		case int:
+			if value.kind != JsonArray {
+				return JsonValue{}, &JsonPathError{path, fmt.Errorf("%w: %s is not an array", ErrJsonTypeMismatch, value.kind)}
+			}
+			if k < 0 || k >= len(value.arr) {
+				return JsonValue{}, &JsonPathError{path, ErrJsonIndexOutOfRange}
+			}
+			value = value.arr[k]
+		default:
+			return JsonValue{}, &JsonPathError{path, ErrJsonInvalidKey}
+		}
+	}
+	return value, nil
+}
+
+func (b JsonValue) getKind(kind JsonKind, keys []interface{}) (JsonValue, string, error) {
+	v, err := b.Get(keys...)
+	if err != nil {
+		return v, "", err
+	}
+	path := ""
+	for _, k := range keys {
+		path = appendJsonPath(path, k)
+	}
+	if v.kind != kind {
+		return v, path, &JsonPathError{path, fmt.Errorf("%w: expected %s, got %s", ErrJsonTypeMismatch, kind, v.kind)}
+	}
+	return v, path, nil
+}
+
+func (b JsonValue) GetString(keys ...interface{}) (string, error) {
+	v, _, err := b.getKind(JsonString, keys)
+	return v.s, err
+}
+
+func (b JsonValue) GetBool(keys ...interface{}) (bool, error) {
+	v, _, err := b.getKind(JsonBool, keys)
+	return v.b, err
+}
+
+func (b JsonValue) GetArray(keys ...interface{}) ([]JsonValue, error) {
+	v, _, err := b.getKind(JsonArray, keys)
+	return v.arr, err
+}
+
+func (b JsonValue) GetObject(keys ...interface{}) (map[string]JsonValue, error) {
+	v, _, err := b.getKind(JsonObject, keys)
+	return v.obj, err
+}
+
+// numberText returns the literal of a number, or the content of a string
+// since nodeos renders 64 bit and larger integers as strings.
+func (b JsonValue) numberText(keys []interface{}) (string, string, error) {
+	v, err := b.Get(keys...)
+	path := ""
+	for _, k := range keys {
+		path = appendJsonPath(path, k)
+	}
+	if err != nil {
+		return "", path, err
+	}
+	if v.kind != JsonNumber && v.kind != JsonString {
+		return "", path, &JsonPathError{path, fmt.Errorf("%w: expected number, got %s", ErrJsonTypeMismatch, v.kind)}
+	}
+	return v.s, path, nil
+}
+
+// GetInt64 accepts a number or a string holding an integer
+func (b JsonValue) GetInt64(keys ...interface{}) (int64, error) {
+	s, path, err := b.numberText(keys)
+	if err != nil {
+		return 0, err
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, &JsonPathError{path, err}
+	}
+	return n, nil
+}
+
+// GetUint64 accepts a number or a string holding an integer
+func (b JsonValue) GetUint64(keys ...interface{}) (uint64, error) {
+	s, path, err := b.numberText(keys)
+	if err != nil {
+		return 0, err
+	}
+	n, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		return 0, &JsonPathError{path, err}
+	}
+	return n, nil
+}
+
+// GetFloat64 accepts a number or a string holding a number
+func (b JsonValue) GetFloat64(keys ...interface{}) (float64, error) {
+	s, path, err := b.numberText(keys)
+	if err != nil {
+		return 0, err
+	}
+	n, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, &JsonPathError{path, err}
+	}
+	return n, nil
+}
+
+// GetBigInt accepts a number or a string holding an integer of any size
+func (b JsonValue) GetBigInt(keys ...interface{}) (*big.Int, error) {
+	s, path, err := b.numberText(keys)
+	if err != nil {
+		return nil, err
+	}
+	n, ok := new(big.Int).SetString(s, 10)
+	if !ok {
+		return nil, &JsonPathError{path, fmt.Errorf("invalid integer %q", s)}
+	}
+	return n, nil
+}
+
+// GetTime parses a string in one of the layouts accepted by ParseTime
+func (b JsonValue) GetTime(keys ...interface{}) (*time.Time, error) {
+	v, path, err := b.getKind(JsonString, keys)
+	if err != nil {
+		return nil, err
+	}
+	t, err := ParseTime(v.s)
+	if err != nil {
+		return nil, &JsonPathError{path, err}
+	}
+	return &t, nil
+}
+
+// String returns the value encoded as JSON
+func (b JsonValue) String() string {
+	bs, _ := b.MarshalJSON()
+	return string(bs)
+}
+
+func (b JsonValue) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := b.encode(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (b *JsonValue) encode(buf *bytes.Buffer) error {
+	switch b.kind {
+	case JsonNull:
+		buf.WriteString("null")
+	case JsonBool:
+		buf.WriteString(strconv.FormatBool(b.b))
+	case JsonNumber:
+		buf.WriteString(b.s)
+	case JsonString:
+		bs, err := json.Marshal(b.s)
+		if err != nil {
+			return newError(err)
+		}
+		buf.Write(bs)
+	case JsonArray:
+		buf.WriteByte('[')
+		for i := range b.arr {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := b.arr[i].encode(buf); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+	case JsonObject:
+		buf.WriteByte('{')
+		for i, k := range b.keys {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			bs, err := json.Marshal(k)
+			if err != nil {
+				return newError(err)
+			}
+			buf.Write(bs)
+			buf.WriteByte(':')
+			v := b.obj[k]
+			if err := v.encode(buf); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+	default:
+		return newErrorf("bad JsonValue kind %d", b.kind)
+	}
+	return nil
+}
+
+func (b *JsonValue) UnmarshalJSON(data []byte) error {
+	v, err := ParseJsonValue(data)
+	if err != nil {
+		return err
+	}
+	*b = v
+	return nil
+}
+
+func decodeJsonValue(dec *json.Decoder, depth int) (JsonValue, error) {
+	if depth > maxJsonDepth {
+		return JsonValue{}, newErrorf("invalid json: nested too deeply")
+	}
+	tok, err := dec.Token()
+	if err != nil {
+		return JsonValue{}, newErrorf("invalid json: %s", err.Error())
+	}
+	switch t := tok.(type) {
+	case nil:
+		return JsonValue{}, nil
+	case bool:
+		return NewJsonBool(t), nil
+	case json.Number:
+		return JsonValue{kind: JsonNumber, s: string(t)}, nil
+	case string:
+		return NewJsonString(t), nil
+	case json.Delim:
+		if t == '[' {
+			arr := NewJsonArray()
+			for dec.More() {
+				v, err := decodeJsonValue(dec, depth+1)
+				if err != nil {
+					return JsonValue{}, err
+				}
+				arr.arr = append(arr.arr, v)
+			}
+			_, err := dec.Token()
+			if err != nil {
+				return JsonValue{}, newErrorf("invalid json: %s", err.Error())
+			}
+			return arr, nil
+		}
+		if t == '{' {
+			obj := NewJsonObject()
+			for dec.More() {
+				key, err := dec.Token()
+				if err != nil {
+					return JsonValue{}, newErrorf("invalid json: %s", err.Error())
+				}
+				v, err := decodeJsonValue(dec, depth+1)
+				if err != nil {
+					return JsonValue{}, err
+				}
+				obj.Set(key.(string), v)
+			}
+			_, err := dec.Token()
+			if err != nil {
+				return JsonValue{}, newErrorf("invalid json: %s", err.Error())
+			}
+			return obj, nil
+		}
+	}
+	return JsonValue{}, newErrorf("invalid json: unexpected %v", tok)
+}
+
+// abiText returns the value in the form expected by ParseAbiStringValue:
+// strings quoted, numbers, bools and null as their literals.
+func (b JsonValue) abiText() string {
+	switch b.kind {
+	case JsonString:
+		return strconv.Quote(b.s)
+	case JsonNumber:
+		return b.s
+	case JsonBool:
+		return strconv.FormatBool(b.b)
+	}
+	return strings.TrimSpace(b.String())
+}
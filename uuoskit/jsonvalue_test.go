@@ -2,7 +2,10 @@ package uuoskit
 
 import (
 	"encoding/json"
+	"errors"
 	"testing"
+
+	"github.com/stretchr/testify/assert"
 )
 
 func TestJsonValue(t *testing.T) {
@@ -24,23 +27,121 @@ func TestJsonValue(t *testing.T) {
 	if err != nil {
 		t.Error(err)
 	}
-	{
-		r2 := r.(JsonValue)
-		t.Logf("+++%T  %v", r2, r2.GetValue().(string))
-	}
+	assert.Equal(t, "goodbye,world", r.GetValue().(string))
 
 	{
 		r, err := json.Marshal(jsonValueMap)
 		if err != nil {
 			t.Error(err)
 		}
-		t.Logf(string(r))
+		assert.Equal(t, `{"bar":"hello,world","foo":["goodbye,world","123"]}`, string(r))
 	}
 	{
 		r, err := json.Marshal(rootJsonValue)
 		if err != nil {
 			t.Error(err)
 		}
-		t.Logf(string(r))
+		// numeric looking strings keep their quotes
+		assert.Equal(t, `{"bar":"hello,world","foo":["goodbye,world","123"]}`, string(r))
 	}
 }
+
+func TestJsonValueRoundTrip(t *testing.T) {
+	doc := `{"memo":"123","amount":18446744073709551615,"big":123456789012345678901234567890,` +
+		`"f":1.50,"ok":true,"none":null,"list":[1,"a",[],{}],"esc":"a\"b\\cé/<>","z":{"b":1,"a":2}}`
+	v, err := ParseJsonValue([]byte(doc))
+	assert.Nil(t, err)
+	r, err := json.Marshal(v)
+	assert.Nil(t, err)
+	// strings are escaped like encoding/json does
+	assert.Equal(t, `{"memo":"123","amount":18446744073709551615,"big":123456789012345678901234567890,`+
+		`"f":1.50,"ok":true,"none":null,"list":[1,"a",[],{}],"esc":"a\"b\\cé/\u003c\u003e","z":{"b":1,"a":2}}`, string(r))
+
+	assert.Equal(t, JsonString, mustGet(t, v, "memo").Kind())
+	assert.Equal(t, JsonNumber, mustGet(t, v, "amount").Kind())
+	assert.Equal(t, JsonBool, mustGet(t, v, "ok").Kind())
+	assert.True(t, mustGet(t, v, "none").IsNull())
+	assert.Equal(t, []string{"b", "a"}, mustGet(t, v, "z").Keys())
+
+	var m map[string]JsonValue
+	assert.Nil(t, json.Unmarshal([]byte(doc), &m))
+	s, err := m["esc"].GetString()
+	assert.Nil(t, err)
+	assert.Equal(t, "a\"b\\cé/<>", s)
+
+	_, err = ParseJsonValue([]byte(`{"a":1} x`))
+	assert.NotNil(t, err)
+	_, err = ParseJsonValue([]byte(`{"a":}`))
+	assert.NotNil(t, err)
+}
+
+func mustGet(t *testing.T, v JsonValue, keys ...interface{}) JsonValue {
+	r, err := v.Get(keys...)
+	assert.Nil(t, err)
+	return r
+}
+
+func TestJsonValueAccessors(t *testing.T) {
+	v, err := ParseJsonValue([]byte(`{"account_name":"eosio","ram_quota":-1,"net_weight":"18446744073709551615",` +
+		`"created":"2018-06-01T12:00:00.000","privileged":true,"permissions":[{"perm_name":"active"}],"x":1.5}`))
+	assert.Nil(t, err)
+
+	s, err := v.GetString("permissions", 0, "perm_name")
+	assert.Nil(t, err)
+	assert.Equal(t, "active", s)
+	n, err := v.GetInt64("ram_quota")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(-1), n)
+	u, err := v.GetUint64("net_weight")
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(18446744073709551615), u)
+	bi, err := v.GetBigInt("net_weight")
+	assert.Nil(t, err)
+	assert.Equal(t, "18446744073709551615", bi.String())
+	f, err := v.GetFloat64("x")
+	assert.Nil(t, err)
+	assert.Equal(t, 1.5, f)
+	b, err := v.GetBool("privileged")
+	assert.Nil(t, err)
+	assert.True(t, b)
+	tm, err := v.GetTime("created")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1527854400), tm.Unix())
+	arr, err := v.GetArray("permissions")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(arr))
+
+	var pathErr *JsonPathError
+	_, err = v.GetString("permissions", 1, "perm_name")
+	assert.True(t, errors.Is(err, ErrJsonIndexOutOfRange))
+	assert.True(t, errors.As(err, &pathErr))
+	assert.Equal(t, "permissions[1]", pathErr.Path)
+	_, err = v.GetString("missing")
+	assert.True(t, errors.Is(err, ErrJsonKeyNotFound))
+	_, err = v.GetString("ram_quota")
+	assert.True(t, errors.Is(err, ErrJsonTypeMismatch))
+	assert.Equal(t, "ram_quota: type mismatch: expected string, got number", err.Error())
+	_, err = v.GetString("account_name", "x")
+	assert.True(t, errors.Is(err, ErrJsonTypeMismatch))
+	_, err = v.Get(1.5)
+	assert.True(t, errors.Is(err, ErrJsonInvalidKey))
+	_, err = v.GetInt64("x")
+	assert.True(t, errors.As(err, &pathErr))
+	assert.Equal(t, "x", pathErr.Path)
+	_, err = v.GetTime("account_name")
+	assert.NotNil(t, err)
+
+	obj := NewJsonObject()
+	obj.Set("b", NewJsonBool(false))
+	obj.Set("a", NewJsonValue(uint64(18446744073709551615)))
+	obj.Set("b", NewJsonValue(nil))
+	list := NewJsonArray()
+	list.Append(NewJsonString("1"), NewJsonValue(map[string]interface{}{"k": []interface{}{1.25, "v"}}))
+	obj.Set("list", list)
+	assert.Equal(t, `{"b":null,"a":18446744073709551615,"list":["1",{"k":[1.25,"v"]}]}`, obj.String())
+	assert.NotNil(t, list.Set("x", JsonValue{}))
+
+	_, err = NewJsonNumber("0x10")
+	assert.NotNil(t, err)
+	assert.NotNil(t, new(JsonValue).SetValue(struct{}{}))
+}
@@ -0,0 +1,60 @@
+package uuoskit
+
+import (
+	"crypto/rand"
+	"io"
+	"math/big"
+
+	secp256k1 "github.com/armoniax/go-secp256k1"
+)
+
+// CreateKey returns a new key pair as {"private": ..., "public": ...}, the
+// public key is in the AM format if oldPubKeyFormat is set and PUB_K1_
+// otherwise.
+func CreateKey(oldPubKeyFormat bool) map[string]string {
+	_priv := GeneratePrivateKey()
+
+	ret := make(map[string]string)
+	ret["private"] = _priv.String()
+	if oldPubKeyFormat {
+		ret["public"] = _priv.GetPublicKey().StringAM()
+	} else {
+		ret["public"] = _priv.GetPublicKey().String()
+	}
+	return ret
+}
+
+// GeneratePrivateKey returns a new random key read from crypto/rand
+func GeneratePrivateKey() *secp256k1.PrivateKey {
+	return secp256k1.NewPrivateKey(genPrivKey(rand.Reader))
+}
+
+// https://github.com/tendermint/tendermint/blob/de2cffe7a44e99bf81a62c542d50ccbf28dc852a/crypto/secp256k1/secp256k1.go#L73
+// genPrivKey generates a new secp256k1 private key using the provided reader.
+func genPrivKey(rand io.Reader) []byte {
+	var privKeyBytes [32]byte
+	d := new(big.Int)
+
+	//"github.com/btcsuite/btcd/btcec"
+	//btcec.S256().N
+	//log.Println(btcec.S256().N.Bytes())
+	N := big.NewInt(0).SetBytes([]byte{255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 254, 186, 174, 220, 230, 175, 72, 160, 59, 191, 210, 94, 140, 208, 54, 65, 65})
+
+	for {
+		privKeyBytes = [32]byte{}
+		_, err := io.ReadFull(rand, privKeyBytes[:])
+		if err != nil {
+			panic(err)
+		}
+
+		d.SetBytes(privKeyBytes[:])
+		// break if we found a valid point (i.e. > 0 and < N == curverOrder)
+		// isValidFieldElement := 0 < d.Sign() && d.Cmp(btcec.S256().N) < 0
+		isValidFieldElement := 0 < d.Sign() && d.Cmp(N) < 0
+		if isValidFieldElement {
+			break
+		}
+	}
+
+	return privKeyBytes[:]
+}
@@ -0,0 +1,240 @@
+package uuoskit
+
+import (
+	"encoding/hex"
+	"encoding/json"
+)
+
+// ProposalApproval is a requested or provided approval of a proposal
+type ProposalApproval struct {
+	Level PermissionLevel `json:"level"`
+	Time  TimePoint       `json:"time"`
+}
+
+// ProposalApprovals is a row of the approvals2 table of eosio.msig
+type ProposalApprovals struct {
+	Version            uint8              `json:"version"`
+	ProposalName       Name               `json:"proposal_name"`
+	RequestedApprovals []ProposalApproval `json:"requested_approvals"`
+	ProvidedApprovals  []ProposalApproval `json:"provided_approvals"`
+}
+
+// Proposal is a row of the proposal table of eosio.msig, Transaction is
+// unpacked from PackedTransaction.
+type Proposal struct {
+	Proposer          Name         `json:"proposer"`
+	ProposalName      Name         `json:"proposal_name"`
+	PackedTransaction Bytes        `json:"packed_transaction"`
+	EarliestExecTime  *TimePoint   `json:"earliest_exec_time"`
+	Transaction       *Transaction `json:"-"`
+}
+
+func (api *ChainApi) msigAction(name string, auth PermissionLevel, data []byte) *Action {
+	return &Action{
+		Account:       api.Profile.MsigAccount,
+		Name:          NewName(name),
+		Authorization: []PermissionLevel{auth},
+		Data:          data,
+	}
+}
+
+// Propose proposes tx as proposalName of proposer, requested lists the
+// permissions whose approvals are needed to execute it.
+func (api *ChainApi) Propose(proposer string, proposalName string, requested []PermissionLevel, tx *Transaction) (JsonValue, error) {
+	_proposalName, err := ParseName(proposalName)
+	if err != nil {
+		return JsonValue{}, err
+	}
+	if len(requested) == 0 {
+		return JsonValue{}, newErrorf("proposal %s: no requested approvals", proposalName)
+	}
+	if len(tx.Actions) == 0 {
+		return JsonValue{}, newErrorf("proposal %s: empty transaction", proposalName)
+	}
+	packedTx := tx.Pack()
+	enc := NewEncoder(8 + 8 + 5 + len(requested)*16 + len(packedTx))
+	enc.PackName(NewName(proposer))
+	enc.PackName(_proposalName)
+	enc.PackLength(len(requested))
+	for _, level := range requested {
+		enc.PackName(level.Actor)
+		enc.PackName(level.Permission)
+	}
+	enc.WriteBytes(packedTx)
+	action := api.msigAction("propose", PermissionLevel{NewName(proposer), NewName("active")}, enc.GetBytes())
+	return api.PushAction(action)
+}
+
+func (api *ChainApi) pushApproval(name string, proposer string, proposalName string, level PermissionLevel) (JsonValue, error) {
+	action := NewAction(
+		api.Profile.MsigAccount,
+		NewName(name),
+		[]PermissionLevel{level},
+		NewName(proposer),
+		NewName(proposalName),
+		level.Actor,
+		level.Permission,
+	)
+	return api.PushAction(action)
+}
+
+// Approve adds the approval of level to the proposal, the action is
+// authorized by level itself.
+func (api *ChainApi) Approve(proposer string, proposalName string, level PermissionLevel) (JsonValue, error) {
+	return api.pushApproval("approve", proposer, proposalName, level)
+}
+
+func (api *ChainApi) Unapprove(proposer string, proposalName string, level PermissionLevel) (JsonValue, error) {
+	return api.pushApproval("unapprove", proposer, proposalName, level)
+}
+
+// Cancel removes the proposal, canceler must be the proposer unless the
+// proposed transaction has expired.
+func (api *ChainApi) Cancel(proposer string, proposalName string, canceler string) (JsonValue, error) {
+	action := NewAction(
+		api.Profile.MsigAccount,
+		NewName("cancel"),
+		[]PermissionLevel{{NewName(canceler), NewName("active")}},
+		NewName(proposer),
+		NewName(proposalName),
+		NewName(canceler),
+	)
+	return api.PushAction(action)
+}
+
+func (api *ChainApi) Exec(proposer string, proposalName string, executer string) (JsonValue, error) {
+	action := NewAction(
+		api.Profile.MsigAccount,
+		NewName("exec"),
+		[]PermissionLevel{{NewName(executer), NewName("active")}},
+		NewName(proposer),
+		NewName(proposalName),
+		NewName(executer),
+	)
+	return api.PushAction(action)
+}
+
+// msigContract returns the contract name of the msig ABI, which is loaded
+// when the profile does not provide it.
+func (api *ChainApi) msigContract() (string, error) {
+	contract := api.Profile.MsigAccount.String()
+	if !api.ABISerializer.IsAbiCached(contract) {
+		if err := api.ABISerializer.SetContractABI(contract, []byte(eosioMsigAbi)); err != nil {
+			return "", err
+		}
+	}
+	return contract, nil
+}
+
+// getMsigRows reads the rows of table in scope proposer between lower and
+// upper, decodes them as abiType and calls fn with the JSON of each row.
+func (api *ChainApi) getMsigRows(proposer string, table string, abiType string, lower string, upper string, fn func(row []byte) error) error {
+	contract, err := api.msigContract()
+	if err != nil {
+		return err
+	}
+	return api.decodeTableRows(contract, contract, proposer, table, abiType, lower, upper, fn)
+}
+
+// GetProposals returns the pending proposals of proposer
+func (api *ChainApi) GetProposals(proposer string) ([]Proposal, error) {
+	return api.getProposals(proposer, "", "")
+}
+
+func (api *ChainApi) getProposals(proposer string, lower string, upper string) ([]Proposal, error) {
+	proposals := []Proposal{}
+	err := api.getMsigRows(proposer, "proposal", "proposal", lower, upper, func(row []byte) error {
+		p := Proposal{Proposer: NewName(proposer)}
+		if err := json.Unmarshal(row, &p); err != nil {
+			return newError(err)
+		}
+		p.Transaction = &Transaction{}
+		if _, err := p.Transaction.Unpack(p.PackedTransaction); err != nil {
+			return newErrorf("proposal %s: %s", p.ProposalName.String(), err.Error())
+		}
+		proposals = append(proposals, p)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return proposals, nil
+}
+
+func (api *ChainApi) GetProposal(proposer string, proposalName string) (*Proposal, error) {
+	proposals, err := api.getProposals(proposer, proposalName, proposalName)
+	if err != nil {
+		return nil, err
+	}
+	if len(proposals) == 0 {
+		return nil, newErrorf("proposal %s of %s not found", proposalName, proposer)
+	}
+	return &proposals[0], nil
+}
+
+func (api *ChainApi) GetProposalApprovals(proposer string, proposalName string) (*ProposalApprovals, error) {
+	var approvals *ProposalApprovals
+	err := api.getMsigRows(proposer, "approvals2", "approvals_info", proposalName, proposalName, func(row []byte) error {
+		approvals = &ProposalApprovals{}
+		if err := json.Unmarshal(row, approvals); err != nil {
+			return newError(err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if approvals == nil {
+		return nil, newErrorf("approvals of proposal %s of %s not found", proposalName, proposer)
+	}
+	return approvals, nil
+}
+
+// DecodeProposal returns the proposed transaction in JSON. The data of each
+// action is decoded when the ABI of its contract is cached in the
+// ABISerializer, the packed data is kept in hex_data.
+func (api *ChainApi) DecodeProposal(p *Proposal) (JsonValue, error) {
+	tx := p.Transaction
+	if tx == nil {
+		tx = &Transaction{}
+		if _, err := tx.Unpack(p.PackedTransaction); err != nil {
+			return JsonValue{}, err
+		}
+	}
+	b, err := json.Marshal(tx)
+	if err != nil {
+		return JsonValue{}, newError(err)
+	}
+	result, err := ParseJsonValue(b)
+	if err != nil {
+		return JsonValue{}, err
+	}
+	for _, key := range []string{"context_free_actions", "actions"} {
+		actions, err := result.GetArray(key)
+		if err != nil {
+			continue
+		}
+		txActions := tx.ContextFreeActions
+		if key == "actions" {
+			txActions = tx.Actions
+		}
+		for i := range actions {
+			a := &txActions[i]
+			contract := a.Account.String()
+			if !api.ABISerializer.IsAbiCached(contract) {
+				continue
+			}
+			data, err := api.ABISerializer.UnpackActionArgs(contract, a.Name.String(), a.Data)
+			if err != nil {
+				return JsonValue{}, err
+			}
+			args, err := ParseJsonValue(data)
+			if err != nil {
+				return JsonValue{}, err
+			}
+			actions[i].Set("hex_data", NewJsonString(hex.EncodeToString(a.Data)))
+			actions[i].Set("data", args)
+		}
+	}
+	return result, nil
+}
@@ -3,6 +3,7 @@ package uuoskit
 import (
 	"encoding/json"
 	"strconv"
+	"strings"
 )
 
 func char_to_symbol(c byte) byte {
@@ -38,6 +39,44 @@ func string_to_name(str string) uint64 {
 	return value
 }
 
+// IsNameValid reports whether s is a valid, normalized name
+func IsNameValid(s string) bool {
+	_, err := ParseName(s)
+	return err == nil
+}
+
+// ParseName converts s to a Name. Unlike S2N it rejects names that would be
+// truncated or mangled: more than 13 characters, characters outside
+// `.12345a-z`, a 13th character beyond `j` and trailing dots.
+func ParseName(s string) (Name, error) {
+	if len(s) > 13 {
+		return Name{}, newErrorf("name %q is longer than 13 characters", s)
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c == '.' || (c >= '1' && c <= '5') || (c >= 'a' && c <= 'z') {
+			if i == 12 && c > 'j' {
+				return Name{}, newErrorf("name %q: 13th character must be one of `.12345abcdefghij`", s)
+			}
+			continue
+		}
+		return Name{}, newErrorf("name %q contains invalid character %q", s, c)
+	}
+	if strings.HasSuffix(s, ".") {
+		return Name{}, newErrorf("name %q must not end with a dot", s)
+	}
+	return Name{N: S2N(s)}, nil
+}
+
+// ParseNameKey parses a table key given either as a decimal uint64 or as a
+// name, the same way nodeos converts i64 bounds in get_table_rows.
+func ParseNameKey(s string) (Name, error) {
+	if n, err := strconv.ParseUint(s, 10, 64); err == nil {
+		return Name{N: n}, nil
+	}
+	return ParseName(strings.TrimSpace(s))
+}
+
 func S2N(s string) uint64 {
 	return string_to_name(s)
 }
@@ -85,34 +124,155 @@ func (a *Name) UnmarshalJSON(b []byte) error {
 	if err != nil {
 		return newError(err)
 	}
-	a.N = S2N(n)
+	name, err := ParseName(n)
+	if err != nil {
+		return err
+	}
+	a.N = name.N
 	return nil
 }
 
+// NewName converts s without validation, invalid input is silently
+// truncated. Use ParseName for untrusted input.
 func NewName(s string) Name {
 	return Name{N: S2N(s)}
 }
 
 func (a *Name) Pack() []byte {
 	enc := NewEncoder(8)
-	enc.WriteUint64(a.N)
+	a.PackTo(enc)
 	return enc.GetBytes()
 }
 
+func (a *Name) PackTo(enc *Encoder) {
+	enc.WriteUint64(a.N)
+}
+
 func (a *Name) Unpack(data []byte) (int, error) {
-	dec := NewDecoder(data)
+	return unpackBytes(data, a)
+}
+
+func (a *Name) UnpackFrom(dec *Decoder) error {
 	n, err := dec.UnpackUint64()
 	if err != nil {
-		return 0, err
+		return err
 	}
 	a.N = n
-	return 8, nil
+	return nil
 }
 
 func (t *Name) Size() int {
 	return 8
 }
 
-func (a *Name) String() string {
+func (a Name) String() string {
 	return N2S(a.N)
 }
+
+// Uint64 returns the raw value of the name, as used by i64 table keys
+func (a Name) Uint64() uint64 {
+	return a.N
+}
+
+// Int64 returns the name reinterpreted as a signed 64 bit integer
+func (a Name) Int64() int64 {
+	return int64(a.N)
+}
+
+// I64Key returns the decimal form of the name for use as an i64 table
+// key or scope in get_table_rows
+func (a Name) I64Key() string {
+	return strconv.FormatUint(a.N, 10)
+}
+
+// Compare returns -1, 0 or 1, names are ordered by their numeric value
+// which is also the order of rows keyed by name on chain.
+func (a Name) Compare(b Name) int {
+	if a.N < b.N {
+		return -1
+	} else if a.N > b.N {
+		return 1
+	}
+	return 0
+}
+
+func (a Name) Less(b Name) bool {
+	return a.N < b.N
+}
+
+// hasDot reports whether one of the first 12 characters is a dot,
+// including the implicit dots that pad names shorter than 12 characters.
+func (a Name) hasDot() bool {
+	tmp := a.N >> 4
+	for i := 0; i < 12; i++ {
+		if tmp&0x1f == 0 {
+			return true
+		}
+		tmp >>= 5
+	}
+	return false
+}
+
+// Suffix returns the part of the name after the last dot, e.g. `abc` for
+// `hello.abc`. Names without a dot other than leading ones are returned
+// unchanged.
+func (a Name) Suffix() Name {
+	s := strings.TrimLeft(a.String(), ".")
+	i := strings.LastIndexByte(s, '.')
+	if i < 0 {
+		return a
+	}
+	return Name{N: S2N(s[i+1:])}
+}
+
+// Prefix returns the part of the name before the last dot, e.g. `hello`
+// for `hello.abc`.
+func (a Name) Prefix() Name {
+	s := a.String()
+	trimmed := strings.TrimLeft(s, ".")
+	i := strings.LastIndexByte(trimmed, '.')
+	if i < 0 {
+		return a
+	}
+	return Name{N: S2N(s[:len(s)-len(trimmed)+i])}
+}
+
+// IsPremium reports whether the name is shorter than 12 characters and has
+// no dot, such names can only be created by the winner of a name bid.
+func (a Name) IsPremium() bool {
+	return a.N != 0 && a.hasDot() && a.Suffix() == a
+}
+
+// ParentAccount returns the account which owns the suffix of a dotted
+// name, e.g. `abc` for `hello.abc`. ok is false for names without a dot.
+func (a Name) ParentAccount() (parent Name, ok bool) {
+	if !a.hasDot() {
+		return Name{}, false
+	}
+	suffix := a.Suffix()
+	if suffix == a {
+		return Name{}, false
+	}
+	return suffix, true
+}
+
+// CheckNewAccountName applies the naming rules of eosio.system newaccount:
+// dotted names can only be created by their parent account and premium
+// names only by the winner of the name bid, which is not checked here.
+// The system account of the chain, e.g. the SystemAccount of its profile,
+// may create any valid name.
+func CheckNewAccountName(systemAccount Name, creator Name, newAccount Name) error {
+	if !IsNameValid(newAccount.String()) || newAccount.N == 0 {
+		return newErrorf("invalid account name %q", newAccount.String())
+	}
+	if creator == systemAccount || !newAccount.hasDot() {
+		return nil
+	}
+	if parent, ok := newAccount.ParentAccount(); ok {
+		if parent != creator {
+			return newErrorf("only %s may create account %s", parent.String(), newAccount.String())
+		}
+		return nil
+	}
+	return newErrorf("%s is a premium name, it can only be created by the winner of its name bid", newAccount.String())
+}
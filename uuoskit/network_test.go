@@ -0,0 +1,158 @@
+package uuoskit_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	secp256k1 "github.com/armoniax/go-secp256k1"
+	"github.com/armoniax/go-uuoskit/uuoskit"
+	"github.com/armoniax/go-uuoskit/uuoskit/testchain"
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestChain starts a test chain where helloworld11 holds 100.0000 EOS
+// and is controlled by the key imported in the wallet
+func newTestChain(t *testing.T) *testchain.Chain {
+	assert.Nil(t, uuoskit.GetWallet().Import("test", "5JRYimgLBrRLCBAcjHUWCYRv3asNedTYYzVgmiU4q2ZVxMBiJXL"))
+	chain := testchain.New()
+	assert.Nil(t, chain.CreateAccount("helloworld11", "EOS6AjF6hvF7GSuSd4sCgfPKq5uWaXvGM2aQtEUCwmEHygQaqxBSV"))
+	assert.Nil(t, chain.CreateToken("eosio", "1000000.0000 EOS"))
+	assert.Nil(t, chain.Issue("helloworld11", "100.0000 EOS"))
+	return chain
+}
+
+func TestChainApi(t *testing.T) {
+	chain := newTestChain(t)
+	defer chain.Close()
+	api := uuoskit.NewChainApi(chain.URL())
+	a, err := api.GetAccount("eosio")
+	assert.Nil(t, err)
+	v, _ := a.GetString("created")
+	assert.Equal(t, "2022-01-01T00:00:00.000", v)
+	v, _ = a.GetString("last_code_update")
+	assert.Equal(t, "1970-01-01T00:00:00.000", v)
+}
+
+func TestPushAction(t *testing.T) {
+	chain := newTestChain(t)
+	defer chain.Close()
+	api := uuoskit.NewChainApi(chain.URL())
+	action := uuoskit.NewAction(uuoskit.NewName("eosio.token"),
+		uuoskit.NewName("transfer"),
+		[]uuoskit.PermissionLevel{{uuoskit.NewName("helloworld11"), uuoskit.NewName("active")}},
+		uuoskit.NewName("helloworld11"),
+		uuoskit.NewName("eosio.token"),
+		uuoskit.NewAsset(1000, uuoskit.NewSymbol("EOS", 4)),
+		"transfer from alice")
+	r, err := api.PushAction(action)
+	assert.Nil(t, err)
+	quantity, _ := r.GetString("processed", "action_traces", 0, "act", "data", "quantity")
+	assert.Equal(t, "0.1000 EOS", quantity)
+
+	strAction := `
+	{
+		"from": "helloworld11",
+		"to": "eosio.token",
+		"quantity": "0.1000 EOS",
+		"memo": "transfer from alice"
+	}
+	`
+	_, err = api.PushActionWithArgs("eosio.token", "transfer", strAction, "helloworld11", "active")
+	assert.Nil(t, err)
+
+	balance, err := chain.Balance("eosio.token", "EOS")
+	assert.Nil(t, err)
+	assert.Equal(t, "0.2000 EOS", balance.String())
+}
+
+func TestGetRequiredKeys(t *testing.T) {
+	chain := newTestChain(t)
+	defer chain.Close()
+	rpc := uuoskit.NewRpc(chain.URL())
+
+	chainInfo, err := rpc.GetInfo()
+	assert.Nil(t, err)
+	expiration := int(time.Now().Unix()) + 60
+	tx := uuoskit.NewTransaction(expiration)
+	tx.SetReferenceBlock(chainInfo.LastIrreversibleBlockID)
+
+	action := uuoskit.NewAction(uuoskit.NewName("eosio.token"),
+		uuoskit.NewName("transfer"),
+		[]uuoskit.PermissionLevel{{uuoskit.NewName("helloworld11"), uuoskit.NewName("active")}},
+		uuoskit.NewName("helloworld11"),
+		uuoskit.NewName("eosio.token"),
+		uuoskit.NewAsset(1000, uuoskit.NewSymbol("EOS", 4)),
+		"transfer from alice")
+	action.AddPermission(uuoskit.NewName("helloworld11"), uuoskit.NewName("active"))
+	tx.AddAction(action)
+
+	args := uuoskit.GetRequiredKeysArgs{tx, uuoskit.GetWallet().GetPublicKeys()}
+	ret, err := rpc.GetRequiredKeys(&args)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"AM6AjF6hvF7GSuSd4sCgfPKq5uWaXvGM2aQtEUCwmEHygQaqxBSV"}, ret.RequiredKeys)
+}
+
+func TestTx(t *testing.T) {
+	chain := newTestChain(t)
+	defer chain.Close()
+	rpc := uuoskit.NewRpc(chain.URL())
+
+	chainInfo, err := rpc.GetInfo()
+	assert.Nil(t, err)
+
+	expiration := int(time.Now().Unix()) + 60
+	tx := uuoskit.NewTransaction(expiration)
+	tx.SetReferenceBlock(chainInfo.LastIrreversibleBlockID)
+
+	pub := "EOS6AjF6hvF7GSuSd4sCgfPKq5uWaXvGM2aQtEUCwmEHygQaqxBSV"
+	priv := "5JRYimgLBrRLCBAcjHUWCYRv3asNedTYYzVgmiU4q2ZVxMBiJXL"
+	privKey, err := secp256k1.NewPrivateKeyFromBase58(priv)
+	assert.Nil(t, err)
+	pubKey, err := secp256k1.GetPublicKey(privKey)
+	assert.Nil(t, err)
+	assert.Equal(t, "EOS6AjF6hvF7GSuSd4sCgfPKq5uWaXvGM2aQtEUCwmEHygQaqxBSV", uuoskit.EOSProfile().PublicKeyString(pubKey))
+	action := uuoskit.NewAction(uuoskit.NewName("eosio.token"),
+		uuoskit.NewName("transfer"),
+		[]uuoskit.PermissionLevel{{uuoskit.NewName("helloworld11"), uuoskit.NewName("active")}},
+		uuoskit.NewName("helloworld11"),
+		uuoskit.NewName("eosio.token"),
+		uuoskit.NewAsset(1000, uuoskit.NewSymbol("EOS", 4)),
+		"transfer from alice")
+	action.AddPermission(uuoskit.NewName("helloworld11"), uuoskit.NewName("active"))
+	tx.AddAction(action)
+
+	chainId := chainInfo.ChainID
+	sign, err := tx.Sign(priv, chainId)
+	assert.Nil(t, err)
+
+	packedTx := uuoskit.NewPackedTransaction(tx)
+	packedTx.SetChainId(chainId)
+	sign2, err := packedTx.SignByPrivateKey(priv)
+	assert.Nil(t, err)
+	assert.Equal(t, sign, sign2)
+
+	// signing again with the same key adds no signature
+	_, err = packedTx.Sign(pub)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(packedTx.Signatures))
+
+	r, err := rpc.PushTransaction(packedTx)
+	assert.Nil(t, err)
+	v, err := r.GetInt64("processed", "action_traces", 0, "action_ordinal")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), v)
+	assert.Equal(t, 1, len(chain.Transactions()))
+}
+
+func TestRpc(t *testing.T) {
+	chain := testchain.New()
+	defer chain.Close()
+	rpc := uuoskit.NewRpc(chain.URL())
+	info, err := rpc.GetInfo()
+	assert.Nil(t, err)
+	assert.Equal(t, chain.ChainId(), info.ChainID)
+	r, err := json.MarshalIndent(info, "", " ")
+	assert.Nil(t, err)
+	t.Log(string(r))
+}
@@ -0,0 +1,195 @@
+package uuoskit
+
+import (
+	"encoding/json"
+)
+
+// Permission is an entry of the permissions returned by get_account
+type Permission struct {
+	PermName     Name      `json:"perm_name"`
+	Parent       Name      `json:"parent"`
+	RequiredAuth Authority `json:"required_auth"`
+}
+
+func (api *ChainApi) GetPermissions(account string) ([]Permission, error) {
+	info, err := api.GetAccount(account)
+	if err != nil {
+		return nil, err
+	}
+	v, err := info.Get("permissions")
+	if err != nil {
+		return nil, newErrorf("get_account %s: %s", account, info.String())
+	}
+	var perms []Permission
+	if err := json.Unmarshal([]byte(v.String()), &perms); err != nil {
+		return nil, newError(err)
+	}
+	return perms, nil
+}
+
+func findPermission(perms []Permission, permission Name) *Permission {
+	for i := range perms {
+		if perms[i].PermName == permission {
+			return &perms[i]
+		}
+	}
+	return nil
+}
+
+func (api *ChainApi) GetPermission(account string, permission string) (*Permission, error) {
+	perms, err := api.GetPermissions(account)
+	if err != nil {
+		return nil, err
+	}
+	perm := findPermission(perms, NewName(permission))
+	if perm == nil {
+		return nil, newErrorf("permission %s@%s not found", account, permission)
+	}
+	return perm, nil
+}
+
+// updateAuthPermission returns the permission used to authorize updateauth
+// of permission: active if it is active or one of its ancestors, owner
+// otherwise. A new permission is looked up through parent.
+func updateAuthPermission(perms []Permission, permission Name, parent Name) Name {
+	owner := NewName("owner")
+	active := NewName("active")
+	if findPermission(perms, permission) == nil {
+		permission = parent
+	}
+	for i := 0; i <= len(perms); i++ {
+		if permission == active || permission == owner {
+			return permission
+		}
+		perm := findPermission(perms, permission)
+		if perm == nil {
+			break
+		}
+		permission = perm.Parent
+	}
+	return owner
+}
+
+func (api *ChainApi) pushUpdateAuth(account Name, perms []Permission, permission Name, parent Name, auth *Authority) (JsonValue, error) {
+	if err := auth.Validate(); err != nil {
+		return JsonValue{}, err
+	}
+	if permission == NewName("owner") {
+		parent = Name{}
+	}
+	action := NewAction(
+		api.Profile.SystemAccount,
+		NewName("updateauth"),
+		[]PermissionLevel{{account, updateAuthPermission(perms, permission, parent)}},
+		account,
+		permission,
+		parent,
+		auth,
+	)
+	return api.PushAction(action)
+}
+
+// SetPermission creates or replaces permission of account
+func (api *ChainApi) SetPermission(account string, permission string, parent string, auth Authority) (JsonValue, error) {
+	perms, err := api.GetPermissions(account)
+	if err != nil {
+		return JsonValue{}, err
+	}
+	auth.Sort()
+	return api.pushUpdateAuth(NewName(account), perms, NewName(permission), NewName(parent), &auth)
+}
+
+// CreatePermission creates a custom permission under parent, active if
+// empty. It fails if the permission already exists.
+func (api *ChainApi) CreatePermission(account string, permission string, parent string, threshold uint32, keys []KeyWeight, accounts []PermissionLevelWeight) (JsonValue, error) {
+	_permission, err := ParseName(permission)
+	if err != nil {
+		return JsonValue{}, err
+	}
+	if parent == "" {
+		parent = "active"
+	}
+	perms, err := api.GetPermissions(account)
+	if err != nil {
+		return JsonValue{}, err
+	}
+	if findPermission(perms, _permission) != nil {
+		return JsonValue{}, newErrorf("permission %s@%s already exists", account, permission)
+	}
+	if findPermission(perms, NewName(parent)) == nil {
+		return JsonValue{}, newErrorf("parent permission %s@%s not found", account, parent)
+	}
+	auth := Authority{Threshold: threshold, Keys: keys, Accounts: accounts, Waits: []WaitWeight{}}
+	auth.Sort()
+	return api.pushUpdateAuth(NewName(account), perms, _permission, NewName(parent), &auth)
+}
+
+func (api *ChainApi) updatePermissionKey(account string, permission string, update func(auth *Authority) error) (JsonValue, error) {
+	perms, err := api.GetPermissions(account)
+	if err != nil {
+		return JsonValue{}, err
+	}
+	perm := findPermission(perms, NewName(permission))
+	if perm == nil {
+		return JsonValue{}, newErrorf("permission %s@%s not found", account, permission)
+	}
+	auth := perm.RequiredAuth
+	auth.Keys = append([]KeyWeight{}, auth.Keys...)
+	if err := update(&auth); err != nil {
+		return JsonValue{}, err
+	}
+	return api.pushUpdateAuth(NewName(account), perms, perm.PermName, perm.Parent, &auth)
+}
+
+// AddPermissionKey adds pubKey with weight to permission of account
+func (api *ChainApi) AddPermissionKey(account string, permission string, pubKey string, weight uint16) (JsonValue, error) {
+	pub, err := api.Profile.ParsePublicKey(pubKey)
+	if err != nil {
+		return JsonValue{}, err
+	}
+	return api.updatePermissionKey(account, permission, func(auth *Authority) error {
+		return auth.AddKey(pub, weight)
+	})
+}
+
+// RemovePermissionKey removes pubKey from permission of account, it fails
+// if the remaining weights can not reach the threshold.
+func (api *ChainApi) RemovePermissionKey(account string, permission string, pubKey string) (JsonValue, error) {
+	pub, err := api.Profile.ParsePublicKey(pubKey)
+	if err != nil {
+		return JsonValue{}, err
+	}
+	return api.updatePermissionKey(account, permission, func(auth *Authority) error {
+		return auth.RemoveKey(pub)
+	})
+}
+
+// LinkAuth makes requirement of account the permission needed for action
+// actionType of code, an empty actionType links every action of code.
+func (api *ChainApi) LinkAuth(account string, code string, actionType string, requirement string) (JsonValue, error) {
+	if _, err := api.GetPermission(account, requirement); err != nil {
+		return JsonValue{}, err
+	}
+	action := NewAction(
+		api.Profile.SystemAccount,
+		NewName("linkauth"),
+		[]PermissionLevel{{NewName(account), NewName("active")}},
+		NewName(account),
+		NewName(code),
+		NewName(actionType),
+		NewName(requirement),
+	)
+	return api.PushAction(action)
+}
+
+func (api *ChainApi) UnlinkAuth(account string, code string, actionType string) (JsonValue, error) {
+	action := NewAction(
+		api.Profile.SystemAccount,
+		NewName("unlinkauth"),
+		[]PermissionLevel{{NewName(account), NewName("active")}},
+		NewName(account),
+		NewName(code),
+		NewName(actionType),
+	)
+	return api.PushAction(action)
+}
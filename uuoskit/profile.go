@@ -0,0 +1,126 @@
+package uuoskit
+
+import (
+	"encoding/json"
+	"strings"
+
+	secp256k1 "github.com/armoniax/go-secp256k1"
+)
+
+// ChainProfile holds the conventions that differ between Antelope based
+// chains. A profile should not be modified once it is in use.
+type ChainProfile struct {
+	Name string `json:"name"`
+	// KeyPrefix is the prefix of legacy public keys, e.g. AM or EOS
+	KeyPrefix     string `json:"key_prefix"`
+	SystemAccount Name   `json:"system_account"`
+	TokenAccount  Name   `json:"token_account"`
+	MsigAccount   Name   `json:"msig_account"`
+	RamAccount    Name   `json:"ram_account"`
+	RamFeeAccount Name   `json:"ramfee_account"`
+	StakeAccount  Name   `json:"stake_account"`
+	CoreSymbol    Symbol `json:"core_symbol"`
+	// ABIs maps account names to the ABIs loaded by NewABISerializerWithProfile
+	ABIs map[string]string `json:"abis"`
+}
+
+// knownKeyPrefixes are accepted for legacy public keys whatever the profile
+var knownKeyPrefixes = []string{"AM", "EOS", "UOS"}
+
+func newChainProfile(name string, keyPrefix string, coreSymbol Symbol) *ChainProfile {
+	return &ChainProfile{
+		Name:          name,
+		KeyPrefix:     keyPrefix,
+		SystemAccount: NewName("eosio"),
+		TokenAccount:  NewName("eosio.token"),
+		MsigAccount:   NewName("eosio.msig"),
+		RamAccount:    NewName("eosio.ram"),
+		RamFeeAccount: NewName("eosio.ramfee"),
+		StakeAccount:  NewName("eosio.stake"),
+		CoreSymbol:    coreSymbol,
+		ABIs:          map[string]string{"eosio.token": eosioTokenAbi, "eosio.msig": eosioMsigAbi},
+	}
+}
+
+func AmaxProfile() *ChainProfile {
+	return newChainProfile("amax", "AM", NewSymbol("AMAX", 8))
+}
+
+func EOSProfile() *ChainProfile {
+	return newChainProfile("eos", "EOS", NewSymbol("EOS", 4))
+}
+
+func UUOSProfile() *ChainProfile {
+	return newChainProfile("uuos", "UOS", NewSymbol("UUOS", 4))
+}
+
+// DefaultChainProfile returns the profile used by the constructors that do
+// not take one.
+func DefaultChainProfile() *ChainProfile {
+	return AmaxProfile()
+}
+
+// ParseChainProfile parses a profile in JSON, missing fields are taken from
+// DefaultChainProfile.
+func ParseChainProfile(b []byte) (*ChainProfile, error) {
+	p := DefaultChainProfile()
+	if err := json.Unmarshal(b, p); err != nil {
+		return nil, newError(err)
+	}
+	if p.KeyPrefix == "" {
+		return nil, newErrorf("chain profile %s: empty key prefix", p.Name)
+	}
+	return p, nil
+}
+
+// Clone returns a deep copy of p
+func (p *ChainProfile) Clone() *ChainProfile {
+	c := *p
+	c.ABIs = make(map[string]string, len(p.ABIs))
+	for k, v := range p.ABIs {
+		c.ABIs[k] = v
+	}
+	return &c
+}
+
+// PublicKeyString renders pub in the legacy format of the chain
+func (p *ChainProfile) PublicKeyString(pub *secp256k1.PublicKey) string {
+	return p.KeyPrefix + pub.StringAM()[len(defaultLegacyKeyPrefix):]
+}
+
+// ParsePublicKey accepts PUB_K1_ keys and legacy keys with the prefix of
+// the profile or any of AM, EOS and UOS.
+func (p *ChainProfile) ParsePublicKey(s string) (*secp256k1.PublicKey, error) {
+	return parsePublicKey(s, p.KeyPrefix)
+}
+
+// UnpackOptions returns the unpack options matching the profile
+func (p *ChainProfile) UnpackOptions() UnpackOptions {
+	return UnpackOptions{LegacyKeyPrefix: p.KeyPrefix}
+}
+
+// ParsePublicKey accepts PUB_K1_ keys and legacy keys prefixed with any of
+// AM, EOS and UOS.
+func ParsePublicKey(s string) (*secp256k1.PublicKey, error) {
+	return parsePublicKey(s, "")
+}
+
+func parsePublicKey(s string, keyPrefix string) (*secp256k1.PublicKey, error) {
+	if !strings.HasPrefix(s, "PUB_") {
+		prefixes := knownKeyPrefixes
+		if keyPrefix != "" {
+			prefixes = append([]string{keyPrefix}, knownKeyPrefixes...)
+		}
+		for _, prefix := range prefixes {
+			if strings.HasPrefix(s, prefix) {
+				s = defaultLegacyKeyPrefix + s[len(prefix):]
+				break
+			}
+		}
+	}
+	pub, err := secp256k1.NewPublicKeyFromBase58(s)
+	if err != nil {
+		return nil, newError(err)
+	}
+	return pub, nil
+}
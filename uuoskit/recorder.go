@@ -0,0 +1,291 @@
+package uuoskit
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// RecorderMode selects whether a Recorder records or replays
+type RecorderMode int
+
+const (
+	// RecorderReplay answers from the fixture file, requests that were not
+	// recorded fail
+	RecorderReplay RecorderMode = iota
+	// RecorderRecord forwards requests to the node and records them, Save
+	// writes the fixture file
+	RecorderRecord
+)
+
+// Interaction is a request to the node and its response as saved in a
+// fixture file. Bodies are stored as JSON, or in RequestText and
+// ResponseText when they are not JSON.
+type Interaction struct {
+	Method       string          `json:"method"`
+	Endpoint     string          `json:"endpoint"`
+	Request      json.RawMessage `json:"request,omitempty"`
+	RequestText  string          `json:"request_text,omitempty"`
+	Status       int             `json:"status"`
+	Response     json.RawMessage `json:"response,omitempty"`
+	ResponseText string          `json:"response_text,omitempty"`
+}
+
+func (i *Interaction) requestBody() []byte {
+	if len(i.Request) != 0 {
+		return i.Request
+	}
+	return []byte(i.RequestText)
+}
+
+type fixture struct {
+	Interactions []*Interaction `json:"interactions"`
+}
+
+// Recorder is an http.RoundTripper that records the requests sent to a node
+// and their responses to a fixture file, and replays them without network.
+//
+// Requests are matched on their endpoint, the path of the url, and their
+// normalized body: JSON is compared without formatting nor key order, the
+// expiration and signatures of transactions and the available keys of
+// get_required_keys are ignored since they change from run to run. The
+// responses recorded for the same request are replayed in order, the last
+// one is repeated.
+type Recorder struct {
+	mu        sync.Mutex
+	path      string
+	mode      RecorderMode
+	transport http.RoundTripper
+	ignored   map[string]bool
+	fixture   fixture
+	// replayed counts the responses replayed by normalized request
+	replayed map[string]int
+}
+
+// NewRecorder returns a recorder of the fixture file at path. In replay
+// mode the file is loaded and must exist, in record mode requests are sent
+// through transport, http.DefaultTransport if nil.
+func NewRecorder(path string, mode RecorderMode, transport http.RoundTripper) (*Recorder, error) {
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	r := &Recorder{
+		path:      path,
+		mode:      mode,
+		transport: transport,
+		ignored:   map[string]bool{"expiration": true, "signatures": true, "available_keys": true},
+		replayed:  map[string]int{},
+	}
+	if mode == RecorderRecord {
+		return r, nil
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, newError(err)
+	}
+	if err := json.Unmarshal(data, &r.fixture); err != nil {
+		return nil, newErrorf("%s: %s", path, err.Error())
+	}
+	return r, nil
+}
+
+// IgnoreFields adds JSON fields ignored when requests are matched, at any
+// depth of the body
+func (r *Recorder) IgnoreFields(fields ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, field := range fields {
+		r.ignored[field] = true
+	}
+}
+
+// Interactions returns the recorded or loaded interactions
+func (r *Recorder) Interactions() []Interaction {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	interactions := make([]Interaction, 0, len(r.fixture.Interactions))
+	for _, i := range r.fixture.Interactions {
+		interactions = append(interactions, *i)
+	}
+	return interactions
+}
+
+// Save writes the recorded interactions to the fixture file
+func (r *Recorder) Save() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.mode != RecorderRecord {
+		return newErrorf("recorder is not recording")
+	}
+	data, err := json.MarshalIndent(&r.fixture, "", "  ")
+	if err != nil {
+		return newError(err)
+	}
+	if err := ioutil.WriteFile(r.path, append(data, '\n'), 0644); err != nil {
+		return newError(err)
+	}
+	return nil
+}
+
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, newError(err)
+		}
+	}
+	if r.mode == RecorderRecord {
+		return r.record(req, body)
+	}
+	return r.replay(req, body)
+}
+
+func (r *Recorder) record(req *http.Request, body []byte) (*http.Response, error) {
+	_req := req.Clone(req.Context())
+	_req.Body = ioutil.NopCloser(bytes.NewReader(body))
+	_req.ContentLength = int64(len(body))
+	resp, err := r.transport.RoundTrip(_req)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, newError(err)
+	}
+
+	i := &Interaction{Method: req.Method, Endpoint: req.URL.Path, Status: resp.StatusCode}
+	if json.Valid(body) {
+		i.Request = compactJson(body)
+	} else {
+		i.RequestText = string(body)
+	}
+	if json.Valid(respBody) {
+		i.Response = compactJson(respBody)
+	} else {
+		i.ResponseText = string(respBody)
+	}
+	r.mu.Lock()
+	r.fixture.Interactions = append(r.fixture.Interactions, i)
+	r.mu.Unlock()
+
+	resp.Body = ioutil.NopCloser(bytes.NewReader(respBody))
+	resp.ContentLength = int64(len(respBody))
+	return resp, nil
+}
+
+func (r *Recorder) replay(req *http.Request, body []byte) (*http.Response, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	normalized := r.normalize(body)
+	var matches []*Interaction
+	for _, i := range r.fixture.Interactions {
+		if i.Method == req.Method && i.Endpoint == req.URL.Path && r.normalize(i.requestBody()) == normalized {
+			matches = append(matches, i)
+		}
+	}
+	if len(matches) == 0 {
+		return nil, newErrorf("no recorded interaction for %s %s %s", req.Method, req.URL.Path, string(body))
+	}
+	key := req.Method + " " + req.URL.Path + " " + normalized
+	n := r.replayed[key]
+	if n >= len(matches) {
+		n = len(matches) - 1
+	}
+	r.replayed[key] = n + 1
+	i := matches[n]
+
+	respBody := []byte(i.Response)
+	if len(respBody) == 0 {
+		respBody = []byte(i.ResponseText)
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", i.Status, http.StatusText(i.Status)),
+		StatusCode:    i.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": []string{"application/json"}},
+		Body:          ioutil.NopCloser(bytes.NewReader(respBody)),
+		ContentLength: int64(len(respBody)),
+		Request:       req,
+	}, nil
+}
+
+func compactJson(data []byte) json.RawMessage {
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, data); err != nil {
+		return json.RawMessage(data)
+	}
+	return json.RawMessage(buf.Bytes())
+}
+
+// normalize returns the form of a request body compared by replay
+func (r *Recorder) normalize(body []byte) string {
+	body = bytes.TrimSpace(body)
+	var v interface{}
+	if err := json.Unmarshal(body, &v); err != nil {
+		return string(body)
+	}
+	normalized, err := json.Marshal(r.normalizeValue(v))
+	if err != nil {
+		return string(body)
+	}
+	return string(normalized)
+}
+
+func (r *Recorder) normalizeValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, value := range v {
+			if r.ignored[k] {
+				delete(v, k)
+				continue
+			}
+			v[k] = r.normalizeValue(value)
+		}
+		if packedTrx, ok := v["packed_trx"].(string); ok {
+			compression, _ := v["compression"].(string)
+			v["packed_trx"] = normalizePackedTrx(packedTrx, compression, r.ignored["expiration"])
+			delete(v, "compression")
+		}
+	case []interface{}:
+		for i := range v {
+			v[i] = r.normalizeValue(v[i])
+		}
+	}
+	return v
+}
+
+// normalizePackedTrx returns a packed transaction uncompressed, with its
+// expiration zeroed if ignoreExpiration is set
+func normalizePackedTrx(packedTrx string, compression string, ignoreExpiration bool) string {
+	data, err := hex.DecodeString(packedTrx)
+	if err != nil {
+		return packedTrx
+	}
+	if strings.EqualFold(compression, "zlib") {
+		zr, err := zlib.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return packedTrx
+		}
+		data, err = ioutil.ReadAll(zr)
+		if err != nil {
+			return packedTrx
+		}
+	}
+	if ignoreExpiration && len(data) >= 4 {
+		copy(data[:4], []byte{0, 0, 0, 0})
+	}
+	return hex.EncodeToString(data)
+}
@@ -0,0 +1,53 @@
+package uuoskit_test
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/armoniax/go-uuoskit/uuoskit"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecorder(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "transfer.json")
+	transfer := `{"from": "helloworld11", "to": "eosio.token", "quantity": "0.1000 EOS", "memo": "%s"}`
+	run := func(api *uuoskit.ChainApi) []string {
+		ids := []string{}
+		for _, memo := range []string{"first", "second"} {
+			r, err := api.PushActionWithArgs("eosio.token", "transfer",
+				fmt.Sprintf(transfer, memo), "helloworld11", "active")
+			assert.Nil(t, err)
+			id, _ := r.GetString("transaction_id")
+			ids = append(ids, id)
+		}
+		a, err := api.GetAccount("helloworld11")
+		assert.Nil(t, err)
+		perm, _ := a.GetString("permissions", 0, "perm_name")
+		ids = append(ids, perm)
+		return ids
+	}
+
+	chain := newTestChain(t)
+	recorder, err := uuoskit.NewRecorder(path, uuoskit.RecorderRecord, nil)
+	assert.Nil(t, err)
+	api := uuoskit.NewChainApi(chain.URL())
+	api.SetTransport(recorder)
+	recorded := run(api)
+	chain.Close()
+	assert.Nil(t, recorder.Save())
+	// get_info, get_required_keys and push_transaction for each transfer
+	assert.Equal(t, 7, len(recorder.Interactions()))
+
+	replayer, err := uuoskit.NewRecorder(path, uuoskit.RecorderReplay, nil)
+	assert.Nil(t, err)
+	api = uuoskit.NewChainApi("http://127.0.0.1:1")
+	api.SetTransport(replayer)
+	assert.Equal(t, recorded, run(api))
+	assert.Equal(t, "active", recorded[2])
+	assert.NotEqual(t, recorded[0], recorded[1])
+
+	_, err = api.PushActionWithArgs("eosio.token", "transfer",
+		fmt.Sprintf(transfer, "third"), "helloworld11", "active")
+	assert.Contains(t, err.Error(), "no recorded interaction for POST /v1/chain/push_transaction")
+}
@@ -0,0 +1,327 @@
+package uuoskit
+
+import (
+	"encoding/json"
+	"math"
+	"math/big"
+	"time"
+)
+
+// PowerUpFraction is 100% of the net or cpu resources in PowerUp
+const PowerUpFraction = 1000000000000000
+
+// systemTablesContract is the name the ABI of the system tables is cached
+// under when the ABI of the system contract is not cached.
+const systemTablesContract = "eosio.system.tables"
+
+type Connector struct {
+	Balance Asset   `json:"balance"`
+	Weight  float64 `json:"weight"`
+}
+
+// RamMarket is the rammarket row of eosio.system, Base holds the RAM and
+// Quote the core token.
+type RamMarket struct {
+	Supply Asset     `json:"supply"`
+	Base   Connector `json:"base"`
+	Quote  Connector `json:"quote"`
+}
+
+// bancorOutput and bancorInput follow exchange_state of eosio.system
+func bancorOutput(inReserve int64, outReserve int64, in int64) int64 {
+	out := int64(float64(in) * float64(outReserve) / (float64(inReserve) + float64(in)))
+	if out < 0 {
+		out = 0
+	}
+	return out
+}
+
+func bancorInput(outReserve int64, inReserve int64, out int64) int64 {
+	in := int64(float64(inReserve) * float64(out) / (float64(outReserve) - float64(out)))
+	if in < 0 {
+		in = 0
+	}
+	return in
+}
+
+// ramFee is the 0.5% fee of buyram and sellram, rounded up
+func ramFee(amount int64) int64 {
+	return (amount + 199) / 200
+}
+
+// RamPrice returns the price of 1 KiB of RAM without the fee
+func (m *RamMarket) RamPrice() *Asset {
+	return &Asset{bancorInput(m.Base.Balance.Amount, m.Quote.Balance.Amount, 1024), m.Quote.Balance.Symbol}
+}
+
+// BuyRamBytesCost returns what buyrambytes charges for bytes, fee included
+func (m *RamMarket) BuyRamBytesCost(bytes int64) *Asset {
+	cost := bancorInput(m.Base.Balance.Amount, m.Quote.Balance.Amount, bytes)
+	return &Asset{int64(float64(cost) / 0.995), m.Quote.Balance.Symbol}
+}
+
+// BuyRamBytes returns the bytes bought by buyram with quant
+func (m *RamMarket) BuyRamBytes(quant Asset) (int64, error) {
+	if quant.Symbol != m.Quote.Balance.Symbol {
+		return 0, newErrorf("symbol mismatch: %s, ram is traded in %s", quant.Symbol.CodeString(), m.Quote.Balance.Symbol.CodeString())
+	}
+	return bancorOutput(m.Quote.Balance.Amount, m.Base.Balance.Amount, quant.Amount-ramFee(quant.Amount)), nil
+}
+
+// SellRam returns the proceeds of selling bytes, fee deducted
+func (m *RamMarket) SellRam(bytes int64) *Asset {
+	out := bancorOutput(m.Base.Balance.Amount, m.Quote.Balance.Amount, bytes)
+	return &Asset{out - ramFee(out), m.Quote.Balance.Symbol}
+}
+
+// GlobalState holds the RAM fields of the global row of eosio.system
+type GlobalState struct {
+	MaxRamSize            uint64 `json:"max_ram_size"`
+	TotalRamBytesReserved uint64 `json:"total_ram_bytes_reserved"`
+	TotalRamStake         int64  `json:"total_ram_stake"`
+	TotalActivatedStake   int64  `json:"total_activated_stake"`
+}
+
+// FreeRam returns the RAM not reserved by any account yet
+func (g *GlobalState) FreeRam() uint64 {
+	if g.TotalRamBytesReserved > g.MaxRamSize {
+		return 0
+	}
+	return g.MaxRamSize - g.TotalRamBytesReserved
+}
+
+type PowerUpResource struct {
+	Version              uint8        `json:"version"`
+	Weight               int64        `json:"weight"`
+	WeightRatio          int64        `json:"weight_ratio"`
+	AssumedStakeWeight   int64        `json:"assumed_stake_weight"`
+	InitialWeightRatio   int64        `json:"initial_weight_ratio"`
+	TargetWeightRatio    int64        `json:"target_weight_ratio"`
+	InitialTimestamp     TimePointSec `json:"initial_timestamp"`
+	TargetTimestamp      TimePointSec `json:"target_timestamp"`
+	Exponent             float64      `json:"exponent"`
+	DecaySecs            uint32       `json:"decay_secs"`
+	MinPrice             Asset        `json:"min_price"`
+	MaxPrice             Asset        `json:"max_price"`
+	Utilization          int64        `json:"utilization"`
+	AdjustedUtilization  int64        `json:"adjusted_utilization"`
+	UtilizationTimestamp TimePointSec `json:"utilization_timestamp"`
+}
+
+// PowerUpState is the powup.state row of eosio.system
+type PowerUpState struct {
+	Version       uint8           `json:"version"`
+	Net           PowerUpResource `json:"net"`
+	Cpu           PowerUpResource `json:"cpu"`
+	PowerUpDays   uint32          `json:"powerup_days"`
+	MinPowerUpFee Asset           `json:"min_powerup_fee"`
+}
+
+// adjustedUtilization decays the adjusted utilization towards the
+// utilization as update_utilization of eosio.system does at now.
+func (r *PowerUpResource) adjustedUtilization(now time.Time) int64 {
+	if r.Utilization >= r.AdjustedUtilization {
+		return r.Utilization
+	}
+	elapsed := now.Unix() - int64(r.UtilizationTimestamp.UTCSeconds)
+	if elapsed <= 0 || r.DecaySecs == 0 {
+		return r.AdjustedUtilization
+	}
+	diff := float64(r.AdjustedUtilization - r.Utilization)
+	delta := diff * math.Exp(-float64(elapsed)/float64(r.DecaySecs))
+	delta = math.Max(0, math.Min(delta, diff))
+	return r.Utilization + int64(delta)
+}
+
+// Amount returns the resource weight of frac, scaled by PowerUpFraction
+func (r *PowerUpResource) Amount(frac int64) int64 {
+	amount := new(big.Int).Mul(big.NewInt(frac), big.NewInt(r.Weight))
+	return amount.Quo(amount, big.NewInt(PowerUpFraction)).Int64()
+}
+
+// Fee returns the fee in core token units of powering up frac of the
+// resource at now, following calc_powerup_fee of eosio.system. Pending
+// changes of the weight are not taken into account.
+func (r *PowerUpResource) Fee(frac int64, now time.Time) (int64, error) {
+	if frac < 0 || frac > PowerUpFraction {
+		return 0, newErrorf("invalid powerup fraction %d", frac)
+	}
+	increase := r.Amount(frac)
+	if increase <= 0 {
+		return 0, nil
+	}
+	if r.Utilization+increase > r.Weight {
+		return 0, newErrorf("market doesn't have enough resources available")
+	}
+	weight := float64(r.Weight)
+	minPrice := float64(r.MinPrice.Amount)
+	maxPrice := float64(r.MaxPrice.Amount)
+	priceFunction := func(utilization float64) float64 {
+		exponent := r.Exponent - 1.0
+		if exponent <= 0.0 {
+			return maxPrice
+		}
+		return minPrice + (maxPrice-minPrice)*math.Pow(utilization/weight, exponent)
+	}
+	priceIntegralDelta := func(start float64, end float64) float64 {
+		coefficient := (maxPrice - minPrice) / r.Exponent
+		startU := start / weight
+		endU := end / weight
+		return minPrice*endU - minPrice*startU + coefficient*math.Pow(endU, r.Exponent) - coefficient*math.Pow(startU, r.Exponent)
+	}
+
+	fee := 0.0
+	adjusted := r.adjustedUtilization(now)
+	start := r.Utilization
+	end := start + increase
+	if start < adjusted {
+		n := adjusted - start
+		if increase < n {
+			n = increase
+		}
+		fee += priceFunction(float64(adjusted)) * float64(n) / weight
+		start = adjusted
+	}
+	if start < end {
+		fee += priceIntegralDelta(float64(start), float64(end))
+	}
+	return int64(math.Ceil(fee)), nil
+}
+
+// Fee returns the fee of powering up netFrac of NET and cpuFrac of CPU at
+// now, it fails where the powerup action would.
+func (s *PowerUpState) Fee(netFrac int64, cpuFrac int64, now time.Time) (*Asset, error) {
+	netFee, err := s.Net.Fee(netFrac, now)
+	if err != nil {
+		return nil, newErrorf("net: %s", err.Error())
+	}
+	cpuFee, err := s.Cpu.Fee(cpuFrac, now)
+	if err != nil {
+		return nil, newErrorf("cpu: %s", err.Error())
+	}
+	fee := &Asset{netFee + cpuFee, s.MinPowerUpFee.Symbol}
+	if fee.Amount < s.MinPowerUpFee.Amount {
+		return fee, newErrorf("calculated fee %s is below minimum %s", fee.String(), s.MinPowerUpFee.String())
+	}
+	return fee, nil
+}
+
+type ResourceLimit struct {
+	Used      int64
+	Available int64
+	Max       int64
+}
+
+// AccountResources are the resources of an account from get_account, a
+// negative RamQuota means unlimited RAM.
+type AccountResources struct {
+	RamQuota  int64
+	RamUsage  int64
+	NetWeight int64
+	CpuWeight int64
+	Net       ResourceLimit
+	Cpu       ResourceLimit
+}
+
+// RamAvailable returns the RAM left to the account, -1 if unlimited
+func (r *AccountResources) RamAvailable() int64 {
+	if r.RamQuota < 0 {
+		return -1
+	}
+	if r.RamUsage > r.RamQuota {
+		return 0
+	}
+	return r.RamQuota - r.RamUsage
+}
+
+func parseResourceLimit(v JsonValue) ResourceLimit {
+	l := ResourceLimit{}
+	l.Used, _ = v.GetInt64("used")
+	l.Available, _ = v.GetInt64("available")
+	l.Max, _ = v.GetInt64("max")
+	return l
+}
+
+func (api *ChainApi) GetAccountResources(account string) (*AccountResources, error) {
+	info, err := api.GetAccount(account)
+	if err != nil {
+		return nil, err
+	}
+	r := &AccountResources{}
+	if r.RamQuota, err = info.GetInt64("ram_quota"); err != nil {
+		return nil, newErrorf("get_account %s: %s", account, info.String())
+	}
+	r.RamUsage, _ = info.GetInt64("ram_usage")
+	r.NetWeight, _ = info.GetInt64("net_weight")
+	r.CpuWeight, _ = info.GetInt64("cpu_weight")
+	if v, err := info.Get("net_limit"); err == nil {
+		r.Net = parseResourceLimit(v)
+	}
+	if v, err := info.Get("cpu_limit"); err == nil {
+		r.Cpu = parseResourceLimit(v)
+	}
+	return r, nil
+}
+
+// systemTables returns the name of the cached ABI used to decode the
+// system tables, the system contract ABI if it is cached.
+func (api *ChainApi) systemTables() (string, error) {
+	if contract := api.Profile.SystemAccount.String(); api.ABISerializer.IsAbiCached(contract) {
+		return contract, nil
+	}
+	if !api.ABISerializer.IsAbiCached(systemTablesContract) {
+		if err := api.ABISerializer.SetContractABI(systemTablesContract, []byte(eosioSystemTablesAbi)); err != nil {
+			return "", err
+		}
+	}
+	return systemTablesContract, nil
+}
+
+// getSystemRow decodes the single row of a singleton table of the system
+// contract into v
+func (api *ChainApi) getSystemRow(table string, abiType string, v interface{}) error {
+	abiContract, err := api.systemTables()
+	if err != nil {
+		return err
+	}
+	found := false
+	code := api.Profile.SystemAccount.String()
+	err = api.decodeTableRows(abiContract, code, code, table, abiType, "", "", func(row []byte) error {
+		found = true
+		if err := json.Unmarshal(row, v); err != nil {
+			return newError(err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if !found {
+		return newErrorf("table %s of %s is empty", table, code)
+	}
+	return nil
+}
+
+func (api *ChainApi) GetRamMarket() (*RamMarket, error) {
+	m := &RamMarket{}
+	if err := api.getSystemRow("rammarket", "exchange_state", m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (api *ChainApi) GetGlobalState() (*GlobalState, error) {
+	g := &GlobalState{}
+	if err := api.getSystemRow("global", "eosio_global_state", g); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+func (api *ChainApi) GetPowerUpState() (*PowerUpState, error) {
+	s := &PowerUpState{}
+	if err := api.getSystemRow("powup.state", "powerup_state", s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
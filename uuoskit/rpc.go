@@ -67,6 +67,11 @@ func NewRpc(url string) *Rpc {
 	return rpc
 }
 
+// SetTransport sets the transport of the http client of r, e.g. a Recorder
+func (r *Rpc) SetTransport(transport http.RoundTripper) {
+	r.client.Transport = transport
+}
+
 func (r *Rpc) GetInfo() (*ChainInfo, error) {
 	var info ChainInfo
 	result, err := r.Call("chain", "get_info", "")
@@ -123,12 +128,12 @@ func (t *Rpc) GetTableRows(args *GetTableRowsArgs) (JsonValue, error) {
 	result := JsonValue{}
 	r, err := t.Call("chain", "get_table_rows", args)
 	if err != nil {
-		return NewJsonValue(nil), err
+		return JsonValue{}, err
 	}
 
 	err = json.Unmarshal(r, &result)
 	if err != nil {
-		return NewJsonValue(nil), err
+		return JsonValue{}, err
 	}
 	return result, nil
 }
@@ -151,6 +156,30 @@ func (t *Rpc) PushTransaction(packedTx *PackedTransaction) (JsonValue, error) {
 	return result, nil
 }
 
+// ComputeTransaction executes packedTx without signatures on the node and
+// returns its trace, nothing is committed to the chain.
+func (t *Rpc) ComputeTransaction(packedTx *PackedTransaction) (JsonValue, error) {
+	return t.callTransaction("compute_transaction", packedTx)
+}
+
+// SendReadOnlyTransaction executes a transaction made of read-only actions
+func (t *Rpc) SendReadOnlyTransaction(packedTx *PackedTransaction) (JsonValue, error) {
+	return t.callTransaction("send_read_only_transaction", packedTx)
+}
+
+func (t *Rpc) callTransaction(endpoint string, packedTx *PackedTransaction) (JsonValue, error) {
+	args := fmt.Sprintf(`{"transaction":%s}`, packedTx.Pack(false))
+	r, err := t.Call("chain", endpoint, args)
+	if err != nil {
+		return JsonValue{}, err
+	}
+	result := JsonValue{}
+	if err := json.Unmarshal(r, &result); err != nil {
+		return JsonValue{}, newError(err)
+	}
+	return result, nil
+}
+
 func (r *Rpc) Call(api string, endpoint string, params interface{}) ([]byte, error) {
 	var _params []byte
 	reqUrl := fmt.Sprintf("%s/v1/%s/%s", r.url, api, endpoint)
@@ -4,7 +4,10 @@ import (
 	"encoding/binary"
 	"fmt"
 	"math"
+	"sync"
 	"unsafe"
+
+	secp256k1 "github.com/armoniax/go-secp256k1"
 )
 
 func PackVarInt32(v int32) []byte {
@@ -114,25 +117,56 @@ type Serializer interface {
 }
 
 type Decoder struct {
-	buf []byte
-	pos int
+	buf  []byte
+	pos  int
+	opts DecoderOptions
+	// base is the offset of buf in the data reported in errors
+	base int
+	path []decodePathElem
 }
 
 type Unpacker interface {
 	Unpack(data []byte) (int, error)
 }
 
+// UnpackerFrom is implemented by types that read their packed form from a
+// Decoder, Decoder.Unpack prefers it to Unpacker as it decodes with the
+// options, error offsets and field path of the decoder.
+type UnpackerFrom interface {
+	UnpackFrom(dec *Decoder) error
+}
+
 type PackedSize interface {
 	Size() int
 }
 
 func NewDecoder(buf []byte) *Decoder {
+	return NewDecoderWithOptions(buf, DefaultDecoderOptions())
+}
+
+// unpackBytes decodes v from data with the default options and returns
+// the number of bytes read
+func unpackBytes(data []byte, v UnpackerFrom) (int, error) {
+	dec := NewDecoder(data)
+	if err := v.UnpackFrom(dec); err != nil {
+		return 0, err
+	}
+	return dec.Pos(), nil
+}
+
+// NewDecoderWithOptions returns a decoder of buf with the limits of opts
+func NewDecoderWithOptions(buf []byte, opts DecoderOptions) *Decoder {
 	dec := &Decoder{}
 	dec.buf = buf
 	dec.pos = 0
+	dec.opts = opts.withDefaults()
 	return dec
 }
 
+func (dec *Decoder) Options() DecoderOptions {
+	return dec.opts
+}
+
 func (dec *Decoder) Pos() int {
 	return dec.pos
 }
@@ -147,7 +181,7 @@ func (dec *Decoder) IsEnd() bool {
 
 func (dec *Decoder) checkPos(n int) error {
 	if dec.pos+n > len(dec.buf) {
-		return newErrorf("checkPos: buffer overflow in Decoder")
+		return dec.errorAt(len(dec.buf), "unexpected EOF")
 	}
 	return nil
 }
@@ -271,27 +305,73 @@ func (dec *Decoder) UnpackBytes() ([]byte, error) {
 	return buf, nil
 }
 
+// readVarUint32 decodes a varuint32 of at most 5 bytes, unlike the package
+// level UnpackVarUint32 it fails on truncated and overlong encodings
+func (dec *Decoder) readVarUint32() (uint32, error) {
+	start := dec.pos
+	v := uint32(0)
+	for i := 0; i < 5; i++ {
+		if dec.pos >= len(dec.buf) {
+			return 0, dec.errorAt(len(dec.buf), "unexpected EOF")
+		}
+		b := dec.buf[dec.pos]
+		dec.pos++
+		v |= uint32(b&0x7f) << (7 * i)
+		if b&0x80 == 0 {
+			return v, nil
+		}
+	}
+	dec.pos = start
+	return 0, dec.errorf("varuint32 longer than 5 bytes")
+}
+
+// UnpackLength decodes the length prefix of bytes and strings, it fails on
+// lengths over MaxAllocSize
 func (dec *Decoder) UnpackLength() (int, error) {
-	v, n := UnpackVarUint32(dec.buf[dec.pos:])
-	dec.incPos(n)
+	start := dec.pos
+	v, err := dec.readVarUint32()
+	if err != nil {
+		return 0, err
+	}
+	if uint64(v) > uint64(dec.opts.MaxAllocSize) {
+		return 0, dec.errorAt(start, "length %d exceeds the limit of %d", v, dec.opts.MaxAllocSize)
+	}
 	return int(v), nil
 }
 
+// unpackCount decodes the element count of an array whose elements pack to
+// at least minSize bytes, it fails when the remaining bytes can not hold them
+// so that callers may allocate count elements
+func (dec *Decoder) unpackCount(minSize int) (int, error) {
+	start := dec.pos
+	count, err := dec.UnpackLength()
+	if err != nil {
+		return 0, err
+	}
+	if err := dec.checkCount(start, count, minSize); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
 func (dec *Decoder) UnpackVarInt32() (int32, error) {
-	v, n := UnpackVarInt32(dec.buf[dec.pos:])
-	dec.incPos(n)
-	return v, nil
+	v, err := dec.readVarUint32()
+	if err != nil {
+		return 0, err
+	}
+	return int32((v >> 1) ^ (^(v & 1) + 1)), nil
 }
 
 func (dec *Decoder) UnpackVarUint32() (VarUint32, error) {
-	v, n := UnpackVarUint32(dec.buf[dec.pos:])
-	dec.incPos(n)
+	v, err := dec.readVarUint32()
+	if err != nil {
+		return 0, err
+	}
 	return VarUint32(v), nil
 }
 
 func (dec *Decoder) UnpackInt16() (int16, error) {
-	v, err := dec.ReadInt16()
-	return v, newError(err)
+	return dec.ReadInt16()
 }
 
 func (dec *Decoder) UnpackUint16() (uint16, error) {
@@ -363,16 +443,14 @@ func (dec *Decoder) UnpackUint8() (uint8, error) {
 
 func (dec *Decoder) UnpackAction() (*Action, error) {
 	a := &Action{}
-	n, err := a.Unpack(dec.buf[dec.pos:])
-	if err != nil {
-		return nil, newError(err)
+	if err := a.UnpackFrom(dec); err != nil {
+		return nil, err
 	}
-	dec.incPos(n)
 	return a, nil
 }
 
 // Unpack supported type:
-// Unpacker, interface,
+// UnpackerFrom, Unpacker, interface,
 // *string, *[]byte,
 // *uint8, *int16, *uint16, *int32, *uint32, *int64, *uint64, *bool
 // *float64
@@ -380,10 +458,14 @@ func (dec *Decoder) UnpackAction() (*Action, error) {
 
 func (dec *Decoder) Unpack(i interface{}) (n int, err error) {
 	switch v := i.(type) {
+	case UnpackerFrom:
+		n = dec.Pos()
+		err = v.UnpackFrom(dec)
+		return dec.Pos() - n, err
 	case Unpacker:
 		n, err := v.Unpack(dec.buf[dec.pos:])
 		if err != nil {
-			return 0, err
+			return 0, dec.rebase(err)
 		}
 		dec.incPos(n)
 		return n, nil
@@ -498,12 +580,49 @@ type Packer interface {
 	Pack() []byte
 }
 
+// PackerTo is implemented by types that append their packed form to an
+// Encoder, Encoder.Pack prefers it to Packer as it does not allocate.
+type PackerTo interface {
+	PackTo(enc *Encoder)
+}
+
 func NewEncoder(initSize int) *Encoder {
 	ret := &Encoder{}
 	ret.buf = make([]byte, 0, initSize)
 	return ret
 }
 
+// encoderPool holds encoders whose buffers are reused by Pack methods
+var encoderPool = sync.Pool{
+	New: func() interface{} {
+		return NewEncoder(512)
+	},
+}
+
+// maxPooledEncoderSize keeps encoders grown by unusually large values out
+// of the pool
+const maxPooledEncoderSize = 64 * 1024
+
+func getEncoder() *Encoder {
+	return encoderPool.Get().(*Encoder)
+}
+
+func putEncoder(enc *Encoder) {
+	if cap(enc.buf) > maxPooledEncoderSize {
+		return
+	}
+	enc.Reset()
+	encoderPool.Put(enc)
+}
+
+// packBytes packs v with a pooled encoder and returns a copy of the result
+func packBytes(v PackerTo) []byte {
+	enc := getEncoder()
+	defer putEncoder(enc)
+	v.PackTo(enc)
+	return append(make([]byte, 0, len(enc.buf)), enc.buf...)
+}
+
 func (enc *Encoder) Reset() {
 	enc.buf = enc.buf[:0]
 }
@@ -527,6 +646,8 @@ func (enc *Encoder) WriteByte(b byte) {
 // Name
 func (enc *Encoder) Pack(i interface{}) error {
 	switch v := i.(type) {
+	case PackerTo:
+		v.PackTo(enc)
 	case Packer:
 		enc.Write(v.Pack())
 	case string:
@@ -589,12 +710,25 @@ func (enc *Encoder) PackName(name Name) {
 	enc.WriteUint64(name.N)
 }
 
+// PublicKeySize is the size of a packed public_key: the key type, 0 for K1,
+// followed by the compressed key.
+const PublicKeySize = 1 + 33
+
+func (enc *Encoder) PackPublicKey(pub *secp256k1.PublicKey) {
+	enc.WriteByte(0)
+	enc.WriteBytes(pub.Data[:])
+}
+
 func (enc *Encoder) PackLength(n int) {
-	enc.Write(PackVarUint32(uint32(n)))
+	enc.PackVarUint32(uint32(n))
 }
 
 func (enc *Encoder) PackVarUint32(n uint32) {
-	enc.Write(PackVarUint32(uint32(n)))
+	for n >= 0x80 {
+		enc.buf = append(enc.buf, byte(n)|0x80)
+		n >>= 7
+	}
+	enc.buf = append(enc.buf, byte(n))
 }
 
 func (enc *Encoder) PackBool(b bool) {
@@ -642,17 +776,17 @@ func (enc *Encoder) PackUint64(d uint64) {
 }
 
 func (enc *Encoder) PackVarInt32(n int32) {
-	enc.Write(PackVarInt32(int32(n)))
+	enc.PackVarUint32(uint32((n << 1) ^ (n >> 31)))
 }
 
 func (enc *Encoder) PackString(s string) {
-	enc.Write(PackVarUint32(uint32(len(s))))
-	enc.Write([]byte(s))
+	enc.PackVarUint32(uint32(len(s)))
+	enc.buf = append(enc.buf, s...)
 }
 
 func (enc *Encoder) PackBytes(v []byte) {
-	enc.Write(PackVarUint32(uint32(len(v))))
-	enc.Write([]byte(v))
+	enc.PackVarUint32(uint32(len(v)))
+	enc.Write(v)
 }
 
 func (enc *Encoder) WriteBytes(v []byte) {
@@ -689,9 +823,7 @@ func (enc *Encoder) WriteUint16(d uint16) {
 }
 
 func (enc *Encoder) WriteInt64(d int64) {
-	b := make([]byte, 8)
-	binary.LittleEndian.PutUint64(b, uint64(d))
-	enc.Write(b)
+	enc.WriteUint64(uint64(d))
 }
 
 func (enc *Encoder) WriteUint64(d uint64) {
@@ -0,0 +1,106 @@
+package uuoskit
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const benchChainId = "aca376f206b8fc25a6ed44dbdc66547c36c6c33e3a119ffbeaef943642f0e906"
+
+func newBenchTransaction() *Transaction {
+	tx := NewTransaction(1641000000)
+	tx.RefBlockNum = 0x1234
+	tx.RefBlockPrefix = 0x56789abc
+	tx.MaxNetUsageWords = 300
+	tx.DelaySec = 1
+	tx.AddAction(NewAction(NewName("eosio.token"), NewName("transfer"),
+		[]PermissionLevel{{NewName("alice"), NewName("active")}},
+		NewName("alice"), NewName("bob"), NewAsset(10000, NewSymbol("EOS", 4)), "memo"))
+	tx.Extention = append(tx.Extention, TransactionExtension{1, []byte{1, 2, 3}})
+	return tx
+}
+
+func TestPackTo(t *testing.T) {
+	tx := newBenchTransaction()
+	packed := "40accf613412bc9a7856ac020001000100a6823403ea3055000000572d3ccdcd010000000000855c3400000000" +
+		"a8ed3232250000000000855c340000000000000e3d102700000000000004454f5300000000046d656d6f01010003010203"
+	assert.Equal(t, packed, hex.EncodeToString(tx.Pack()))
+	digest, err := tx.Digest(benchChainId)
+	assert.Nil(t, err)
+	assert.Equal(t, "43f97ddebbba02bfe1c8f683a279a2c50c83351eecb089cd70172d94abca226c", digest)
+
+	asset := NewAsset(10000, NewSymbol("EOS", 4))
+	vi := VarInt32(-300)
+	vu := VarUint32(300)
+	values := []interface {
+		Packer
+		PackerTo
+	}{
+		tx, &tx.Actions[0], &tx.Actions[0].Authorization[0], &tx.Extention[0],
+		asset, &asset.Symbol, &ExtendedAsset{*asset, NewName("eosio.token")},
+		&Transfer{NewName("alice"), NewName("bob"), *asset, "memo"},
+		&TimePoint{1}, &TimePointSec{2}, &BlockTimestampType{3},
+		&vi, &vu, &Int128{1}, &Uint128{2}, &Uint256{3}, &Float128{4},
+	}
+	enc := NewEncoder(0)
+	for _, v := range values {
+		enc.Reset()
+		v.PackTo(enc)
+		assert.Equal(t, v.Pack(), enc.Bytes(), "%T", v)
+	}
+	assert.Equal(t, PackVarInt32(int32(vi)), vi.Pack())
+
+	// packing into a reused encoder does not allocate
+	allocs := testing.AllocsPerRun(100, func() {
+		enc.Reset()
+		tx.PackTo(enc)
+	})
+	assert.Equal(t, 0.0, allocs)
+}
+
+func BenchmarkTransactionPack(b *testing.B) {
+	tx := newBenchTransaction()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		tx.Pack()
+	}
+}
+
+func BenchmarkTransactionPackTo(b *testing.B) {
+	tx := newBenchTransaction()
+	enc := NewEncoder(256)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		enc.Reset()
+		tx.PackTo(enc)
+	}
+}
+
+func BenchmarkTransactionDigest(b *testing.B) {
+	tx := newBenchTransaction()
+	chainId, _ := hex.DecodeString(benchChainId)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		tx.digest(chainId)
+	}
+}
+
+func BenchmarkActionPack(b *testing.B) {
+	tx := newBenchTransaction()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		tx.Actions[0].Pack()
+	}
+}
+
+func BenchmarkNewAction(b *testing.B) {
+	perms := []PermissionLevel{{NewName("alice"), NewName("active")}}
+	asset := NewAsset(10000, NewSymbol("EOS", 4))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		NewAction(NewName("eosio.token"), NewName("transfer"), perms,
+			NewName("alice"), NewName("bob"), asset, "memo")
+	}
+}
@@ -0,0 +1,185 @@
+package uuoskit
+
+import (
+	"encoding/hex"
+	"errors"
+	"strings"
+)
+
+// SimulateResult is the outcome of a transaction executed by Simulate
+type SimulateResult struct {
+	TransactionId string
+	// CpuUsageUs and NetUsageWords are what the receipt of the pushed
+	// transaction would bill
+	CpuUsageUs    uint64
+	NetUsageWords uint64
+	Elapsed       int64
+	// RamDeltas sums the account_ram_deltas of all action traces by account
+	RamDeltas map[string]int64
+	// Error is the message of the failed assertion or exception, it is empty
+	// when the transaction succeeded.
+	Error string
+	// Trace is the processed trace returned by the node
+	Trace JsonValue
+}
+
+// ErrComputeTransactionUnsupported is returned by Simulate for nodes
+// without the compute_transaction endpoint
+var ErrComputeTransactionUnsupported = errors.New("compute_transaction unsupported by the node")
+
+// Simulate executes actions on the node through compute_transaction without
+// signing nor committing them. A failed transaction is reported in
+// SimulateResult.Error, the error return is for failures to reach the node
+// and ErrComputeTransactionUnsupported for nodes without the endpoint.
+func (api *ChainApi) Simulate(actions []*Action) (*SimulateResult, error) {
+	packedTx, err := api.newUnsignedTransaction(actions)
+	if err != nil {
+		return nil, err
+	}
+	r, err := api.rpc.ComputeTransaction(packedTx)
+	if err != nil {
+		return nil, err
+	}
+	if code, err := r.GetInt64("code"); err == nil && code == 404 {
+		return nil, ErrComputeTransactionUnsupported
+	}
+	return newSimulateResult(r)
+}
+
+// SimulateReadOnly is Simulate through send_read_only_transaction, the node
+// rejects actions that modify state, it is meant for read-only actions and
+// for nodes without compute_transaction.
+func (api *ChainApi) SimulateReadOnly(actions []*Action) (*SimulateResult, error) {
+	packedTx, err := api.newUnsignedTransaction(actions)
+	if err != nil {
+		return nil, err
+	}
+	r, err := api.rpc.SendReadOnlyTransaction(packedTx)
+	if err != nil {
+		return nil, err
+	}
+	return newSimulateResult(r)
+}
+
+func (api *ChainApi) newUnsignedTransaction(actions []*Action) (*PackedTransaction, error) {
+	tx, _, err := api.newTransaction(actions)
+	if err != nil {
+		return nil, err
+	}
+	packedTx := NewPackedTransaction(tx)
+	packedTx.Signatures = []string{}
+	return packedTx, nil
+}
+
+func newSimulateResult(r JsonValue) (*SimulateResult, error) {
+	result := &SimulateResult{RamDeltas: map[string]int64{}}
+	if e, err := r.Get("error"); err == nil {
+		result.Error = errorMessage(e)
+		if result.Error == "" {
+			return nil, newErrorf("%s", r.String())
+		}
+		return result, nil
+	}
+	trace, err := r.Get("processed")
+	if err != nil {
+		return nil, newErrorf("no trace: %s", r.String())
+	}
+	result.Trace = trace
+	result.TransactionId, _ = r.GetString("transaction_id")
+	result.CpuUsageUs, _ = trace.GetUint64("receipt", "cpu_usage_us")
+	result.NetUsageWords, _ = trace.GetUint64("receipt", "net_usage_words")
+	result.Elapsed, _ = trace.GetInt64("elapsed")
+	if except, err := trace.Get("except"); err == nil && !except.IsNull() {
+		result.Error = exceptMessage(except)
+	}
+
+	actionTraces, _ := trace.GetArray("action_traces")
+	for _, a := range actionTraces {
+		deltas, _ := a.GetArray("account_ram_deltas")
+		for _, d := range deltas {
+			account, err := d.GetString("account")
+			if err != nil {
+				continue
+			}
+			delta, _ := d.GetInt64("delta")
+			result.RamDeltas[account] += delta
+		}
+	}
+	return result, nil
+}
+
+// errorMessage returns the message of the error object of a failed api call
+func errorMessage(e JsonValue) string {
+	if msg, err := e.GetString("details", 0, "message"); err == nil {
+		return msg
+	}
+	if msg, err := e.GetString("what"); err == nil {
+		return msg
+	}
+	return ""
+}
+
+// exceptMessage formats the first entry of the stack of an exception in a
+// trace, e.g. "assertion failure with message: overdrawn balance".
+func exceptMessage(except JsonValue) string {
+	format, err := except.GetString("stack", 0, "format")
+	if err != nil {
+		msg, _ := except.GetString("message")
+		return msg
+	}
+	data, _ := except.GetObject("stack", 0, "data")
+	for k, v := range data {
+		s, ok := v.GetStringValue()
+		if !ok {
+			s = v.String()
+		}
+		format = strings.Replace(format, "${"+k+"}", s, -1)
+	}
+	return format
+}
+
+// ReadOnly sends the read-only action of contract with args in JSON
+// through send_read_only_transaction, unsigned, and returns the return
+// value of the action decoded with the action_results of the cached ABI.
+func (api *ChainApi) ReadOnly(contract string, action string, args string) (JsonValue, error) {
+	data, err := api.ABISerializer.PackActionArgs(contract, action, args)
+	if err != nil {
+		return JsonValue{}, err
+	}
+	a := &Action{
+		Account:       NewName(contract),
+		Name:          NewName(action),
+		Authorization: []PermissionLevel{},
+		Data:          data,
+	}
+	result, err := api.SimulateReadOnly([]*Action{a})
+	if err != nil {
+		return JsonValue{}, err
+	}
+	if result.Error != "" {
+		return JsonValue{}, newErrorf("%s", result.Error)
+	}
+
+	actionTraces, _ := result.Trace.GetArray("action_traces")
+	for _, trace := range actionTraces {
+		account, _ := trace.GetString("act", "account")
+		name, _ := trace.GetString("act", "name")
+		if account != contract || name != action {
+			continue
+		}
+		hexData, err := trace.GetString("return_value_hex_data")
+		if err != nil {
+			return JsonValue{}, newErrorf("%s::%s returned no value", contract, action)
+		}
+		returnValue, err := hex.DecodeString(hexData)
+		if err != nil {
+			return JsonValue{}, newError(err)
+		}
+		value, err := api.ABISerializer.UnpackActionResult(contract, action, returnValue)
+		if err != nil {
+			return JsonValue{}, err
+		}
+		return ParseJsonValue(value)
+	}
+	return JsonValue{}, newErrorf("no trace of %s::%s", contract, action)
+}
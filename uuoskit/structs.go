@@ -4,6 +4,7 @@ import (
 	"encoding/binary"
 	"encoding/hex"
 	"encoding/json"
+	"math"
 	"strconv"
 	"strings"
 	"time"
@@ -30,10 +31,21 @@ func (t *VarInt32) Pack() []byte {
 	return PackVarInt32(int32(*t))
 }
 
+func (t *VarInt32) PackTo(enc *Encoder) {
+	enc.PackVarInt32(int32(*t))
+}
+
 func (t *VarInt32) Unpack(data []byte) (int, error) {
-	v, n := UnpackVarInt32(data)
+	return unpackBytes(data, t)
+}
+
+func (t *VarInt32) UnpackFrom(dec *Decoder) error {
+	v, err := dec.UnpackVarInt32()
+	if err != nil {
+		return err
+	}
 	*t = VarInt32(v)
-	return n, nil
+	return nil
 }
 
 func (t *VarInt32) Size() int {
@@ -46,10 +58,21 @@ func (t *VarUint32) Pack() []byte {
 	return PackVarUint32(uint32(*t))
 }
 
+func (t *VarUint32) PackTo(enc *Encoder) {
+	enc.PackVarUint32(uint32(*t))
+}
+
 func (t *VarUint32) Unpack(data []byte) (int, error) {
-	v, n := UnpackVarUint32(data)
-	*t = VarUint32(v)
-	return n, nil
+	return unpackBytes(data, t)
+}
+
+func (t *VarUint32) UnpackFrom(dec *Decoder) error {
+	v, err := dec.UnpackVarUint32()
+	if err != nil {
+		return err
+	}
+	*t = v
+	return nil
 }
 
 func (t *VarUint32) Size() int {
@@ -66,12 +89,19 @@ func (n *Int128) Pack() []byte {
 	return n[:]
 }
 
+func (n *Int128) PackTo(enc *Encoder) {
+	enc.Write(n[:])
+}
+
 func (n *Int128) Unpack(data []byte) (int, error) {
-	dec := NewDecoder(data)
+	return unpackBytes(data, n)
+}
+
+func (n *Int128) UnpackFrom(dec *Decoder) error {
 	if err := dec.Read(n[:]); err != nil {
-		return 0, err
+		return err
 	}
-	return 16, nil
+	return nil
 }
 
 func (t *Int128) Size() int {
@@ -84,12 +114,19 @@ func (n *Uint128) Pack() []byte {
 	return n[:]
 }
 
+func (n *Uint128) PackTo(enc *Encoder) {
+	enc.Write(n[:])
+}
+
 func (n *Uint128) Unpack(data []byte) (int, error) {
-	dec := NewDecoder(data)
+	return unpackBytes(data, n)
+}
+
+func (n *Uint128) UnpackFrom(dec *Decoder) error {
 	if err := dec.Read(n[:]); err != nil {
-		return 0, err
+		return err
 	}
-	return 16, nil
+	return nil
 }
 
 func (t *Uint128) Size() int {
@@ -112,12 +149,19 @@ func (n *Uint256) Pack() []byte {
 	return n[:]
 }
 
+func (n *Uint256) PackTo(enc *Encoder) {
+	enc.Write(n[:])
+}
+
 func (n *Uint256) Unpack(data []byte) (int, error) {
-	dec := NewDecoder(data)
+	return unpackBytes(data, n)
+}
+
+func (n *Uint256) UnpackFrom(dec *Decoder) error {
 	if err := dec.Read(n[:]); err != nil {
-		return 0, err
+		return err
 	}
-	return 32, nil
+	return nil
 }
 
 func (t *Uint256) Size() int {
@@ -140,256 +184,236 @@ func (n *Float128) Pack() []byte {
 	return n[:]
 }
 
+func (n *Float128) PackTo(enc *Encoder) {
+	enc.Write(n[:])
+}
+
 func (n *Float128) Unpack(data []byte) (int, error) {
-	dec := NewDecoder(data)
+	return unpackBytes(data, n)
+}
+
+func (n *Float128) UnpackFrom(dec *Decoder) error {
 	if err := dec.Read(n[:]); err != nil {
-		return 0, err
+		return err
 	}
-	return 16, nil
+	return nil
 }
 
 func (t *Float128) Size() int {
 	return 16
 }
 
+// time formats used by nodeos, all times are UTC
+const (
+	TimePointFormat    = "2006-01-02T15:04:05.000"
+	TimePointSecFormat = "2006-01-02T15:04:05"
+)
+
+// block timestamps count 500ms slots since 2000-01-01T00:00:00
+const (
+	blockTimestampEpochMs    = 946684800000
+	blockTimestampIntervalMs = 500
+)
+
+var timeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02",
+}
+
+// ParseTime parses a timestamp as returned by nodeos, with or without
+// fractional seconds. Timestamps without a zone are taken as UTC, the
+// result is always in UTC.
+func ParseTime(s string) (time.Time, error) {
+	for _, layout := range timeLayouts {
+		t, err := time.ParseInLocation(layout, s, time.UTC)
+		if err == nil {
+			return t.UTC(), nil
+		}
+	}
+	return time.Time{}, newErrorf("invalid time: %s", s)
+}
+
+func unquoteTime(b []byte) (time.Time, error) {
+	s, err := strconv.Unquote(string(b))
+	if err != nil {
+		return time.Time{}, newError(err)
+	}
+	return ParseTime(s)
+}
+
 type TimePoint struct {
 	Elapsed uint64
 }
 
+// NewTimePoint converts t to microseconds since the unix epoch
+func NewTimePoint(t time.Time) TimePoint {
+	return TimePoint{uint64(t.UnixMicro())}
+}
+
 func (t *TimePoint) Pack() []byte {
 	enc := NewEncoder(t.Size())
-	enc.PackUint64(t.Elapsed)
+	t.PackTo(enc)
 	return enc.GetBytes()
 }
 
+func (t *TimePoint) PackTo(enc *Encoder) {
+	enc.PackUint64(t.Elapsed)
+}
+
 func (t *TimePoint) Unpack(data []byte) (int, error) {
-	dec := NewDecoder(data)
-	dec.Unpack(&t.Elapsed)
-	return 8, nil
+	return unpackBytes(data, t)
+}
+
+func (t *TimePoint) UnpackFrom(dec *Decoder) error {
+	if _, err := dec.Unpack(&t.Elapsed); err != nil {
+		return err
+	}
+	return nil
 }
 
 func (t *TimePoint) Size() int {
 	return 8
 }
 
+func (t TimePoint) Time() time.Time {
+	return time.UnixMicro(int64(t.Elapsed)).UTC()
+}
+
+func (t TimePoint) String() string {
+	return t.Time().Format(TimePointFormat)
+}
+
+func (t TimePoint) MarshalJSON() ([]byte, error) {
+	return json.Marshal(t.String())
+}
+
+func (a *TimePoint) UnmarshalJSON(b []byte) error {
+	t, err := unquoteTime(b)
+	if err != nil {
+		return err
+	}
+	*a = NewTimePoint(t)
+	return nil
+}
+
 type TimePointSec struct {
 	UTCSeconds uint32
 }
 
+// NewTimePointSec converts t to seconds since the unix epoch, sub-second
+// precision is truncated.
+func NewTimePointSec(t time.Time) (TimePointSec, error) {
+	n := t.Unix()
+	if n < 0 || n > math.MaxUint32 {
+		return TimePointSec{}, newErrorf("time %s out of range for time_point_sec", t.UTC().Format(TimePointSecFormat))
+	}
+	return TimePointSec{uint32(n)}, nil
+}
+
 func (t *TimePointSec) Pack() []byte {
 	enc := NewEncoder(t.Size())
-	enc.PackUint32(t.UTCSeconds)
+	t.PackTo(enc)
 	return enc.GetBytes()
 }
 
+func (t *TimePointSec) PackTo(enc *Encoder) {
+	enc.PackUint32(t.UTCSeconds)
+}
+
 func (t *TimePointSec) Unpack(data []byte) (int, error) {
-	dec := NewDecoder(data)
-	dec.Unpack(&t.UTCSeconds)
-	return 4, nil
+	return unpackBytes(data, t)
+}
+
+func (t *TimePointSec) UnpackFrom(dec *Decoder) error {
+	if _, err := dec.Unpack(&t.UTCSeconds); err != nil {
+		return err
+	}
+	return nil
 }
 
 func (t *TimePointSec) Size() int {
 	return 4
 }
 
+func (t TimePointSec) Time() time.Time {
+	return time.Unix(int64(t.UTCSeconds), 0).UTC()
+}
+
+func (t TimePointSec) String() string {
+	return t.Time().Format(TimePointSecFormat)
+}
+
 func (t TimePointSec) MarshalJSON() ([]byte, error) {
-	s := time.Unix(int64(t.UTCSeconds), 0).UTC().Format("2006-01-02T15:04:05")
-	return json.Marshal(s)
+	return json.Marshal(t.String())
 }
 
 func (a *TimePointSec) UnmarshalJSON(b []byte) error {
-	t, err := time.Parse("2006-01-02T15:04:05", strings.Trim(string(b), "\""))
+	t, err := unquoteTime(b)
 	if err != nil {
-		return newError(err)
+		return err
 	}
-	a.UTCSeconds = uint32(t.Unix())
-	return nil
+	*a, err = NewTimePointSec(t)
+	return err
 }
 
 type BlockTimestampType struct {
 	Slot uint32
 }
 
+// NewBlockTimestamp converts t to the 500ms slot containing it
+func NewBlockTimestamp(t time.Time) (BlockTimestampType, error) {
+	ms := t.UnixMilli() - blockTimestampEpochMs
+	if ms < 0 || ms/blockTimestampIntervalMs > math.MaxUint32 {
+		return BlockTimestampType{}, newErrorf("time %s out of range for block_timestamp_type", t.UTC().Format(TimePointFormat))
+	}
+	return BlockTimestampType{uint32(ms / blockTimestampIntervalMs)}, nil
+}
+
 func (t *BlockTimestampType) Pack() []byte {
 	enc := NewEncoder(t.Size())
-	enc.PackUint32(t.Slot)
+	t.PackTo(enc)
 	return enc.GetBytes()
 }
 
-func (t *BlockTimestampType) Unpack(data []byte) (int, error) {
-	dec := NewDecoder(data)
-	dec.Unpack(&t.Slot)
-	return 4, nil
-}
-
-func (t *BlockTimestampType) Size() int {
-	return 4
-}
-
-type JsonValue struct {
-	value interface{}
-}
-
-func NewJsonValue(value interface{}) JsonValue {
-	retValue := JsonValue{}
-	retValue.SetValue(value)
-	return retValue
+func (t *BlockTimestampType) PackTo(enc *Encoder) {
+	enc.PackUint32(t.Slot)
 }
 
-func (b *JsonValue) GetValue() interface{} {
-	return b.value
+func (t *BlockTimestampType) Unpack(data []byte) (int, error) {
+	return unpackBytes(data, t)
 }
 
-func (b *JsonValue) SetValue(value interface{}) error {
-	switch value.(type) {
-	case string, []JsonValue, map[string]JsonValue:
-		b.value = value
-		return nil
-	default:
-		panic("value must be a string, slice, or map")
+func (t *BlockTimestampType) UnpackFrom(dec *Decoder) error {
+	if _, err := dec.Unpack(&t.Slot); err != nil {
+		return err
 	}
 	return nil
 }
 
-func parseSubValue(subValue JsonValue) interface{} {
-	switch v := subValue.value.(type) {
-	case string:
-		return strings.Trim(v, "\"")
-	case []JsonValue, map[string]JsonValue:
-		return subValue.value
-	}
-	return subValue
+func (t *BlockTimestampType) Size() int {
+	return 4
 }
 
-func (b *JsonValue) GetStringValue() (string, bool) {
-	switch v := b.value.(type) {
-	case string:
-		return strings.Trim(v, "\""), true
-	default:
-		return "", false
-	}
+func (t BlockTimestampType) Time() time.Time {
+	return time.UnixMilli(int64(t.Slot)*blockTimestampIntervalMs + blockTimestampEpochMs).UTC()
 }
 
-//return string, []JsonValue, or map[string]JsonValue
-func (b *JsonValue) Get(keys ...interface{}) (interface{}, error) {
-	if len(keys) == 0 {
-		return JsonValue{}, newErrorf("no key specified")
-	}
-
-	value := b.value
-	for _, key := range keys {
-		switch v := key.(type) {
-		case string:
-			switch v2 := value.(type) {
-			case map[string]JsonValue:
-				subValue, ok := v2[v]
-				if !ok {
-					return nil, newErrorf("key not found")
-				}
-				value = parseSubValue(subValue)
-			case JsonValue:
-				v3, ok := v2.value.(map[string]JsonValue)
-				if !ok {
-					return JsonValue{}, newErrorf("1:JsonValue is not a map")
-				}
-				subValue, ok := v3[v]
-				if !ok {
-					return nil, newErrorf("key not found")
-				}
-				value = parseSubValue(subValue)
-			default:
-				return JsonValue{}, newErrorf("2:JsonValue is not a map")
-			}
-		case int:
-			var arr []JsonValue
-			var ok bool
-			switch v := value.(type) {
-			case []JsonValue:
-				arr = v
-			case JsonValue:
-				arr, ok = v.value.([]JsonValue)
-				if !ok {
-					return JsonValue{}, newErrorf("JsonValue is not an array")
-				}
-			default:
-				return JsonValue{}, newErrorf("JsonValue is not an array")
-			}
-			if v < 0 || v >= len(arr) {
-				return JsonValue{}, newErrorf("index out of range")
-			}
-			value = arr[v]
-		default:
-			return JsonValue{}, newErrorf("invalid key type")
-		}
-	}
-	return value, nil
+func (t BlockTimestampType) String() string {
+	return t.Time().Format(TimePointFormat)
 }
 
-func (b *JsonValue) GetString(keys ...interface{}) (string, error) {
-	v, err := b.Get(keys...)
-	if err != nil {
-		return "", err
-	}
-
-	_v, ok := v.(string)
-	if !ok {
-		return "", newErrorf("value is not a string")
-	}
-	return strings.Trim(_v, "\""), nil
+func (t BlockTimestampType) MarshalJSON() ([]byte, error) {
+	return json.Marshal(t.String())
 }
 
-func (b *JsonValue) GetTime(keys ...interface{}) (*time.Time, error) {
-	v, err := b.GetString(keys...)
-	if err != nil {
-		return nil, err
-	}
-
-	t, err := time.Parse("2006-01-02T15:04:05", v)
+func (a *BlockTimestampType) UnmarshalJSON(b []byte) error {
+	t, err := unquoteTime(b)
 	if err != nil {
-		return nil, err
+		return err
 	}
-	return &t, nil
-}
-
-func (b JsonValue) MarshalJSON() ([]byte, error) {
-	switch v := b.value.(type) {
-	case string:
-		if _, err := strconv.ParseInt(v, 10, 64); err == nil {
-			return []byte(v), nil
-		} else {
-			return []byte(strconv.Quote(v)), nil
-		}
-	case JsonValue:
-		if s, ok := v.value.(string); ok {
-			return []byte(s), nil
-		}
-		return json.Marshal(v.value)
-	case map[string]JsonValue:
-		return json.Marshal(v)
-	case []JsonValue:
-		return json.Marshal(v)
-	}
-	return nil, newErrorf("bad JsonValue")
-}
-
-func (b *JsonValue) UnmarshalJSON(data []byte) error {
-	// fmt.Println("+++++:UnmarshaJSON", string(data))
-	if data[0] == '{' {
-		m := make(map[string]JsonValue)
-		err := json.Unmarshal(data, &m)
-		if err != nil {
-			return newError(err)
-		}
-		b.value = m
-	} else if data[0] == '[' {
-		m := make([]JsonValue, 0, 1)
-		err := json.Unmarshal(data, &m)
-		if err != nil {
-			return newError(err)
-		}
-		b.value = m
-	} else {
-		b.value = string(data)
-	}
-	return nil
+	*a, err = NewBlockTimestamp(t)
+	return err
 }
@@ -0,0 +1,17 @@
+package system
+
+import "github.com/armoniax/go-uuoskit/uuoskit"
+
+// Authority and its weights are defined in uuoskit, next to PermissionLevel,
+// and re-exported here.
+type (
+	Authority             = uuoskit.Authority
+	KeyWeight             = uuoskit.KeyWeight
+	PermissionLevelWeight = uuoskit.PermissionLevelWeight
+	WaitWeight            = uuoskit.WaitWeight
+)
+
+var (
+	NewKeyAuthority     = uuoskit.NewKeyAuthority
+	NewAccountAuthority = uuoskit.NewAccountAuthority
+)
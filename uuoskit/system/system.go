@@ -0,0 +1,403 @@
+// Package system builds the actions of the eosio system contract.
+package system
+
+import (
+	"sort"
+
+	secp256k1 "github.com/armoniax/go-secp256k1"
+	"github.com/armoniax/go-uuoskit/uuoskit"
+)
+
+var (
+	ownerPermission  = uuoskit.NewName("owner")
+	activePermission = uuoskit.NewName("active")
+)
+
+// Contract builds actions for the system contract deployed to Account.
+// Actions are authorized by the active permission of the acting account
+// unless noted otherwise.
+type Contract struct {
+	Account uuoskit.Name
+}
+
+// Default is the system contract deployed to eosio
+var Default = Contract{Account: uuoskit.NewName("eosio")}
+
+func NewContract(profile *uuoskit.ChainProfile) Contract {
+	return Contract{Account: profile.SystemAccount}
+}
+
+func (c Contract) action(name string, actor uuoskit.Name, permission uuoskit.Name, data interface{}) *uuoskit.Action {
+	return uuoskit.NewAction(
+		c.Account,
+		uuoskit.NewName(name),
+		[]uuoskit.PermissionLevel{{Actor: actor, Permission: permission}},
+		data,
+	)
+}
+
+type NewAccount struct {
+	Creator uuoskit.Name
+	Name    uuoskit.Name
+	Owner   Authority
+	Active  Authority
+}
+
+func (t *NewAccount) Pack() []byte {
+	enc := uuoskit.NewEncoder(t.Size())
+	enc.PackName(t.Creator)
+	enc.PackName(t.Name)
+	enc.Pack(&t.Owner)
+	enc.Pack(&t.Active)
+	return enc.GetBytes()
+}
+
+func (t *NewAccount) Size() int {
+	return 16 + t.Owner.Size() + t.Active.Size()
+}
+
+func (c Contract) NewAccount(creator uuoskit.Name, name uuoskit.Name, owner Authority, active Authority) *uuoskit.Action {
+	return c.action("newaccount", creator, activePermission, &NewAccount{creator, name, owner, active})
+}
+
+type BuyRAMBytes struct {
+	Payer    uuoskit.Name
+	Receiver uuoskit.Name
+	Bytes    uint32
+}
+
+func (t *BuyRAMBytes) Pack() []byte {
+	enc := uuoskit.NewEncoder(t.Size())
+	enc.PackName(t.Payer)
+	enc.PackName(t.Receiver)
+	enc.PackUint32(t.Bytes)
+	return enc.GetBytes()
+}
+
+func (t *BuyRAMBytes) Size() int {
+	return 20
+}
+
+func (c Contract) BuyRAMBytes(payer uuoskit.Name, receiver uuoskit.Name, bytes uint32) *uuoskit.Action {
+	return c.action("buyrambytes", payer, activePermission, &BuyRAMBytes{payer, receiver, bytes})
+}
+
+type BuyRAM struct {
+	Payer    uuoskit.Name
+	Receiver uuoskit.Name
+	Quant    uuoskit.Asset
+}
+
+func (t *BuyRAM) Pack() []byte {
+	enc := uuoskit.NewEncoder(t.Size())
+	enc.PackName(t.Payer)
+	enc.PackName(t.Receiver)
+	enc.Pack(&t.Quant)
+	return enc.GetBytes()
+}
+
+func (t *BuyRAM) Size() int {
+	return 32
+}
+
+func (c Contract) BuyRAM(payer uuoskit.Name, receiver uuoskit.Name, quant uuoskit.Asset) *uuoskit.Action {
+	return c.action("buyram", payer, activePermission, &BuyRAM{payer, receiver, quant})
+}
+
+type SellRAM struct {
+	Account uuoskit.Name
+	Bytes   int64
+}
+
+func (t *SellRAM) Pack() []byte {
+	enc := uuoskit.NewEncoder(t.Size())
+	enc.PackName(t.Account)
+	enc.PackInt64(t.Bytes)
+	return enc.GetBytes()
+}
+
+func (t *SellRAM) Size() int {
+	return 16
+}
+
+func (c Contract) SellRAM(account uuoskit.Name, bytes int64) *uuoskit.Action {
+	return c.action("sellram", account, activePermission, &SellRAM{account, bytes})
+}
+
+type DelegateBW struct {
+	From             uuoskit.Name
+	Receiver         uuoskit.Name
+	StakeNetQuantity uuoskit.Asset
+	StakeCPUQuantity uuoskit.Asset
+	Transfer         bool
+}
+
+func (t *DelegateBW) Pack() []byte {
+	enc := uuoskit.NewEncoder(t.Size())
+	enc.PackName(t.From)
+	enc.PackName(t.Receiver)
+	enc.Pack(&t.StakeNetQuantity)
+	enc.Pack(&t.StakeCPUQuantity)
+	enc.PackBool(t.Transfer)
+	return enc.GetBytes()
+}
+
+func (t *DelegateBW) Size() int {
+	return 49
+}
+
+func (c Contract) DelegateBW(from uuoskit.Name, receiver uuoskit.Name, net uuoskit.Asset, cpu uuoskit.Asset, transfer bool) *uuoskit.Action {
+	return c.action("delegatebw", from, activePermission, &DelegateBW{from, receiver, net, cpu, transfer})
+}
+
+type UndelegateBW struct {
+	From               uuoskit.Name
+	Receiver           uuoskit.Name
+	UnstakeNetQuantity uuoskit.Asset
+	UnstakeCPUQuantity uuoskit.Asset
+}
+
+func (t *UndelegateBW) Pack() []byte {
+	enc := uuoskit.NewEncoder(t.Size())
+	enc.PackName(t.From)
+	enc.PackName(t.Receiver)
+	enc.Pack(&t.UnstakeNetQuantity)
+	enc.Pack(&t.UnstakeCPUQuantity)
+	return enc.GetBytes()
+}
+
+func (t *UndelegateBW) Size() int {
+	return 48
+}
+
+func (c Contract) UndelegateBW(from uuoskit.Name, receiver uuoskit.Name, net uuoskit.Asset, cpu uuoskit.Asset) *uuoskit.Action {
+	return c.action("undelegatebw", from, activePermission, &UndelegateBW{from, receiver, net, cpu})
+}
+
+type UpdateAuth struct {
+	Account    uuoskit.Name
+	Permission uuoskit.Name
+	Parent     uuoskit.Name
+	Auth       Authority
+}
+
+func (t *UpdateAuth) Pack() []byte {
+	enc := uuoskit.NewEncoder(t.Size())
+	enc.PackName(t.Account)
+	enc.PackName(t.Permission)
+	enc.PackName(t.Parent)
+	enc.Pack(&t.Auth)
+	return enc.GetBytes()
+}
+
+func (t *UpdateAuth) Size() int {
+	return 24 + t.Auth.Size()
+}
+
+// UpdateAuth is authorized by the owner permission when it updates owner
+func (c Contract) UpdateAuth(account uuoskit.Name, permission uuoskit.Name, parent uuoskit.Name, auth Authority) *uuoskit.Action {
+	actorPermission := activePermission
+	if permission == ownerPermission {
+		actorPermission = ownerPermission
+	}
+	return c.action("updateauth", account, actorPermission, &UpdateAuth{account, permission, parent, auth})
+}
+
+type DeleteAuth struct {
+	Account    uuoskit.Name
+	Permission uuoskit.Name
+}
+
+func (t *DeleteAuth) Pack() []byte {
+	enc := uuoskit.NewEncoder(t.Size())
+	enc.PackName(t.Account)
+	enc.PackName(t.Permission)
+	return enc.GetBytes()
+}
+
+func (t *DeleteAuth) Size() int {
+	return 16
+}
+
+func (c Contract) DeleteAuth(account uuoskit.Name, permission uuoskit.Name) *uuoskit.Action {
+	return c.action("deleteauth", account, activePermission, &DeleteAuth{account, permission})
+}
+
+type LinkAuth struct {
+	Account     uuoskit.Name
+	Code        uuoskit.Name
+	Type        uuoskit.Name
+	Requirement uuoskit.Name
+}
+
+func (t *LinkAuth) Pack() []byte {
+	enc := uuoskit.NewEncoder(t.Size())
+	enc.PackName(t.Account)
+	enc.PackName(t.Code)
+	enc.PackName(t.Type)
+	enc.PackName(t.Requirement)
+	return enc.GetBytes()
+}
+
+func (t *LinkAuth) Size() int {
+	return 32
+}
+
+// LinkAuth requires permission of account to satisfy action typ of code
+func (c Contract) LinkAuth(account uuoskit.Name, code uuoskit.Name, typ uuoskit.Name, requirement uuoskit.Name) *uuoskit.Action {
+	return c.action("linkauth", account, activePermission, &LinkAuth{account, code, typ, requirement})
+}
+
+type UnlinkAuth struct {
+	Account uuoskit.Name
+	Code    uuoskit.Name
+	Type    uuoskit.Name
+}
+
+func (t *UnlinkAuth) Pack() []byte {
+	enc := uuoskit.NewEncoder(t.Size())
+	enc.PackName(t.Account)
+	enc.PackName(t.Code)
+	enc.PackName(t.Type)
+	return enc.GetBytes()
+}
+
+func (t *UnlinkAuth) Size() int {
+	return 24
+}
+
+func (c Contract) UnlinkAuth(account uuoskit.Name, code uuoskit.Name, typ uuoskit.Name) *uuoskit.Action {
+	return c.action("unlinkauth", account, activePermission, &UnlinkAuth{account, code, typ})
+}
+
+type VoteProducer struct {
+	Voter     uuoskit.Name
+	Proxy     uuoskit.Name
+	Producers []uuoskit.Name
+}
+
+func (t *VoteProducer) Pack() []byte {
+	enc := uuoskit.NewEncoder(t.Size())
+	enc.PackName(t.Voter)
+	enc.PackName(t.Proxy)
+	enc.PackLength(len(t.Producers))
+	for _, producer := range t.Producers {
+		enc.PackName(producer)
+	}
+	return enc.GetBytes()
+}
+
+func (t *VoteProducer) Size() int {
+	return 16 + uuoskit.PackedVarUint32Length(uint32(len(t.Producers))) + 8*len(t.Producers)
+}
+
+// VoteProducer sorts a copy of producers as the contract requires
+func (c Contract) VoteProducer(voter uuoskit.Name, proxy uuoskit.Name, producers []uuoskit.Name) *uuoskit.Action {
+	sorted := make([]uuoskit.Name, len(producers))
+	copy(sorted, producers)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].N < sorted[j].N
+	})
+	return c.action("voteproducer", voter, activePermission, &VoteProducer{voter, proxy, sorted})
+}
+
+type RegProducer struct {
+	Producer    uuoskit.Name
+	ProducerKey secp256k1.PublicKey
+	URL         string
+	Location    uint16
+}
+
+func (t *RegProducer) Pack() []byte {
+	enc := uuoskit.NewEncoder(t.Size())
+	enc.PackName(t.Producer)
+	enc.PackPublicKey(&t.ProducerKey)
+	enc.PackString(t.URL)
+	enc.PackUint16(t.Location)
+	return enc.GetBytes()
+}
+
+func (t *RegProducer) Size() int {
+	return 8 + uuoskit.PublicKeySize + uuoskit.PackedVarUint32Length(uint32(len(t.URL))) + len(t.URL) + 2
+}
+
+func (c Contract) RegProducer(producer uuoskit.Name, producerKey *secp256k1.PublicKey, url string, location uint16) *uuoskit.Action {
+	return c.action("regproducer", producer, activePermission, &RegProducer{producer, *producerKey, url, location})
+}
+
+// PowerUp fractions are scaled by 10^15, see PowerUpFraction
+type PowerUp struct {
+	Payer      uuoskit.Name
+	Receiver   uuoskit.Name
+	Days       uint32
+	NetFrac    int64
+	CPUFrac    int64
+	MaxPayment uuoskit.Asset
+}
+
+// PowerUpFraction is 100% of the net or cpu resources in PowerUp
+const PowerUpFraction = uuoskit.PowerUpFraction
+
+func (t *PowerUp) Pack() []byte {
+	enc := uuoskit.NewEncoder(t.Size())
+	enc.PackName(t.Payer)
+	enc.PackName(t.Receiver)
+	enc.PackUint32(t.Days)
+	enc.PackInt64(t.NetFrac)
+	enc.PackInt64(t.CPUFrac)
+	enc.Pack(&t.MaxPayment)
+	return enc.GetBytes()
+}
+
+func (t *PowerUp) Size() int {
+	return 52
+}
+
+func (c Contract) PowerUp(payer uuoskit.Name, receiver uuoskit.Name, days uint32, netFrac int64, cpuFrac int64, maxPayment uuoskit.Asset) *uuoskit.Action {
+	return c.action("powerup", payer, activePermission, &PowerUp{payer, receiver, days, netFrac, cpuFrac, maxPayment})
+}
+
+type SetCode struct {
+	Account   uuoskit.Name
+	VMType    uint8
+	VMVersion uint8
+	Code      []byte
+}
+
+func (t *SetCode) Pack() []byte {
+	enc := uuoskit.NewEncoder(t.Size())
+	enc.PackName(t.Account)
+	enc.PackUint8(t.VMType)
+	enc.PackUint8(t.VMVersion)
+	enc.PackBytes(t.Code)
+	return enc.GetBytes()
+}
+
+func (t *SetCode) Size() int {
+	return 10 + uuoskit.PackedVarUint32Length(uint32(len(t.Code))) + len(t.Code)
+}
+
+func (c Contract) SetCode(account uuoskit.Name, code []byte) *uuoskit.Action {
+	return c.action("setcode", account, activePermission, &SetCode{account, 0, 0, code})
+}
+
+// SetABI takes the binary ABI, see ABISerializer.PackABI
+type SetABI struct {
+	Account uuoskit.Name
+	ABI     []byte
+}
+
+func (t *SetABI) Pack() []byte {
+	enc := uuoskit.NewEncoder(t.Size())
+	enc.PackName(t.Account)
+	enc.PackBytes(t.ABI)
+	return enc.GetBytes()
+}
+
+func (t *SetABI) Size() int {
+	return 8 + uuoskit.PackedVarUint32Length(uint32(len(t.ABI))) + len(t.ABI)
+}
+
+func (c Contract) SetABI(account uuoskit.Name, abi []byte) *uuoskit.Action {
+	return c.action("setabi", account, activePermission, &SetABI{account, abi})
+}
@@ -0,0 +1,127 @@
+package system
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"testing"
+
+	"github.com/armoniax/go-uuoskit/uuoskit"
+	"github.com/stretchr/testify/assert"
+)
+
+const testKey = "AM6AjF6hvF7GSuSd4sCgfPKq5uWaXvGM2aQtEUCwmEHygQaqxBSV"
+
+func newSystemSerializer(t *testing.T) *uuoskit.ABISerializer {
+	abi, err := ioutil.ReadFile("data/eosio.system.abi")
+	assert.Nil(t, err)
+	s := uuoskit.NewABISerializer()
+	assert.Nil(t, s.SetContractABI("eosio", abi))
+	return s
+}
+
+func mustAsset(t *testing.T, v string) uuoskit.Asset {
+	a, err := uuoskit.ParseAsset(v)
+	assert.Nil(t, err)
+	return *a
+}
+
+func TestSystemActions(t *testing.T) {
+	s := newSystemSerializer(t)
+	pub, err := uuoskit.ParsePublicKey(testKey)
+	assert.Nil(t, err)
+	n := uuoskit.NewName
+	c := Default
+
+	auth := NewKeyAuthority(pub)
+	auth.Accounts = []PermissionLevelWeight{
+		{Permission: uuoskit.PermissionLevel{Actor: n("bob"), Permission: n("active")}, Weight: 1},
+		{Permission: uuoskit.PermissionLevel{Actor: n("alice"), Permission: n("owner")}, Weight: 1},
+	}
+	auth.Waits = []WaitWeight{{WaitSec: 3600, Weight: 1}}
+	assert.NotNil(t, auth.Validate())
+	auth.Sort()
+	assert.Nil(t, auth.Validate())
+	authJSON := fmt.Sprintf(`{"threshold":1,"keys":[{"key":"%s","weight":1}],`+
+		`"accounts":[{"permission":{"actor":"alice","permission":"owner"},"weight":1},`+
+		`{"permission":{"actor":"bob","permission":"active"},"weight":1}],"waits":[{"wait_sec":3600,"weight":1}]}`, testKey)
+
+	tests := []struct {
+		action     *uuoskit.Action
+		permission string
+		expected   string
+	}{
+		{c.NewAccount(n("alice"), n("newaccount11"), auth, NewAccountAuthority(n("alice"), n("active"))), "alice@active",
+			`{"creator":"alice","name":"newaccount11","owner":` + authJSON + `,"active":{"threshold":1,"keys":[],` +
+				`"accounts":[{"permission":{"actor":"alice","permission":"active"},"weight":1}],"waits":[]}}`},
+		{c.BuyRAMBytes(n("alice"), n("bob"), 8192), "alice@active",
+			`{"payer":"alice","receiver":"bob","bytes":8192}`},
+		{c.BuyRAM(n("alice"), n("bob"), mustAsset(t, "1.0000 EOS")), "alice@active",
+			`{"payer":"alice","receiver":"bob","quant":"1.0000 EOS"}`},
+		{c.SellRAM(n("alice"), 1024), "alice@active",
+			`{"account":"alice","bytes":1024}`},
+		{c.DelegateBW(n("alice"), n("bob"), mustAsset(t, "1.0000 EOS"), mustAsset(t, "2.0000 EOS"), true), "alice@active",
+			`{"from":"alice","receiver":"bob","stake_net_quantity":"1.0000 EOS","stake_cpu_quantity":"2.0000 EOS","transfer":true}`},
+		{c.UndelegateBW(n("alice"), n("bob"), mustAsset(t, "1.0000 EOS"), mustAsset(t, "2.0000 EOS")), "alice@active",
+			`{"from":"alice","receiver":"bob","unstake_net_quantity":"1.0000 EOS","unstake_cpu_quantity":"2.0000 EOS"}`},
+		{c.UpdateAuth(n("alice"), n("owner"), n(""), auth), "alice@owner",
+			`{"account":"alice","permission":"owner","parent":"","auth":` + authJSON + `}`},
+		{c.UpdateAuth(n("alice"), n("transfer"), n("active"), NewKeyAuthority(pub)), "alice@active",
+			fmt.Sprintf(`{"account":"alice","permission":"transfer","parent":"active","auth":`+
+				`{"threshold":1,"keys":[{"key":"%s","weight":1}],"accounts":[],"waits":[]}}`, testKey)},
+		{c.DeleteAuth(n("alice"), n("transfer")), "alice@active",
+			`{"account":"alice","permission":"transfer"}`},
+		{c.LinkAuth(n("alice"), n("eosio.token"), n("transfer"), n("transfer")), "alice@active",
+			`{"account":"alice","code":"eosio.token","type":"transfer","requirement":"transfer"}`},
+		{c.UnlinkAuth(n("alice"), n("eosio.token"), n("transfer")), "alice@active",
+			`{"account":"alice","code":"eosio.token","type":"transfer"}`},
+		{c.VoteProducer(n("alice"), n(""), []uuoskit.Name{n("prodb"), n("proda")}), "alice@active",
+			`{"voter":"alice","proxy":"","producers":["proda","prodb"]}`},
+		{c.RegProducer(n("proda"), pub, "https://example.com", 840), "proda@active",
+			fmt.Sprintf(`{"producer":"proda","producer_key":"%s","url":"https://example.com","location":840}`, testKey)},
+		{c.PowerUp(n("alice"), n("bob"), 1, PowerUpFraction/100, PowerUpFraction/10, mustAsset(t, "1.0000 EOS")), "alice@active",
+			`{"payer":"alice","receiver":"bob","days":1,"net_frac":10000000000000,"cpu_frac":100000000000000,"max_payment":"1.0000 EOS"}`},
+		{c.SetCode(n("alice"), []byte{0, 0x61, 0x73, 0x6d}), "alice@active",
+			`{"account":"alice","vmtype":0,"vmversion":0,"code":"0061736d"}`},
+		{c.SetABI(n("alice"), []byte{1, 2, 3}), "alice@active",
+			`{"account":"alice","abi":"010203"}`},
+	}
+
+	for _, test := range tests {
+		a := test.action
+		name := a.Name.String()
+		assert.Equal(t, "eosio", a.Account.String())
+		assert.Equal(t, 1, len(a.Authorization))
+		perm := a.Authorization[0]
+		assert.Equal(t, test.permission, perm.Actor.String()+"@"+perm.Permission.String(), name)
+
+		r, err := s.UnpackActionArgs("eosio", name, a.Data)
+		assert.Nil(t, err, name)
+		assert.Equal(t, test.expected, string(r), name)
+
+		packed, err := s.PackActionArgs("eosio", name, test.expected)
+		assert.Nil(t, err, name)
+		assert.Equal(t, hex.EncodeToString(packed), hex.EncodeToString(a.Data), name)
+	}
+
+	c = NewContract(uuoskit.EOSProfile())
+	assert.Equal(t, "eosio", c.SellRAM(n("alice"), 1).Account.String())
+}
+
+func TestAuthorityValidate(t *testing.T) {
+	pub, err := uuoskit.ParsePublicKey(testKey)
+	assert.Nil(t, err)
+	auth := NewKeyAuthority(pub)
+	assert.Nil(t, auth.Validate())
+
+	auth.Threshold = 2
+	assert.NotNil(t, auth.Validate())
+	auth.Keys = append(auth.Keys, auth.Keys[0])
+	assert.NotNil(t, auth.Validate())
+
+	auth = NewAccountAuthority(uuoskit.NewName("alice"), uuoskit.NewName("active"))
+	auth.Accounts[0].Weight = 0
+	assert.NotNil(t, auth.Validate())
+	auth = Authority{}
+	assert.NotNil(t, auth.Validate())
+}
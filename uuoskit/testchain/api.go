@@ -0,0 +1,507 @@
+package testchain
+
+import (
+	"bytes"
+	"compress/zlib"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	secp256k1 "github.com/armoniax/go-secp256k1"
+	"github.com/armoniax/go-uuoskit/uuoskit"
+)
+
+// ServeHTTP answers the chain api endpoints of nodeos, other endpoints are
+// answered with 404 as nodeos does.
+func (c *Chain) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	handlers := map[string]func(body []byte) (interface{}, error){
+		"get_info":          c.getInfo,
+		"get_account":       c.getAccount,
+		"get_required_keys": c.getRequiredKeys,
+		"get_raw_abi":       c.getRawAbi,
+		"get_table_rows":    c.getTableRows,
+		"push_transaction":  c.pushTransaction,
+	}
+	handler, ok := handlers[strings.TrimPrefix(r.URL.Path, "/v1/chain/")]
+	if !ok || !strings.HasPrefix(r.URL.Path, "/v1/chain/") {
+		writeStatus(w, http.StatusNotFound, map[string]interface{}{
+			"code":    http.StatusNotFound,
+			"message": "Not Found",
+			"error": map[string]interface{}{
+				"code":    0,
+				"name":    "exception",
+				"what":    "unknown",
+				"details": []map[string]interface{}{{"message": "Unknown Endpoint"}},
+			},
+		})
+		return
+	}
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, badRequest(err))
+		return
+	}
+
+	c.mu.Lock()
+	result, err := handler(body)
+	c.mu.Unlock()
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeStatus(w, http.StatusOK, result)
+}
+
+func (c *Chain) getInfo(body []byte) (interface{}, error) {
+	return &uuoskit.ChainInfo{
+		ServerVersion:             "00000000",
+		ChainID:                   c.ChainId(),
+		HeadBlockNum:              int64(c.blockNum),
+		LastIrreversibleBlockNum:  int64(c.blockNum),
+		LastIrreversibleBlockID:   c.blockId(c.blockNum),
+		HeadBlockID:               c.blockId(c.blockNum),
+		HeadBlockTime:             c.blockTime(c.blockNum),
+		HeadBlockProducer:         c.profile.SystemAccount.String(),
+		VirtualBlockCPULimit:      200000000,
+		VirtualBlockNetLimit:      1048576000,
+		BlockCPULimit:             200000,
+		BlockNetLimit:             1048576,
+		ServerVersionString:       "testchain",
+		ForkDBHeadBlockNum:        int64(c.blockNum),
+		ForkDBHeadBlockID:         c.blockId(c.blockNum),
+		ServerFullVersionString:   "testchain",
+		LastIrreversibleBlockTime: c.blockTime(c.blockNum),
+	}, nil
+}
+
+// authorityJson renders auth with the keys in the legacy format of the
+// profile as nodeos does
+func (c *Chain) authorityJson(auth *uuoskit.Authority) map[string]interface{} {
+	keys := make([]map[string]interface{}, 0, len(auth.Keys))
+	for i := range auth.Keys {
+		keys = append(keys, map[string]interface{}{
+			"key":    c.profile.PublicKeyString(&auth.Keys[i].Key),
+			"weight": auth.Keys[i].Weight,
+		})
+	}
+	accounts := append([]uuoskit.PermissionLevelWeight{}, auth.Accounts...)
+	waits := append([]uuoskit.WaitWeight{}, auth.Waits...)
+	return map[string]interface{}{
+		"threshold": auth.Threshold,
+		"keys":      keys,
+		"accounts":  accounts,
+		"waits":     waits,
+	}
+}
+
+func (c *Chain) getAccount(body []byte) (interface{}, error) {
+	var args uuoskit.GetAccountArgs
+	if err := json.Unmarshal(body, &args); err != nil {
+		return nil, badRequest(err)
+	}
+	a, ok := c.state.accounts[uuoskit.NewName(args.AccountName)]
+	if !ok {
+		return nil, unknownAccount(args.AccountName)
+	}
+	permissions := make([]map[string]interface{}, 0, len(a.permissions))
+	for i := range a.permissions {
+		p := &a.permissions[i]
+		permissions = append(permissions, map[string]interface{}{
+			"perm_name":     p.PermName.String(),
+			"parent":        p.Parent.String(),
+			"required_auth": c.authorityJson(&p.RequiredAuth),
+		})
+	}
+	unlimited := map[string]interface{}{"used": 0, "available": -1, "max": -1}
+	result := map[string]interface{}{
+		"account_name":             args.AccountName,
+		"head_block_num":           c.blockNum,
+		"head_block_time":          c.blockTime(c.blockNum),
+		"privileged":               false,
+		"last_code_update":         "1970-01-01T00:00:00.000",
+		"created":                  c.blockTime(a.created),
+		"ram_quota":                -1,
+		"net_weight":               -1,
+		"cpu_weight":               -1,
+		"net_limit":                unlimited,
+		"cpu_limit":                unlimited,
+		"ram_usage":                0,
+		"permissions":              permissions,
+		"total_resources":          nil,
+		"self_delegated_bandwidth": nil,
+		"refund_request":           nil,
+		"voter_info":               nil,
+	}
+	if balance, ok := c.state.balances[uuoskit.NewName(args.AccountName)][c.profile.CoreSymbol.Code()]; ok {
+		result["core_liquid_balance"] = balance.String()
+	}
+	return result, nil
+}
+
+func (c *Chain) getRequiredKeys(body []byte) (interface{}, error) {
+	var args struct {
+		Transaction   uuoskit.Transaction `json:"transaction"`
+		AvailableKeys []string            `json:"available_keys"`
+	}
+	if err := json.Unmarshal(body, &args); err != nil {
+		return nil, badRequest(err)
+	}
+	available := map[string]*secp256k1.PublicKey{}
+	keys := map[string]bool{}
+	for _, key := range args.AvailableKeys {
+		pub, err := c.profile.ParsePublicKey(key)
+		if err != nil {
+			return nil, badRequest(err)
+		}
+		available[pub.String()] = pub
+		keys[pub.String()] = true
+	}
+	required, err := c.checkAuthorization(args.Transaction.Actions, keys)
+	if err != nil {
+		return nil, err
+	}
+	requiredKeys := make([]string, 0, len(required))
+	for _, key := range required {
+		requiredKeys = append(requiredKeys, c.profile.PublicKeyString(available[key]))
+	}
+	return &uuoskit.GetRequiredKeysResult{RequiredKeys: requiredKeys}, nil
+}
+
+func (c *Chain) getRawAbi(body []byte) (interface{}, error) {
+	var args struct {
+		AccountName string `json:"account_name"`
+	}
+	if err := json.Unmarshal(body, &args); err != nil {
+		return nil, badRequest(err)
+	}
+	a, ok := c.state.accounts[uuoskit.NewName(args.AccountName)]
+	if !ok {
+		return nil, unknownAccount(args.AccountName)
+	}
+	result := map[string]interface{}{
+		"account_name": args.AccountName,
+		"code_hash":    hex.EncodeToString(make([]byte, 32)),
+		"abi_hash":     hex.EncodeToString(make([]byte, 32)),
+	}
+	if a.abi == "" {
+		return result, nil
+	}
+	abi, err := c.serializer.PackABI(a.abi)
+	if err != nil {
+		return nil, err
+	}
+	abiHash := sha256.Sum256(abi)
+	result["abi_hash"] = hex.EncodeToString(abiHash[:])
+	result["abi"] = base64.StdEncoding.EncodeToString(abi)
+	return result, nil
+}
+
+type tableRow struct {
+	key  uint64
+	data interface{}
+}
+
+// parseBound parses a bound of get_table_rows given as a number, a symbol
+// code or a name
+func parseBound(bound string) (uint64, error) {
+	if n, err := strconv.ParseUint(bound, 10, 64); err == nil {
+		return n, nil
+	}
+	if uuoskit.IsSymbolValid(bound) {
+		return parseSymbolCode(bound)
+	}
+	name, err := uuoskit.ParseName(bound)
+	if err != nil {
+		return 0, err
+	}
+	return name.N, nil
+}
+
+// tableRows returns the rows of the tables of the token contract, sorted
+// by primary key. The tables of other contracts are empty.
+func (c *Chain) tableRows(code uuoskit.Name, scope string, table string) []tableRow {
+	rows := []tableRow{}
+	if code != c.profile.TokenAccount {
+		return rows
+	}
+	switch table {
+	case "accounts":
+		for symbolCode, balance := range c.state.balances[uuoskit.NewName(scope)] {
+			rows = append(rows, tableRow{symbolCode, map[string]interface{}{"balance": balance}})
+		}
+	case "stat":
+		if symbolCode, err := parseSymbolCode(scope); err == nil {
+			if stats, ok := c.state.stats[symbolCode]; ok {
+				rows = append(rows, tableRow{symbolCode, stats})
+			}
+		}
+	}
+	sort.Slice(rows, func(i, j int) bool { return rows[i].key < rows[j].key })
+	return rows
+}
+
+func (c *Chain) getTableRows(body []byte) (interface{}, error) {
+	var args uuoskit.GetTableRowsArgs
+	if err := json.Unmarshal(body, &args); err != nil {
+		return nil, badRequest(err)
+	}
+	code := uuoskit.NewName(args.Code)
+	a, ok := c.state.accounts[code]
+	if !ok {
+		return nil, unknownAccount(args.Code)
+	}
+	var abi uuoskit.ABI
+	if a.abi == "" || json.Unmarshal([]byte(a.abi), &abi) != nil {
+		return nil, tableQuery(fmt.Sprintf("ABI for contract %s not found", args.Code))
+	}
+	abiType := ""
+	for _, t := range abi.Tables {
+		if t.Name == args.Table {
+			abiType = t.Type
+		}
+	}
+	if abiType == "" {
+		return nil, tableQuery(fmt.Sprintf("Table %s is not specified in the ABI", args.Table))
+	}
+
+	lower, upper := uint64(0), ^uint64(0)
+	var err error
+	if args.LowerBound != "" {
+		if lower, err = parseBound(args.LowerBound); err != nil {
+			return nil, badRequest(err)
+		}
+	}
+	if args.UpperBound != "" {
+		if upper, err = parseBound(args.UpperBound); err != nil {
+			return nil, badRequest(err)
+		}
+	}
+	limit := args.Limit
+	if limit <= 0 {
+		limit = 10
+	}
+
+	rows := []interface{}{}
+	more := false
+	nextKey := ""
+	for _, row := range c.tableRows(code, args.Scope, args.Table) {
+		if row.key < lower || row.key > upper {
+			continue
+		}
+		if len(rows) == limit {
+			more = true
+			nextKey = strconv.FormatUint(row.key, 10)
+			break
+		}
+		data, err := json.Marshal(row.data)
+		if err != nil {
+			return nil, err
+		}
+		if args.Json {
+			rows = append(rows, json.RawMessage(data))
+			continue
+		}
+		packed, err := c.serializer.PackAbiType(args.Code, abiType, string(data))
+		if err != nil {
+			return nil, err
+		}
+		rows = append(rows, hex.EncodeToString(packed))
+	}
+	return map[string]interface{}{"rows": rows, "more": more, "next_key": nextKey}, nil
+}
+
+// unpackTransaction returns the transaction of a packed transaction, its
+// id and its signing digest
+func (c *Chain) unpackTransaction(packed *uuoskit.PackedTransaction) (*uuoskit.Transaction, string, []byte, error) {
+	packedTrx := []byte(packed.PackedTx)
+	if packed.Compression == "zlib" || packed.Compression == "1" {
+		r, err := zlib.NewReader(bytes.NewReader(packedTrx))
+		if err != nil {
+			return nil, "", nil, invalidTransaction(err)
+		}
+		if packedTrx, err = ioutil.ReadAll(io.LimitReader(r, 1<<20)); err != nil {
+			return nil, "", nil, invalidTransaction(err)
+		}
+	}
+	tx := &uuoskit.Transaction{}
+	if _, err := tx.Unpack(packedTrx); err != nil {
+		return nil, "", nil, invalidTransaction(err)
+	}
+	id := sha256.Sum256(packedTrx)
+
+	cfdHash := [32]byte{}
+	if len(packed.PackedContext) > 0 {
+		cfdHash = sha256.Sum256(packed.PackedContext)
+	}
+	hash := sha256.New()
+	hash.Write(c.chainId[:])
+	hash.Write(packedTrx)
+	hash.Write(cfdHash[:])
+	return tx, hex.EncodeToString(id[:]), hash.Sum(nil), nil
+}
+
+// checkTapos checks the expiration and the reference block of tx against
+// the block that would include it
+func (c *Chain) checkTapos(tx *uuoskit.Transaction) error {
+	pendingTime := genesisTime.Add(time.Duration(c.blockNum) * blockInterval)
+	if tx.Expiration.Time().Before(pendingTime) {
+		return expiredTransaction(tx.Expiration.String(), c.blockTime(c.blockNum+1))
+	}
+	refBlockNum := c.blockNum - uint32(uint16(c.blockNum)-tx.RefBlockNum)
+	if refBlockNum < 1 || refBlockNum > c.blockNum {
+		return invalidRefBlock()
+	}
+	id, _ := hex.DecodeString(c.blockId(refBlockNum))
+	if uuoskit.GetRefBlockPrefix(id) != tx.RefBlockPrefix {
+		return invalidRefBlock()
+	}
+	return nil
+}
+
+func (c *Chain) pushTransaction(body []byte) (interface{}, error) {
+	packed := &uuoskit.PackedTransaction{}
+	if err := json.Unmarshal(body, packed); err != nil {
+		return nil, badRequest(err)
+	}
+	tx, id, digest, err := c.unpackTransaction(packed)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.checkTapos(tx); err != nil {
+		return nil, err
+	}
+	if c.txIds[id] {
+		return nil, duplicateTransaction(id)
+	}
+
+	keys := map[string]bool{}
+	for _, s := range packed.Signatures {
+		sig, err := secp256k1.NewSignatureFromBase58(s)
+		if err != nil {
+			return nil, invalidTransaction(err)
+		}
+		pub, err := secp256k1.Recover(digest, sig)
+		if err != nil {
+			return nil, invalidTransaction(err)
+		}
+		keys[pub.String()] = true
+	}
+	required, err := c.checkAuthorization(tx.Actions, keys)
+	if err != nil {
+		return nil, err
+	}
+	if len(required) < len(keys) {
+		irrelevant := []string{}
+		for key := range keys {
+			if i := sort.SearchStrings(required, key); i == len(required) || required[i] != key {
+				irrelevant = append(irrelevant, key)
+			}
+		}
+		sort.Strings(irrelevant)
+		return nil, irrelevantSignatures(irrelevant)
+	}
+
+	blockNum := c.blockNum + 1
+	blockTime := c.blockTime(blockNum)
+	st := c.state.clone()
+	actionTraces := []interface{}{}
+	for i := range tx.Actions {
+		a := &tx.Actions[i]
+		if !c.declaresAction(st, a) {
+			return nil, actionValidate(fmt.Sprintf("action %s::%s is not declared in the ABI of %s",
+				a.Account.String(), a.Name.String(), a.Account.String()))
+		}
+		args, err := c.serializer.UnpackActionArgs(a.Account.String(), a.Name.String(), a.Data)
+		if err != nil {
+			return nil, invalidArgs(err)
+		}
+		notified, err := c.apply(st, a, args)
+		if err != nil {
+			return nil, err
+		}
+		creator := len(actionTraces) + 1
+		receivers := append([]uuoskit.Name{a.Account}, notified...)
+		for j, receiver := range receivers {
+			creatorOrdinal := 0
+			if j > 0 {
+				creatorOrdinal = creator
+			}
+			actionTraces = append(actionTraces, map[string]interface{}{
+				"action_ordinal":         len(actionTraces) + 1,
+				"creator_action_ordinal": creatorOrdinal,
+				"receiver":               receiver.String(),
+				"act": map[string]interface{}{
+					"account":       a.Account.String(),
+					"name":          a.Name.String(),
+					"authorization": a.Authorization,
+					"data":          json.RawMessage(args),
+					"hex_data":      hex.EncodeToString(a.Data),
+				},
+				"context_free":          false,
+				"elapsed":               10,
+				"console":               "",
+				"trx_id":                id,
+				"block_num":             blockNum,
+				"block_time":            blockTime,
+				"producer_block_id":     nil,
+				"account_ram_deltas":    []interface{}{},
+				"except":                nil,
+				"error_code":            nil,
+				"return_value_hex_data": "",
+			})
+		}
+	}
+
+	c.state = st
+	c.blockNum = blockNum
+	c.txIds[id] = true
+	c.pushed = append(c.pushed, *tx)
+	netUsageWords := (len(packed.PackedTx) + len(packed.PackedContext) + 65*len(packed.Signatures) + 7) / 8
+	return map[string]interface{}{
+		"transaction_id": id,
+		"processed": map[string]interface{}{
+			"id":         id,
+			"block_num":  blockNum,
+			"block_time": blockTime,
+			"receipt": map[string]interface{}{
+				"status":          "executed",
+				"cpu_usage_us":    100,
+				"net_usage_words": netUsageWords,
+			},
+			"elapsed":           100,
+			"net_usage":         netUsageWords * 8,
+			"scheduled":         false,
+			"action_traces":     actionTraces,
+			"account_ram_delta": nil,
+			"except":            nil,
+			"error_code":        nil,
+		},
+	}, nil
+}
+
+// declaresAction reports whether the ABI of the account of a declares it
+func (c *Chain) declaresAction(st *state, a *uuoskit.Action) bool {
+	account, ok := st.accounts[a.Account]
+	if !ok || account.abi == "" {
+		return false
+	}
+	var abi uuoskit.ABI
+	if err := json.Unmarshal([]byte(account.abi), &abi); err != nil {
+		return false
+	}
+	for _, action := range abi.Actions {
+		if action.Name == a.Name.String() {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,246 @@
+package testchain
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/armoniax/go-uuoskit/uuoskit"
+)
+
+func parseSymbolCode(code string) (uint64, error) {
+	if !uuoskit.IsSymbolValid(code) {
+		return 0, fmt.Errorf("invalid symbol code %q", code)
+	}
+	sym := uuoskit.NewSymbol(code, 0)
+	return sym.Code(), nil
+}
+
+func (s *state) createToken(issuer uuoskit.Name, maxSupply uuoskit.Asset) error {
+	if _, ok := s.accounts[issuer]; !ok {
+		return assertionFailure("issuer account does not exist")
+	}
+	if !maxSupply.Symbol.IsValid() {
+		return assertionFailure("invalid symbol name")
+	}
+	if maxSupply.Amount <= 0 || maxSupply.Amount > uuoskit.MAX_AMOUNT {
+		return assertionFailure("max-supply must be positive")
+	}
+	code := maxSupply.Symbol.Code()
+	if _, ok := s.stats[code]; ok {
+		return assertionFailure("token with symbol already exists")
+	}
+	s.stats[code] = &currencyStats{
+		Supply:    uuoskit.Asset{Amount: 0, Symbol: maxSupply.Symbol},
+		MaxSupply: maxSupply,
+		Issuer:    issuer,
+	}
+	return nil
+}
+
+func (s *state) issue(to uuoskit.Name, quantity uuoskit.Asset) error {
+	stats, ok := s.stats[quantity.Symbol.Code()]
+	if !ok {
+		return assertionFailure("token with symbol does not exist, create token before issue")
+	}
+	if quantity.Amount <= 0 {
+		return assertionFailure("must issue positive quantity")
+	}
+	if quantity.Symbol != stats.Supply.Symbol {
+		return assertionFailure("symbol precision mismatch")
+	}
+	if quantity.Amount > stats.MaxSupply.Amount-stats.Supply.Amount {
+		return assertionFailure("quantity exceeds available supply")
+	}
+	stats.Supply.Amount += quantity.Amount
+	s.addBalance(to, quantity)
+	return nil
+}
+
+func (s *state) addBalance(owner uuoskit.Name, value uuoskit.Asset) {
+	balances, ok := s.balances[owner]
+	if !ok {
+		balances = map[uint64]uuoskit.Asset{}
+		s.balances[owner] = balances
+	}
+	balance, ok := balances[value.Symbol.Code()]
+	if !ok {
+		balance = uuoskit.Asset{Amount: 0, Symbol: value.Symbol}
+	}
+	balance.Amount += value.Amount
+	balances[value.Symbol.Code()] = balance
+}
+
+func (s *state) subBalance(owner uuoskit.Name, value uuoskit.Asset) error {
+	balance, ok := s.balances[owner][value.Symbol.Code()]
+	if !ok {
+		return assertionFailure("no balance object found")
+	}
+	if balance.Amount < value.Amount {
+		return assertionFailure("overdrawn balance")
+	}
+	balance.Amount -= value.Amount
+	s.balances[owner][value.Symbol.Code()] = balance
+	return nil
+}
+
+func (s *state) transfer(from uuoskit.Name, to uuoskit.Name, quantity uuoskit.Asset, memo string) error {
+	if from == to {
+		return assertionFailure("cannot transfer to self")
+	}
+	if _, ok := s.accounts[to]; !ok {
+		return assertionFailure("to account does not exist")
+	}
+	stats, ok := s.stats[quantity.Symbol.Code()]
+	if !ok {
+		return assertionFailure("unable to find key")
+	}
+	if !quantity.IsValid() {
+		return assertionFailure("invalid quantity")
+	}
+	if quantity.Amount <= 0 {
+		return assertionFailure("must transfer positive quantity")
+	}
+	if quantity.Symbol != stats.Supply.Symbol {
+		return assertionFailure("symbol precision mismatch")
+	}
+	if len(memo) > 256 {
+		return assertionFailure("memo has more than 256 bytes")
+	}
+	if err := s.subBalance(from, quantity); err != nil {
+		return err
+	}
+	s.addBalance(to, quantity)
+	return nil
+}
+
+// requireAuth fails as require_auth does when actor is not among the
+// authorizations of a
+func requireAuth(a *uuoskit.Action, actor uuoskit.Name) error {
+	for _, level := range a.Authorization {
+		if level.Actor == actor {
+			return nil
+		}
+	}
+	return missingAuthority(actor)
+}
+
+// apply runs a on st and returns the accounts notified besides the
+// receiver. args is the JSON of the action data.
+func (c *Chain) apply(st *state, a *uuoskit.Action, args []byte) ([]uuoskit.Name, error) {
+	switch a.Account {
+	case c.profile.SystemAccount:
+		return nil, c.applySystem(st, a, args)
+	case c.profile.TokenAccount:
+		return c.applyToken(st, a, args)
+	}
+	return nil, nil
+}
+
+func (c *Chain) applySystem(st *state, a *uuoskit.Action, args []byte) error {
+	switch a.Name.String() {
+	case "newaccount":
+		var v struct {
+			Creator uuoskit.Name      `json:"creator"`
+			Name    uuoskit.Name      `json:"name"`
+			Owner   uuoskit.Authority `json:"owner"`
+			Active  uuoskit.Authority `json:"active"`
+		}
+		if err := json.Unmarshal(args, &v); err != nil {
+			return invalidArgs(err)
+		}
+		if err := requireAuth(a, v.Creator); err != nil {
+			return err
+		}
+		if err := uuoskit.CheckNewAccountName(c.profile.SystemAccount, v.Creator, v.Name); err != nil {
+			return assertionFailure(err.Error())
+		}
+		if _, ok := st.accounts[v.Name]; ok {
+			return accountNameExists(v.Name)
+		}
+		st.accounts[v.Name] = newAccount(c.blockNum+1, v.Owner, v.Active)
+	case "updateauth":
+		var v struct {
+			Account    uuoskit.Name      `json:"account"`
+			Permission uuoskit.Name      `json:"permission"`
+			Parent     uuoskit.Name      `json:"parent"`
+			Auth       uuoskit.Authority `json:"auth"`
+		}
+		if err := json.Unmarshal(args, &v); err != nil {
+			return invalidArgs(err)
+		}
+		if err := requireAuth(a, v.Account); err != nil {
+			return err
+		}
+		account, ok := st.accounts[v.Account]
+		if !ok {
+			return assertionFailure(fmt.Sprintf("account %s does not exist", v.Account.String()))
+		}
+		if perm := findPermission(account, v.Permission); perm != nil {
+			perm.RequiredAuth = v.Auth
+			return nil
+		}
+		if findPermission(account, v.Parent) == nil {
+			return assertionFailure(fmt.Sprintf("parent permission %s does not exist", v.Parent.String()))
+		}
+		account.permissions = append(account.permissions, uuoskit.Permission{
+			PermName:     v.Permission,
+			Parent:       v.Parent,
+			RequiredAuth: v.Auth,
+		})
+	}
+	return nil
+}
+
+func (c *Chain) applyToken(st *state, a *uuoskit.Action, args []byte) ([]uuoskit.Name, error) {
+	switch a.Name.String() {
+	case "create":
+		var v struct {
+			Issuer        uuoskit.Name  `json:"issuer"`
+			MaximumSupply uuoskit.Asset `json:"maximum_supply"`
+		}
+		if err := json.Unmarshal(args, &v); err != nil {
+			return nil, invalidArgs(err)
+		}
+		if err := requireAuth(a, c.profile.TokenAccount); err != nil {
+			return nil, err
+		}
+		return nil, st.createToken(v.Issuer, v.MaximumSupply)
+	case "issue":
+		var v struct {
+			To       uuoskit.Name  `json:"to"`
+			Quantity uuoskit.Asset `json:"quantity"`
+		}
+		if err := json.Unmarshal(args, &v); err != nil {
+			return nil, invalidArgs(err)
+		}
+		stats, ok := st.stats[v.Quantity.Symbol.Code()]
+		if !ok {
+			return nil, assertionFailure("token with symbol does not exist, create token before issue")
+		}
+		if v.To != stats.Issuer {
+			return nil, assertionFailure("tokens can only be issued to issuer account")
+		}
+		if err := requireAuth(a, stats.Issuer); err != nil {
+			return nil, err
+		}
+		return nil, st.issue(v.To, v.Quantity)
+	case "transfer":
+		var v struct {
+			From     uuoskit.Name  `json:"from"`
+			To       uuoskit.Name  `json:"to"`
+			Quantity uuoskit.Asset `json:"quantity"`
+			Memo     string        `json:"memo"`
+		}
+		if err := json.Unmarshal(args, &v); err != nil {
+			return nil, invalidArgs(err)
+		}
+		if err := requireAuth(a, v.From); err != nil {
+			return nil, err
+		}
+		if err := st.transfer(v.From, v.To, v.Quantity, v.Memo); err != nil {
+			return nil, err
+		}
+		return []uuoskit.Name{v.From, v.To}, nil
+	}
+	return nil, nil
+}
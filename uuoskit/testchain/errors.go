@@ -0,0 +1,128 @@
+package testchain
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/armoniax/go-uuoskit/uuoskit"
+)
+
+// chainError is an exception of nodeos, it is written as the error object
+// of a failed api call.
+type chainError struct {
+	status  int
+	code    int64
+	name    string
+	what    string
+	message string
+}
+
+func (e *chainError) Error() string {
+	return e.message
+}
+
+func newChainError(code int64, name string, what string, message string) *chainError {
+	return &chainError{http.StatusInternalServerError, code, name, what, message}
+}
+
+func assertionFailure(msg string) error {
+	return newChainError(3050003, "eosio_assert_message_exception", "eosio_assert_message assertion failure",
+		"assertion failure with message: "+msg)
+}
+
+func missingAuthority(actor uuoskit.Name) error {
+	return newChainError(3090004, "missing_auth_exception", "Missing required authority",
+		"missing authority of "+actor.String())
+}
+
+func unsatisfiedAuthorization(level uuoskit.PermissionLevel) error {
+	return newChainError(3090003, "unsatisfied_authorization",
+		"Provided keys, permissions, and delays do not satisfy declared authorizations",
+		fmt.Sprintf("transaction declares authority '{\"actor\":\"%s\",\"permission\":\"%s\"}', but does not have signatures for it.",
+			level.Actor.String(), level.Permission.String()))
+}
+
+func irrelevantSignatures(keys []string) error {
+	return newChainError(3090005, "tx_irrelevant_sig", "Irrelevant signature included",
+		fmt.Sprintf("transaction bears irrelevant signatures from these keys: %v", keys))
+}
+
+func accountNameExists(name uuoskit.Name) error {
+	return newChainError(3050001, "account_name_exists_exception", "Account name already exists",
+		fmt.Sprintf("Cannot create account named %s, as that name is already taken", name.String()))
+}
+
+func unknownAccount(name string) error {
+	return newChainError(3060002, "account_query_exception", "Account Query Exception",
+		fmt.Sprintf("unknown key (eosio::chain::name): %s", name))
+}
+
+func invalidArgs(err error) error {
+	return newChainError(3050002, "invalid_action_args_exception", "Invalid Action Arguments", err.Error())
+}
+
+func actionValidate(msg string) error {
+	return newChainError(3050000, "action_validate_exception", "Action validate exception", msg)
+}
+
+func tableQuery(msg string) error {
+	return newChainError(3060003, "contract_table_query_exception", "Contract Table Query Exception", msg)
+}
+
+func invalidTransaction(err error) error {
+	return newChainError(3040000, "transaction_exception", "Transaction exception", err.Error())
+}
+
+func expiredTransaction(expiration string, now string) error {
+	return newChainError(3040005, "expired_tx_exception", "Expired Transaction",
+		fmt.Sprintf("expired transaction, expiration is %s and pending block time is %s", expiration, now))
+}
+
+func invalidRefBlock() error {
+	return newChainError(3040007, "invalid_ref_block_exception", "Invalid Reference Block",
+		"Transaction's reference block did not match. Is this transaction from a different fork?")
+}
+
+func duplicateTransaction(id string) error {
+	return newChainError(3040008, "tx_duplicate", "Duplicate transaction", "duplicate transaction "+id)
+}
+
+func badRequest(err error) error {
+	return &chainError{http.StatusBadRequest, 4, "parse_error_exception", "Parse Error", err.Error()}
+}
+
+// writeError writes err in the format of nodeos
+func writeError(w http.ResponseWriter, err error) {
+	e, ok := err.(*chainError)
+	if !ok {
+		e = newChainError(0, "exception", "unspecified", err.Error())
+	}
+	message := "Internal Service Error"
+	if e.status == http.StatusBadRequest {
+		message = "Bad Request"
+	}
+	writeStatus(w, e.status, map[string]interface{}{
+		"code":    e.status,
+		"message": message,
+		"error": map[string]interface{}{
+			"code": e.code,
+			"name": e.name,
+			"what": e.what,
+			"details": []map[string]interface{}{
+				{"message": e.message, "file": "", "line_number": 0, "method": ""},
+			},
+		},
+	})
+}
+
+func writeStatus(w http.ResponseWriter, status int, v interface{}) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		status = http.StatusInternalServerError
+		b = []byte(`{"code":500,"message":"Internal Service Error"}`)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(b)
+}
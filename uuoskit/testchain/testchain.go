@@ -0,0 +1,341 @@
+// Package testchain is an in-process stand-in for the chain api of nodeos,
+// tests built on it run without a network.
+package testchain
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"net/http/httptest"
+	"sort"
+	"sync"
+	"time"
+
+	secp256k1 "github.com/armoniax/go-secp256k1"
+	"github.com/armoniax/go-uuoskit/uuoskit"
+)
+
+// GenesisPrivateKey controls the system accounts created by New
+const GenesisPrivateKey = "5KQwrPbwdL6PhXujxW37FSSQZ1JiwsST4cqQzDeyXtP79zkvFD3"
+
+// genesisTime is the time of block 1, blocks follow every blockInterval
+var genesisTime = time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+
+const blockInterval = 500 * time.Millisecond
+
+type account struct {
+	created     uint32
+	permissions []uuoskit.Permission
+	abi         string
+}
+
+type currencyStats struct {
+	Supply    uuoskit.Asset `json:"supply"`
+	MaxSupply uuoskit.Asset `json:"max_supply"`
+	Issuer    uuoskit.Name  `json:"issuer"`
+}
+
+// state is everything a transaction can change, it is cloned before a
+// transaction is applied and restored when the transaction fails.
+type state struct {
+	accounts map[uuoskit.Name]*account
+	// stats and balances of the token contract are keyed by symbol code
+	stats    map[uint64]*currencyStats
+	balances map[uuoskit.Name]map[uint64]uuoskit.Asset
+}
+
+func (s *state) clone() *state {
+	c := &state{
+		accounts: make(map[uuoskit.Name]*account, len(s.accounts)),
+		stats:    make(map[uint64]*currencyStats, len(s.stats)),
+		balances: make(map[uuoskit.Name]map[uint64]uuoskit.Asset, len(s.balances)),
+	}
+	for name, a := range s.accounts {
+		_a := *a
+		_a.permissions = append([]uuoskit.Permission{}, a.permissions...)
+		c.accounts[name] = &_a
+	}
+	for code, stats := range s.stats {
+		_stats := *stats
+		c.stats[code] = &_stats
+	}
+	for owner, balances := range s.balances {
+		_balances := make(map[uint64]uuoskit.Asset, len(balances))
+		for code, balance := range balances {
+			_balances[code] = balance
+		}
+		c.balances[owner] = _balances
+	}
+	return c
+}
+
+// Chain keeps accounts, permissions, ABIs and the balances of the token
+// contract of its profile in memory. Every pushed transaction makes a new
+// block, so a chain driven by the same calls always answers the same.
+type Chain struct {
+	server     *httptest.Server
+	profile    *uuoskit.ChainProfile
+	serializer *uuoskit.ABISerializer
+	chainId    [32]byte
+
+	mu       sync.Mutex
+	blockNum uint32
+	state    *state
+	txIds    map[string]bool
+	pushed   []uuoskit.Transaction
+}
+
+// New starts a chain with the default profile
+func New() *Chain {
+	return NewWithProfile(uuoskit.DefaultChainProfile())
+}
+
+// NewWithProfile starts a chain whose system accounts are the accounts of
+// profile, owned by GenesisPrivateKey.
+func NewWithProfile(profile *uuoskit.ChainProfile) *Chain {
+	c := &Chain{
+		profile:    profile,
+		serializer: uuoskit.NewABISerializer(),
+		chainId:    sha256.Sum256([]byte("testchain " + profile.Name)),
+		blockNum:   1,
+		state: &state{
+			accounts: map[uuoskit.Name]*account{},
+			stats:    map[uint64]*currencyStats{},
+			balances: map[uuoskit.Name]map[uint64]uuoskit.Asset{},
+		},
+		txIds: map[string]bool{},
+	}
+	// keys are compared in the PUB_K1_ format whatever the key prefix
+	c.serializer.SetUnpackOptions(uuoskit.UnpackOptions{PublicKeyFormat: uuoskit.PublicKeyK1})
+
+	priv, err := secp256k1.NewPrivateKeyFromBase58(GenesisPrivateKey)
+	if err != nil {
+		panic(err)
+	}
+	genesisAuth := uuoskit.NewKeyAuthority(priv.GetPublicKey())
+	for _, name := range []uuoskit.Name{
+		profile.SystemAccount,
+		profile.TokenAccount,
+		profile.MsigAccount,
+		profile.RamAccount,
+		profile.RamFeeAccount,
+		profile.StakeAccount,
+	} {
+		c.state.accounts[name] = newAccount(c.blockNum, genesisAuth, genesisAuth)
+	}
+
+	tokenAbi, ok := profile.ABIs["eosio.token"]
+	if !ok {
+		tokenAbi = uuoskit.DefaultChainProfile().ABIs["eosio.token"]
+	}
+	if err := c.setABI(profile.SystemAccount, systemAbi); err != nil {
+		panic(err)
+	}
+	if err := c.setABI(profile.TokenAccount, tokenAbi); err != nil {
+		panic(err)
+	}
+	c.server = httptest.NewServer(c)
+	return c
+}
+
+func newAccount(blockNum uint32, owner uuoskit.Authority, active uuoskit.Authority) *account {
+	return &account{
+		created: blockNum,
+		permissions: []uuoskit.Permission{
+			{PermName: uuoskit.NewName("active"), Parent: uuoskit.NewName("owner"), RequiredAuth: active},
+			{PermName: uuoskit.NewName("owner"), RequiredAuth: owner},
+		},
+	}
+}
+
+// URL is the url to give to NewRpc or NewChainApi
+func (c *Chain) URL() string {
+	return c.server.URL
+}
+
+func (c *Chain) Close() {
+	c.server.Close()
+}
+
+func (c *Chain) ChainId() string {
+	return hex.EncodeToString(c.chainId[:])
+}
+
+func (c *Chain) Profile() *uuoskit.ChainProfile {
+	return c.profile
+}
+
+// Transactions returns the transactions pushed successfully
+func (c *Chain) Transactions() []uuoskit.Transaction {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]uuoskit.Transaction{}, c.pushed...)
+}
+
+// CreateAccount creates an account whose owner and active permissions are
+// satisfied by pubKey
+func (c *Chain) CreateAccount(name string, pubKey string) error {
+	_name, err := uuoskit.ParseName(name)
+	if err != nil {
+		return err
+	}
+	pub, err := c.profile.ParsePublicKey(pubKey)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.state.accounts[_name]; ok {
+		return fmt.Errorf("account %s already exists", name)
+	}
+	auth := uuoskit.NewKeyAuthority(pub)
+	c.state.accounts[_name] = newAccount(c.blockNum, auth, auth)
+	return nil
+}
+
+// SetABI sets the ABI of an existing account, actions sent to an account
+// are decoded with its ABI and accepted without effect unless the account
+// is the system or the token contract.
+func (c *Chain) SetABI(account string, abi string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.setABI(uuoskit.NewName(account), abi)
+}
+
+func (c *Chain) setABI(name uuoskit.Name, abi string) error {
+	a, ok := c.state.accounts[name]
+	if !ok {
+		return fmt.Errorf("account %s does not exist", name.String())
+	}
+	if err := c.serializer.SetContractABI(name.String(), []byte(abi)); err != nil {
+		return err
+	}
+	a.abi = abi
+	return nil
+}
+
+// CreateToken creates a token of the token contract as its create action
+// does
+func (c *Chain) CreateToken(issuer string, maxSupply string) error {
+	_maxSupply, err := uuoskit.ParseAsset(maxSupply)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.state.createToken(uuoskit.NewName(issuer), *_maxSupply)
+}
+
+// Issue adds quantity to the supply of its token and to the balance of
+// to, the token must exist.
+func (c *Chain) Issue(to string, quantity string) error {
+	_quantity, err := uuoskit.ParseAsset(quantity)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_to := uuoskit.NewName(to)
+	if _, ok := c.state.accounts[_to]; !ok {
+		return fmt.Errorf("account %s does not exist", to)
+	}
+	return c.state.issue(_to, *_quantity)
+}
+
+// Balance returns the balance of account in the token whose symbol code
+// is symbolCode, e.g. EOS
+func (c *Chain) Balance(account string, symbolCode string) (*uuoskit.Asset, error) {
+	code, err := parseSymbolCode(symbolCode)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stats, ok := c.state.stats[code]
+	if !ok {
+		return nil, fmt.Errorf("token %s does not exist", symbolCode)
+	}
+	balance, ok := c.state.balances[uuoskit.NewName(account)][code]
+	if !ok {
+		return &uuoskit.Asset{Amount: 0, Symbol: stats.Supply.Symbol}, nil
+	}
+	return &balance, nil
+}
+
+func (c *Chain) blockId(blockNum uint32) string {
+	var n [4]byte
+	binary.BigEndian.PutUint32(n[:], blockNum)
+	id := sha256.Sum256(append(c.chainId[:], n[:]...))
+	copy(id[:4], n[:])
+	return hex.EncodeToString(id[:])
+}
+
+func (c *Chain) blockTime(blockNum uint32) string {
+	t := genesisTime.Add(time.Duration(blockNum-1) * blockInterval)
+	return t.Format("2006-01-02T15:04:05.000")
+}
+
+func findPermission(a *account, permission uuoskit.Name) *uuoskit.Permission {
+	for i := range a.permissions {
+		if a.permissions[i].PermName == permission {
+			return &a.permissions[i]
+		}
+	}
+	return nil
+}
+
+// satisfied reports whether keys, in the PUB_K1_ format, satisfy level.
+// The keys that count are added to used, waits are never satisfied.
+func (c *Chain) satisfied(level uuoskit.PermissionLevel, keys map[string]bool, used map[string]bool, depth int) bool {
+	if depth > 6 {
+		return false
+	}
+	a, ok := c.state.accounts[level.Actor]
+	if !ok {
+		return false
+	}
+	perm := findPermission(a, level.Permission)
+	if perm == nil {
+		return false
+	}
+	weight := uint32(0)
+	counted := map[string]bool{}
+	for _, k := range perm.RequiredAuth.Keys {
+		if key := k.Key.String(); keys[key] {
+			weight += uint32(k.Weight)
+			counted[key] = true
+		}
+	}
+	for _, p := range perm.RequiredAuth.Accounts {
+		if c.satisfied(p.Permission, keys, counted, depth+1) {
+			weight += uint32(p.Weight)
+		}
+	}
+	if weight < perm.RequiredAuth.Threshold {
+		return false
+	}
+	for key := range counted {
+		used[key] = true
+	}
+	return true
+}
+
+// checkAuthorization returns the keys needed to satisfy the authorizations
+// of actions, sorted.
+func (c *Chain) checkAuthorization(actions []uuoskit.Action, keys map[string]bool) ([]string, error) {
+	used := map[string]bool{}
+	for _, a := range actions {
+		for _, level := range a.Authorization {
+			if !c.satisfied(level, keys, used, 0) {
+				return nil, unsatisfiedAuthorization(level)
+			}
+		}
+	}
+	required := make([]string, 0, len(used))
+	for key := range used {
+		required = append(required, key)
+	}
+	sort.Strings(required)
+	return required, nil
+}
@@ -0,0 +1,202 @@
+package testchain
+
+import (
+	"encoding/base64"
+	"testing"
+
+	secp256k1 "github.com/armoniax/go-secp256k1"
+	"github.com/armoniax/go-uuoskit/uuoskit"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	testPriv = "5JRYimgLBrRLCBAcjHUWCYRv3asNedTYYzVgmiU4q2ZVxMBiJXL"
+	testPub  = "EOS6AjF6hvF7GSuSd4sCgfPKq5uWaXvGM2aQtEUCwmEHygQaqxBSV"
+)
+
+func newTestChain(t *testing.T) (*Chain, *uuoskit.ChainApi) {
+	assert.Nil(t, uuoskit.GetWallet().Import("test", testPriv))
+	assert.Nil(t, uuoskit.GetWallet().Import("genesis", GenesisPrivateKey))
+	chain := New()
+	assert.Nil(t, chain.CreateAccount("alice", testPub))
+	assert.Nil(t, chain.CreateAccount("bob", testPub))
+	assert.Nil(t, chain.CreateToken("eosio", "1000000.0000 EOS"))
+	assert.Nil(t, chain.Issue("alice", "100.0000 EOS"))
+	return chain, uuoskit.NewChainApiWithProfile(chain.URL(), chain.Profile())
+}
+
+func TestTransfer(t *testing.T) {
+	chain, api := newTestChain(t)
+	defer chain.Close()
+
+	r, err := api.PushActionWithArgs("eosio.token", "transfer",
+		`{"from": "alice", "to": "bob", "quantity": "1.5000 EOS", "memo": "hello"}`, "alice", "active")
+	assert.Nil(t, err)
+	receiver, _ := r.GetString("processed", "action_traces", 2, "receiver")
+	assert.Equal(t, "bob", receiver)
+	memo, _ := r.GetString("processed", "action_traces", 0, "act", "data", "memo")
+	assert.Equal(t, "hello", memo)
+
+	balance, err := chain.Balance("alice", "EOS")
+	assert.Nil(t, err)
+	assert.Equal(t, "98.5000 EOS", balance.String())
+	balance, err = chain.Balance("bob", "EOS")
+	assert.Nil(t, err)
+	assert.Equal(t, "1.5000 EOS", balance.String())
+	assert.Equal(t, 1, len(chain.Transactions()))
+
+	_, err = api.PushActionWithArgs("eosio.token", "transfer",
+		`{"from": "bob", "to": "alice", "quantity": "2.0000 EOS", "memo": ""}`, "bob", "active")
+	assert.Equal(t, "assertion failure with message: overdrawn balance", err.Error())
+	_, err = api.PushActionWithArgs("eosio.token", "transfer",
+		`{"from": "bob", "to": "alice", "quantity": "1.0000 EOS", "memo": ""}`, "alice", "active")
+	assert.Equal(t, "missing authority of bob", err.Error())
+	_, err = api.PushActionWithArgs("eosio.token", "issue",
+		`{"to": "alice", "quantity": "1.0000 EOS", "memo": ""}`, "eosio", "active")
+	assert.Equal(t, "assertion failure with message: tokens can only be issued to issuer account", err.Error())
+	// failed transactions change nothing
+	assert.Equal(t, 1, len(chain.Transactions()))
+	balance, _ = chain.Balance("bob", "EOS")
+	assert.Equal(t, "1.5000 EOS", balance.String())
+
+	rows, err := api.GetTableRows(true, "eosio.token", "alice", "accounts", "", "", 10, "", 0, false, false)
+	assert.Nil(t, err)
+	amount, _ := rows.GetString("rows", 0, "balance")
+	assert.Equal(t, "98.5000 EOS", amount)
+	rows, err = api.GetTableRows(false, "eosio.token", "EOS", "stat", "", "", 10, "", 0, false, false)
+	assert.Nil(t, err)
+	hexRow, _ := rows.GetString("rows", 0)
+	assert.NotEqual(t, "", hexRow)
+	r, _ = api.GetTableRows(true, "eosio.token", "alice", "balances", "", "", 10, "", 0, false, false)
+	msg, _ := r.GetString("error", "details", 0, "message")
+	assert.Equal(t, "Table balances is not specified in the ABI", msg)
+}
+
+func TestAuthorization(t *testing.T) {
+	chain, api := newTestChain(t)
+	defer chain.Close()
+
+	genesis, err := secp256k1.NewPrivateKeyFromBase58(GenesisPrivateKey)
+	assert.Nil(t, err)
+	rpc := uuoskit.NewRpc(chain.URL())
+	info, err := rpc.GetInfo()
+	assert.Nil(t, err)
+	assert.Equal(t, chain.ChainId(), info.ChainID)
+
+	action := uuoskit.NewAction(uuoskit.NewName("eosio.token"), uuoskit.NewName("transfer"),
+		[]uuoskit.PermissionLevel{{Actor: uuoskit.NewName("alice"), Permission: uuoskit.NewName("active")}},
+		uuoskit.NewName("alice"), uuoskit.NewName("bob"),
+		uuoskit.NewAsset(10000, uuoskit.NewSymbol("EOS", 4)), "")
+	tx := uuoskit.NewTransaction(int(genesisTime.Unix()) + 60)
+	assert.Nil(t, tx.SetReferenceBlock(info.LastIrreversibleBlockID))
+	tx.AddAction(action)
+
+	keys, err := rpc.GetRequiredKeys(&uuoskit.GetRequiredKeysArgs{
+		Transaction:   tx,
+		AvailableKeys: []string{testPub, chain.Profile().PublicKeyString(genesis.GetPublicKey())},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"AM6AjF6hvF7GSuSd4sCgfPKq5uWaXvGM2aQtEUCwmEHygQaqxBSV"}, keys.RequiredKeys)
+
+	unsigned := uuoskit.NewPackedTransaction(tx)
+	unsigned.Pack(false)
+	r, err := rpc.PushTransaction(unsigned)
+	assert.Nil(t, err)
+	name, _ := r.GetString("error", "name")
+	assert.Equal(t, "unsatisfied_authorization", name)
+
+	signed := uuoskit.NewPackedTransaction(tx)
+	assert.Nil(t, signed.SetChainId(chain.ChainId()))
+	_, err = signed.SignByPrivateKey(GenesisPrivateKey)
+	assert.Nil(t, err)
+	r, _ = rpc.PushTransaction(signed)
+	name, _ = r.GetString("error", "name")
+	assert.Equal(t, "unsatisfied_authorization", name)
+
+	signed = uuoskit.NewPackedTransaction(tx)
+	assert.Nil(t, signed.SetChainId(chain.ChainId()))
+	_, err = signed.SignByPrivateKey(testPriv)
+	assert.Nil(t, err)
+	signed.Pack(true)
+	r, _ = rpc.PushTransaction(signed)
+	id, err := r.GetString("transaction_id")
+	assert.Nil(t, err)
+	r, _ = rpc.PushTransaction(signed)
+	msg, _ := r.GetString("error", "details", 0, "message")
+	assert.Equal(t, "duplicate transaction "+id, msg)
+
+	expired := uuoskit.NewTransaction(int(genesisTime.Unix()))
+	assert.Nil(t, expired.SetReferenceBlock(info.LastIrreversibleBlockID))
+	expired.AddAction(action)
+	signed = uuoskit.NewPackedTransaction(expired)
+	assert.Nil(t, signed.SetChainId(chain.ChainId()))
+	_, err = signed.SignByPrivateKey(testPriv)
+	assert.Nil(t, err)
+	r, _ = rpc.PushTransaction(signed)
+	name, _ = r.GetString("error", "name")
+	assert.Equal(t, "expired_tx_exception", name)
+
+	// alice@active delegated to bob@active
+	bobAuth := uuoskit.NewAccountAuthority(uuoskit.NewName("bob"), uuoskit.NewName("active"))
+	_, err = api.SetPermission("alice", "active", "owner", bobAuth)
+	assert.Nil(t, err)
+	perm, err := api.GetPermission("alice", "active")
+	assert.Nil(t, err)
+	assert.Equal(t, "bob", perm.RequiredAuth.Accounts[0].Permission.Actor.String())
+	_, err = api.PushActionWithArgs("eosio.token", "transfer",
+		`{"from": "alice", "to": "bob", "quantity": "1.0000 EOS", "memo": ""}`, "alice", "active")
+	assert.Nil(t, err)
+}
+
+func TestAccounts(t *testing.T) {
+	chain, api := newTestChain(t)
+	defer chain.Close()
+
+	result, err := api.CreateAccount("alice", "carol1111111", nil, nil, 8192,
+		*uuoskit.NewAsset(10000, chain.Profile().CoreSymbol), *uuoskit.NewAsset(10000, chain.Profile().CoreSymbol))
+	if !assert.Nil(t, err) {
+		t.Fatal(err)
+	}
+	key, _ := result.Account.GetString("permissions", 0, "required_auth", "keys", 0, "key")
+	assert.Equal(t, result.ActiveKey["public"], key)
+
+	_, err = api.CreateAccount("alice", "carol1111111", nil, nil, 0, uuoskit.Asset{}, uuoskit.Asset{})
+	assert.Equal(t, "Cannot create account named carol1111111, as that name is already taken", err.Error())
+
+	account, err := api.GetAccount("nobody")
+	assert.Nil(t, err)
+	code, _ := account.GetInt64("error", "code")
+	assert.Equal(t, int64(3060002), code)
+
+	rpc := uuoskit.NewRpc(chain.URL())
+	r, err := rpc.Call("chain", "get_raw_abi", `{"account_name": "eosio.token"}`)
+	assert.Nil(t, err)
+	v, err := uuoskit.ParseJsonValue(r)
+	assert.Nil(t, err)
+	abi, _ := v.GetString("abi")
+	binABI, err := base64.StdEncoding.DecodeString(abi)
+	assert.Nil(t, err)
+	_, err = uuoskit.NewABISerializer().UnpackABI(binABI)
+	assert.Nil(t, err)
+
+	r, err = rpc.Call("chain", "get_block", `{"block_num_or_id": 1}`)
+	assert.Nil(t, err)
+	v, _ = uuoskit.ParseJsonValue(r)
+	status, _ := v.GetInt64("code")
+	assert.Equal(t, int64(404), status)
+}
+
+func TestSystemAccountOfProfile(t *testing.T) {
+	assert.Nil(t, uuoskit.GetWallet().Import("genesis", GenesisPrivateKey))
+	profile := uuoskit.EOSProfile()
+	profile.SystemAccount = uuoskit.NewName("amax")
+	chain := NewWithProfile(profile)
+	defer chain.Close()
+	api := uuoskit.NewChainApiWithProfile(chain.URL(), profile)
+
+	// the system account of the profile may create dotted names
+	_, err := api.CreateAccount("amax", "carol.x", nil, nil, 0, uuoskit.Asset{}, uuoskit.Asset{})
+	assert.Nil(t, err)
+	_, err = api.CreateAccount("eosio.token", "dave.x", nil, nil, 0, uuoskit.Asset{}, uuoskit.Asset{})
+	assert.NotNil(t, err)
+}
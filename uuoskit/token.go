@@ -1,6 +1,11 @@
 package uuoskit
 
-import "math/big"
+import (
+	"encoding/json"
+	"math/big"
+	"strconv"
+	"strings"
+)
 
 const MAX_AMOUNT = (1 << 62) - 1
 
@@ -34,6 +39,52 @@ func (a *Symbol) Precision() uint64 {
 	return a.Value & 0xff
 }
 
+// CodeString returns the symbol code, e.g. EOS
+func (a *Symbol) CodeString() string {
+	code := a.Code()
+	buf := make([]byte, 0, 7)
+	for code != 0 {
+		buf = append(buf, byte(code&0xff))
+		code >>= 8
+	}
+	return string(buf)
+}
+
+// String returns the symbol in the "4,EOS" form
+func (a *Symbol) String() string {
+	return strconv.Itoa(int(a.Precision())) + "," + a.CodeString()
+}
+
+// ParseSymbol parses a symbol in the "4,EOS" form
+func ParseSymbol(v string) (Symbol, error) {
+	vv := strings.Split(v, ",")
+	if len(vv) != 2 || !IsSymbolValid(vv[1]) {
+		return Symbol{}, newErrorf("invalid symbol %q", v)
+	}
+	precision, err := strconv.ParseUint(vv[0], 10, 8)
+	if err != nil || precision > maxAssetPrecision {
+		return Symbol{}, newErrorf("invalid symbol precision %q", v)
+	}
+	return NewSymbol(vv[1], int(precision)), nil
+}
+
+func (a Symbol) MarshalJSON() ([]byte, error) {
+	return json.Marshal(a.String())
+}
+
+func (a *Symbol) UnmarshalJSON(b []byte) error {
+	s, err := strconv.Unquote(string(b))
+	if err != nil {
+		return newError(err)
+	}
+	sym, err := ParseSymbol(s)
+	if err != nil {
+		return err
+	}
+	*a = sym
+	return nil
+}
+
 func (a *Symbol) IsValid() bool {
 	sym := a.Code()
 	for i := 0; i < 7; i++ {
@@ -57,14 +108,23 @@ func (a *Symbol) IsValid() bool {
 
 func (a *Symbol) Pack() []byte {
 	enc := NewEncoder(8)
-	enc.Pack(a.Value)
+	a.PackTo(enc)
 	return enc.GetBytes()
 }
 
+func (a *Symbol) PackTo(enc *Encoder) {
+	enc.WriteUint64(a.Value)
+}
+
 func (a *Symbol) Unpack(data []byte) (int, error) {
-	dec := NewDecoder(data)
-	dec.Unpack(&a.Value)
-	return dec.Pos(), nil
+	return unpackBytes(data, a)
+}
+
+func (a *Symbol) UnpackFrom(dec *Decoder) error {
+	if _, err := dec.Unpack(&a.Value); err != nil {
+		return err
+	}
+	return nil
 }
 
 type Asset struct {
@@ -72,6 +132,21 @@ type Asset struct {
 	Symbol Symbol
 }
 
+// RoundingMode selects how results that can not be represented at the
+// precision of an asset are rounded.
+type RoundingMode int
+
+const (
+	RoundDown     RoundingMode = iota // toward zero
+	RoundUp                           // away from zero
+	RoundHalfUp                       // to nearest, ties away from zero
+	RoundHalfEven                     // to nearest, ties to even
+	RoundFloor                        // toward negative infinity
+	RoundCeil                         // toward positive infinity
+)
+
+const maxAssetPrecision = 18
+
 func isAmountWithInRange(amount int64) bool {
 	return -MAX_AMOUNT <= amount && amount <= MAX_AMOUNT
 }
@@ -83,44 +158,238 @@ func NewAsset(amount int64, symbol Symbol) *Asset {
 	return a
 }
 
-func (a *Asset) Add(b *Asset) *Asset {
-	Check(a.Symbol == b.Symbol, "Asset.Add:Symbol not the same")
-	a.Amount += b.Amount
-	Check(-MAX_AMOUNT <= a.Amount, "addition underflow")
-	Check(a.Amount <= MAX_AMOUNT, "addition overflow")
-	return a
+func newAssetFromBig(amount *big.Int, symbol Symbol) (*Asset, error) {
+	if !amount.IsInt64() || !isAmountWithInRange(amount.Int64()) {
+		return nil, newErrorf("magnitude of asset amount must be less than 2^62")
+	}
+	return &Asset{amount.Int64(), symbol}, nil
 }
 
-func (a *Asset) Sub(b *Asset) *Asset {
-	Check(a.Symbol == b.Symbol, "Asset.Sub:Symbol not the same")
-	a.Amount -= b.Amount
-	Check(a.Amount >= -MAX_AMOUNT, "subtraction underflow")
-	Check(a.Amount <= MAX_AMOUNT, "subtraction overflow")
-	return a
+// ParseAsset parses an asset in the "1.0000 EOS" form, negative amounts
+// are allowed. The precision is the number of digits after the dot.
+func ParseAsset(v string) (*Asset, error) {
+	vv := strings.Split(v, " ")
+	if len(vv) != 2 {
+		return nil, newErrorf("invalid asset %q", v)
+	}
+	amount, code := vv[0], vv[1]
+	if !IsSymbolValid(code) {
+		return nil, newErrorf("invalid asset symbol %q", v)
+	}
+
+	digits := strings.TrimPrefix(amount, "-")
+	precision := 0
+	if i := strings.IndexByte(digits, '.'); i >= 0 {
+		precision = len(digits) - i - 1
+		if i == 0 || precision == 0 {
+			return nil, newErrorf("invalid asset amount %q", v)
+		}
+		digits = digits[:i] + digits[i+1:]
+	}
+	if precision > maxAssetPrecision {
+		return nil, newErrorf("asset precision must not exceed %d: %q", maxAssetPrecision, v)
+	}
+	if len(digits) == 0 {
+		return nil, newErrorf("invalid asset amount %q", v)
+	}
+	for i := 0; i < len(digits); i++ {
+		if digits[i] < '0' || digits[i] > '9' {
+			return nil, newErrorf("invalid asset amount %q", v)
+		}
+	}
+
+	n, err := strconv.ParseInt(digits, 10, 64)
+	if err != nil || !isAmountWithInRange(n) {
+		return nil, newErrorf("magnitude of asset amount must be less than 2^62: %q", v)
+	}
+	if strings.HasPrefix(amount, "-") {
+		n = -n
+	}
+	return &Asset{n, NewSymbol(code, precision)}, nil
 }
 
-func (a *Asset) Mul(b *Asset) *Asset {
-	Check(a.Symbol == b.Symbol, "Asset.Mul:Symbol not the same")
-	_a := big.NewInt(a.Amount)
-	_b := big.NewInt(b.Amount)
-	_z := big.NewInt(0)
-	_z.Mul(_a, _b)
+func (a *Asset) String() string {
+	precision := int(a.Symbol.Precision())
+	digits := strconv.FormatUint(uint64(a.Amount), 10)
+	sign := ""
+	if a.Amount < 0 {
+		sign = "-"
+		digits = strconv.FormatUint(uint64(-a.Amount), 10)
+	}
+	if precision > 0 {
+		if len(digits) <= precision {
+			digits = strings.Repeat("0", precision-len(digits)+1) + digits
+		}
+		digits = digits[:len(digits)-precision] + "." + digits[len(digits)-precision:]
+	}
+	return sign + digits + " " + a.Symbol.CodeString()
+}
 
-	m := big.NewInt(MAX_AMOUNT)
-	Check(m.Cmp(_z) >= 0, "multiplication overflow")
+func (a Asset) MarshalJSON() ([]byte, error) {
+	return json.Marshal(a.String())
+}
 
-	m = big.NewInt(-MAX_AMOUNT)
-	Check(_z.Cmp(m) >= 0, "multiplication underflow")
-	a.Amount = _z.Int64()
-	return a
+func (a *Asset) UnmarshalJSON(b []byte) error {
+	s, err := strconv.Unquote(string(b))
+	if err != nil {
+		return newError(err)
+	}
+	r, err := ParseAsset(s)
+	if err != nil {
+		return err
+	}
+	*a = *r
+	return nil
 }
 
-func (a *Asset) Div(b *Asset) *Asset {
-	Check(a.Symbol == b.Symbol, "Asset.Mul:Symbol not the same")
-	Check(b.Amount != 0, "divide by zero")
-	Check(!(a.Amount == int64(-9223372036854775808) && b.Amount == -1), "signed division overflow")
-	a.Amount /= b.Amount
-	return a
+// roundQuo returns num / den rounded according to mode.
+func roundQuo(num, den *big.Int, mode RoundingMode) *big.Int {
+	q, r := new(big.Int).QuoRem(num, den, new(big.Int))
+	if r.Sign() == 0 {
+		return q
+	}
+	sign := int64(num.Sign() * den.Sign())
+	away := false
+	switch mode {
+	case RoundUp:
+		away = true
+	case RoundFloor:
+		away = sign < 0
+	case RoundCeil:
+		away = sign > 0
+	case RoundHalfUp, RoundHalfEven:
+		half := new(big.Int).Abs(r)
+		half.Lsh(half, 1)
+		cmp := half.Cmp(new(big.Int).Abs(den))
+		away = cmp > 0 || (cmp == 0 && (mode == RoundHalfUp || q.Bit(0) == 1))
+	}
+	if away {
+		q.Add(q, big.NewInt(sign))
+	}
+	return q
+}
+
+func (a *Asset) checkSymbol(b *Asset, op string) error {
+	if a.Symbol != b.Symbol {
+		return newErrorf("Asset.%s: symbol not the same: %s, %s", op, a.Symbol.String(), b.Symbol.String())
+	}
+	return nil
+}
+
+// Rescale converts the asset to the given precision, rounding with mode
+// when digits are dropped.
+func (a *Asset) Rescale(precision int, mode RoundingMode) (*Asset, error) {
+	if precision < 0 || precision > maxAssetPrecision {
+		return nil, newErrorf("asset precision must be between 0 and %d", maxAssetPrecision)
+	}
+	current := int(a.Symbol.Precision())
+	amount := big.NewInt(a.Amount)
+	if precision > current {
+		amount.Mul(amount, pow10(precision-current))
+	} else if precision < current {
+		amount = roundQuo(amount, pow10(current-precision), mode)
+	}
+	return newAssetFromBig(amount, NewSymbol(a.Symbol.CodeString(), precision))
+}
+
+// Add returns a + b, a is left unchanged.
+func (a *Asset) Add(b *Asset) (*Asset, error) {
+	if err := a.checkSymbol(b, "Add"); err != nil {
+		return nil, err
+	}
+	return newAssetFromBig(new(big.Int).Add(big.NewInt(a.Amount), big.NewInt(b.Amount)), a.Symbol)
+}
+
+// Sub returns a - b, a is left unchanged.
+func (a *Asset) Sub(b *Asset) (*Asset, error) {
+	if err := a.checkSymbol(b, "Sub"); err != nil {
+		return nil, err
+	}
+	return newAssetFromBig(new(big.Int).Sub(big.NewInt(a.Amount), big.NewInt(b.Amount)), a.Symbol)
+}
+
+// Mul multiplies the raw amounts of a and b, a is left unchanged.
+func (a *Asset) Mul(b *Asset) (*Asset, error) {
+	if err := a.checkSymbol(b, "Mul"); err != nil {
+		return nil, err
+	}
+	return newAssetFromBig(new(big.Int).Mul(big.NewInt(a.Amount), big.NewInt(b.Amount)), a.Symbol)
+}
+
+// Div divides the raw amounts of a and b truncating toward zero,
+// a is left unchanged.
+func (a *Asset) Div(b *Asset) (*Asset, error) {
+	if err := a.checkSymbol(b, "Div"); err != nil {
+		return nil, err
+	}
+	if b.Amount == 0 {
+		return nil, newErrorf("Asset.Div: divide by zero")
+	}
+	return newAssetFromBig(new(big.Int).Quo(big.NewInt(a.Amount), big.NewInt(b.Amount)), a.Symbol)
+}
+
+// Cmp compares a and b, which may differ in precision but not in symbol code.
+func (a *Asset) Cmp(b *Asset) (int, error) {
+	r, err := a.ratioOperands(b, "Cmp")
+	if err != nil {
+		return 0, err
+	}
+	return r[0].Cmp(r[1]), nil
+}
+
+// Ratio returns the exact value of a / b, a and b may differ in precision
+// but not in symbol code.
+func (a *Asset) Ratio(b *Asset) (*big.Rat, error) {
+	r, err := a.ratioOperands(b, "Ratio")
+	if err != nil {
+		return nil, err
+	}
+	if r[1].Sign() == 0 {
+		return nil, newErrorf("Asset.Ratio: divide by zero")
+	}
+	return new(big.Rat).SetFrac(r[0], r[1]), nil
+}
+
+// ratioOperands returns the amounts of a and b scaled to a common precision.
+func (a *Asset) ratioOperands(b *Asset, op string) ([2]*big.Int, error) {
+	if a.Symbol.Code() != b.Symbol.Code() {
+		return [2]*big.Int{}, newErrorf("Asset.%s: symbol not the same: %s, %s", op, a.Symbol.String(), b.Symbol.String())
+	}
+	x, y := big.NewInt(a.Amount), big.NewInt(b.Amount)
+	pa, pb := int(a.Symbol.Precision()), int(b.Symbol.Precision())
+	if pa < pb {
+		x.Mul(x, pow10(pb-pa))
+	} else if pb < pa {
+		y.Mul(y, pow10(pa-pb))
+	}
+	return [2]*big.Int{x, y}, nil
+}
+
+// MulRatio returns a * num / den rounded with mode.
+func (a *Asset) MulRatio(num, den int64, mode RoundingMode) (*Asset, error) {
+	if den == 0 {
+		return nil, newErrorf("Asset.MulRatio: divide by zero")
+	}
+	return a.mulRat(new(big.Rat).SetFrac(big.NewInt(num), big.NewInt(den)), mode)
+}
+
+// Percent returns percent% of a rounded with mode. percent is a decimal
+// string such as "2.5" so that no precision is lost to float64.
+func (a *Asset) Percent(percent string, mode RoundingMode) (*Asset, error) {
+	r, ok := new(big.Rat).SetString(percent)
+	if !ok {
+		return nil, newErrorf("Asset.Percent: invalid percent %q", percent)
+	}
+	return a.mulRat(r.Quo(r, big.NewRat(100, 1)), mode)
+}
+
+func (a *Asset) mulRat(r *big.Rat, mode RoundingMode) (*Asset, error) {
+	num := new(big.Int).Mul(big.NewInt(a.Amount), r.Num())
+	return newAssetFromBig(roundQuo(num, r.Denom(), mode), a.Symbol)
+}
+
+func pow10(n int) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil)
 }
 
 func (a *Asset) IsValid() bool {
@@ -129,16 +398,27 @@ func (a *Asset) IsValid() bool {
 
 func (a *Asset) Pack() []byte {
 	enc := NewEncoder(16)
+	a.PackTo(enc)
+	return enc.GetBytes()
+}
+
+func (a *Asset) PackTo(enc *Encoder) {
 	enc.WriteUint64(uint64(a.Amount))
 	enc.WriteUint64(a.Symbol.Value)
-	return enc.GetBytes()
 }
 
 func (a *Asset) Unpack(data []byte) (int, error) {
-	dec := NewDecoder(data)
-	dec.Unpack(&a.Amount)
-	dec.Unpack(&a.Symbol)
-	return 16, nil
+	return unpackBytes(data, a)
+}
+
+func (a *Asset) UnpackFrom(dec *Decoder) error {
+	if _, err := dec.Unpack(&a.Amount); err != nil {
+		return err
+	}
+	if _, err := dec.Unpack(&a.Symbol); err != nil {
+		return err
+	}
+	return nil
 }
 
 func (t *Asset) Size() int {
@@ -156,16 +436,27 @@ func NewExtendedAsset(quantity Asset, contract Name) *ExtendedAsset {
 
 func (t *ExtendedAsset) Pack() []byte {
 	enc := NewEncoder(16 + 8)
-	enc.Pack(&t.Quantity)
-	enc.PackName(t.Contract)
+	t.PackTo(enc)
 	return enc.GetBytes()
 }
 
+func (t *ExtendedAsset) PackTo(enc *Encoder) {
+	t.Quantity.PackTo(enc)
+	enc.PackName(t.Contract)
+}
+
 func (t *ExtendedAsset) Unpack(data []byte) (int, error) {
-	dec := NewDecoder(data)
-	dec.Unpack(&t.Quantity)
-	dec.Unpack(&t.Contract)
-	return dec.Pos(), nil
+	return unpackBytes(data, t)
+}
+
+func (t *ExtendedAsset) UnpackFrom(dec *Decoder) error {
+	if _, err := dec.Unpack(&t.Quantity); err != nil {
+		return err
+	}
+	if _, err := dec.Unpack(&t.Contract); err != nil {
+		return err
+	}
+	return nil
 }
 
 func (t *ExtendedAsset) Size() int {
@@ -181,18 +472,33 @@ type Transfer struct {
 
 func (a *Transfer) Pack() []byte {
 	enc := NewEncoder(8 + 8 + 16 + len(a.Memo) + 5)
-	enc.Pack(&a.From)
-	enc.Pack(&a.To)
-	enc.Pack(&a.Quantity)
-	enc.PackString(a.Memo)
+	a.PackTo(enc)
 	return enc.GetBytes()
 }
 
+func (a *Transfer) PackTo(enc *Encoder) {
+	enc.PackName(a.From)
+	enc.PackName(a.To)
+	a.Quantity.PackTo(enc)
+	enc.PackString(a.Memo)
+}
+
 func (a *Transfer) Unpack(data []byte) (int, error) {
-	dec := NewDecoder(data)
-	dec.Unpack(&a.From)
-	dec.Unpack(&a.To)
-	dec.Unpack(&a.Quantity)
-	dec.Unpack(&a.Memo)
-	return dec.Pos(), nil
+	return unpackBytes(data, a)
+}
+
+func (a *Transfer) UnpackFrom(dec *Decoder) error {
+	if _, err := dec.Unpack(&a.From); err != nil {
+		return err
+	}
+	if _, err := dec.Unpack(&a.To); err != nil {
+		return err
+	}
+	if _, err := dec.Unpack(&a.Quantity); err != nil {
+		return err
+	}
+	if _, err := dec.Unpack(&a.Memo); err != nil {
+		return err
+	}
+	return nil
 }
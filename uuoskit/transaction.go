@@ -6,6 +6,7 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
+	"sync"
 
 	secp256k1 "github.com/armoniax/go-secp256k1"
 )
@@ -21,24 +22,31 @@ func (a *TransactionExtension) Size() int {
 
 func (t *TransactionExtension) Pack() []byte {
 	enc := NewEncoder(2 + 5 + len(t.Data))
-	enc.Pack(t.Type)
-	enc.Pack(t.Data)
+	t.PackTo(enc)
 	return enc.GetBytes()
 }
 
+func (t *TransactionExtension) PackTo(enc *Encoder) {
+	enc.PackUint16(t.Type)
+	enc.PackBytes(t.Data)
+}
+
 func (t *TransactionExtension) Unpack(data []byte) (int, error) {
+	return unpackBytes(data, t)
+}
+
+func (t *TransactionExtension) UnpackFrom(dec *Decoder) error {
 	var err error
-	dec := NewDecoder(data)
 	t.Type, err = dec.UnpackUint16()
 	if err != nil {
-		return 0, err
+		return err
 	}
 
 	t.Data, err = dec.UnpackBytes()
 	if err != nil {
-		return 0, err
+		return err
 	}
-	return dec.Pos(), nil
+	return nil
 }
 
 type Transaction struct {
@@ -62,6 +70,7 @@ type Transaction struct {
 }
 
 type PackedTransaction struct {
+	mu            sync.Mutex
 	chainId       [32]byte
 	tx            *Transaction
 	compressed    bool
@@ -109,124 +118,131 @@ func (t *Transaction) AddAction(a *Action) {
 }
 
 func (t *Transaction) Pack() []byte {
-	initSize := 4 + 2 + 4 + 5 + 1 + 5
-
-	initSize += 5 // Max varint size
-	for _, action := range t.ContextFreeActions {
-		initSize += action.Size()
-	}
-
-	initSize += 5 // Max varint size
-	for _, action := range t.Actions {
-		initSize += action.Size()
-	}
+	return packBytes(t)
+}
 
-	initSize += 5 // Max varint size
-	for _, extention := range t.Extention {
-		initSize += extention.Size()
-	}
-	enc := NewEncoder(initSize)
-	enc.Pack(t.Expiration.UTCSeconds)
-	enc.Pack(t.RefBlockNum)
-	enc.Pack(t.RefBlockPrefix)
+// PackTo appends the packed transaction to enc, signers packing many
+// transactions can reuse one Encoder with Reset to avoid allocations.
+func (t *Transaction) PackTo(enc *Encoder) {
+	enc.PackUint32(t.Expiration.UTCSeconds)
+	enc.PackUint16(t.RefBlockNum)
+	enc.PackUint32(t.RefBlockPrefix)
 	enc.PackVarUint32(uint32(t.MaxNetUsageWords))
 	enc.PackUint8(t.MaxCpuUsageMs)
 	enc.PackVarUint32(uint32(t.DelaySec))
 
 	enc.PackLength(len(t.ContextFreeActions))
-	for _, action := range t.ContextFreeActions {
-		enc.Pack(&action)
+	for i := range t.ContextFreeActions {
+		t.ContextFreeActions[i].PackTo(enc)
 	}
 
 	enc.PackLength(len(t.Actions))
-	for _, action := range t.Actions {
-		enc.Pack(&action)
+	for i := range t.Actions {
+		t.Actions[i].PackTo(enc)
 	}
 
 	enc.PackLength(len(t.Extention))
-	for _, extention := range t.Extention {
-		enc.Pack(&extention)
+	for i := range t.Extention {
+		t.Extention[i].PackTo(enc)
 	}
-	return enc.GetBytes()
+}
+
+// digest returns the signing digest of the transaction for chainId
+func (t *Transaction) digest(chainId []byte) []byte {
+	enc := getEncoder()
+	defer putEncoder(enc)
+	t.PackTo(enc)
+
+	hash := sha256.New()
+	hash.Write(chainId)
+	hash.Write(enc.Bytes())
+	//TODO: hash context_free_data
+	cfdHash := [32]byte{}
+	hash.Write(cfdHash[:])
+	return hash.Sum(nil)
 }
 
 func (t *Transaction) Unpack(data []byte) (int, error) {
+	return unpackBytes(data, t)
+}
+
+func (t *Transaction) UnpackFrom(dec *Decoder) error {
 	var err error
 
-	dec := NewDecoder(data)
 	t.Expiration.UTCSeconds, err = dec.UnpackUint32()
 	if err != nil {
-		return 0, err
+		return err
 	}
 
 	t.RefBlockNum, err = dec.UnpackUint16()
 	if err != nil {
-		return 0, err
+		return err
 	}
 
 	t.RefBlockPrefix, err = dec.UnpackUint32()
 	if err != nil {
-		return 0, err
+		return err
 	}
 
 	t.MaxNetUsageWords, err = dec.UnpackVarUint32()
 	if err != nil {
-		return 0, err
+		return err
 	}
 
 	t.MaxCpuUsageMs, err = dec.UnpackUint8()
 	if err != nil {
-		return 0, err
+		return err
 	}
 
 	t.DelaySec, err = dec.UnpackVarUint32()
 	if err != nil {
-		return 0, err
+		return err
 	}
 
-	contextFreeActionLength, err := dec.UnpackVarUint32()
+	// an action packs to at least 18 bytes
+	contextFreeActionLength, err := dec.unpackCount(18)
 	if err != nil {
-		return 0, err
+		return err
 	}
 
 	t.ContextFreeActions = make([]Action, contextFreeActionLength)
-	for i := 0; i < int(contextFreeActionLength); i++ {
+	for i := 0; i < contextFreeActionLength; i++ {
 		_, err := dec.Unpack(&t.ContextFreeActions[i])
 		if err != nil {
-			return 0, err
+			return err
 		}
 	}
 
-	actionLength, err := dec.UnpackVarUint32()
+	actionLength, err := dec.unpackCount(18)
 	if err != nil {
-		return 0, err
+		return err
 	}
 
 	t.Actions = make([]Action, actionLength)
-	for i := 0; i < int(actionLength); i++ {
+	for i := 0; i < actionLength; i++ {
 		_, err := dec.Unpack(&t.Actions[i])
 		if err != nil {
-			return 0, err
+			return err
 		}
 	}
 
-	extentionLength, err := dec.UnpackVarUint32()
+	extentionLength, err := dec.unpackCount(3)
 	if err != nil {
-		return 0, err
+		return err
 	}
 	t.Extention = make([]TransactionExtension, extentionLength)
-	for i := 0; i < int(extentionLength); i++ {
+	for i := 0; i < extentionLength; i++ {
 		t.Extention[i].Type, err = dec.UnpackUint16()
 		if err != nil {
-			return 0, err
+			return err
 		}
 
 		t.Extention[i].Data, err = dec.UnpackBytes()
 		if err != nil {
-			return 0, err
+			return err
 		}
 	}
-	return dec.Pos(), nil
+	return nil
 }
 
 func (t *Transaction) Sign(privKey string, chainId string) (string, error) {
@@ -238,13 +254,7 @@ func (t *Transaction) Sign(privKey string, chainId string) (string, error) {
 		return "", newErrorf("chainId must be 32 bytes")
 	}
 
-	hash := sha256.New()
-	hash.Write(_chainId)
-	hash.Write(t.Pack())
-	//TODO: hash context_free_data
-	cfdHash := [32]byte{}
-	hash.Write(cfdHash[:])
-	digest := hash.Sum(nil)
+	digest := t.digest(_chainId)
 
 	priv, err := secp256k1.NewPrivateKeyFromBase58(privKey)
 	if err != nil {
@@ -266,13 +276,7 @@ func (t *Transaction) Digest(chainId string) (string, error) {
 		return "", newErrorf("chainId must be 32 bytes")
 	}
 
-	hash := sha256.New()
-	hash.Write(_chainId)
-	hash.Write(t.Pack())
-	//TODO: hash context_free_data
-	cfdHash := [32]byte{}
-	hash.Write(cfdHash[:])
-	digest := hash.Sum(nil)
+	digest := t.digest(_chainId)
 	return hex.EncodeToString(digest), nil
 }
 
@@ -303,11 +307,15 @@ func (t *PackedTransaction) SetChainId(chainId string) error {
 	if err != nil {
 		return newError(err)
 	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
 	copy(t.chainId[:], id)
 	return nil
 }
 
 func (t *PackedTransaction) AddAction(a *Action) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
 	if t.PackedTx != nil {
 		return newErrorf("can not add new action after pack or sign")
 	}
@@ -316,6 +324,8 @@ func (t *PackedTransaction) AddAction(a *Action) error {
 }
 
 func (t *PackedTransaction) sign(priv *secp256k1.PrivateKey) (string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
 	if t.compressed {
 		return "", newErrorf("can not sign after pack")
 	}
@@ -351,21 +361,26 @@ func (t *PackedTransaction) sign(priv *secp256k1.PrivateKey) (string, error) {
 }
 
 func (t *PackedTransaction) Digest(chainId string) (string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
 	return t.tx.Digest(chainId)
 }
 
+// Sign signs with a key of the global wallet, see SignWithWallet
 func (t *PackedTransaction) Sign(pubKey string) (string, error) {
-	priv, err := GetWallet().GetPrivateKey(pubKey)
+	return t.SignWithWallet(GetWallet(), pubKey)
+}
+
+// SignWithWallet signs with the key of pubKey in w, pubKey may be in the
+// legacy format of the chain profile of w.
+func (t *PackedTransaction) SignWithWallet(w *Wallet, pubKey string) (string, error) {
+	priv, err := w.GetPrivateKey(pubKey)
 	if err != nil {
 		return "", err
 	}
-	empty := false
-	for i := 0; i < 32; i++ {
-		if t.chainId[i] != 0 {
-			empty = false
-			break
-		}
-	}
+	t.mu.Lock()
+	empty := t.chainId == [32]byte{}
+	t.mu.Unlock()
 
 	if empty {
 		return "", newErrorf("chainId is empty")
@@ -384,11 +399,15 @@ func (t *PackedTransaction) SignByPrivateKey(privKey string) (string, error) {
 }
 
 func (t *PackedTransaction) Marshal() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
 	r, _ := json.Marshal(t.tx)
 	return string(r)
 }
 
 func (t *PackedTransaction) Pack(compress bool) string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
 	if compress {
 		t.Compression = "zlib"
 	} else {
@@ -4,9 +4,7 @@ import (
 	"encoding/hex"
 	"errors"
 	"fmt"
-	"math"
 	"strconv"
-	"strings"
 
 	traceable_errors "github.com/go-errors/errors"
 )
@@ -39,6 +37,10 @@ func newError(err error) error {
 		if _, ok := err.(*traceable_errors.Error); ok {
 			return err
 		}
+		// keep the offset and path of decoding errors
+		if _, ok := err.(*DecodeError); ok {
+			return err
+		}
 		return traceable_errors.New(err.Error())
 	} else {
 		return err
@@ -97,48 +99,3 @@ func IsSymbolValid(sym string) bool {
 	}
 	return true
 }
-
-func ParseAsset(v string) ([]byte, bool) {
-	vv := strings.Split(v, " ")
-	if len(vv) != 2 {
-		return nil, false
-	}
-	amount := vv[0]
-	symbol_code := vv[1]
-	if !IsSymbolValid(symbol_code) {
-		return nil, false
-	}
-	_, err := strconv.ParseFloat(amount, 64)
-
-	if err != nil {
-		return nil, false
-	}
-	_amount := strings.Split(amount, ".")
-	if len(_amount) > 2 {
-		return nil, false
-	}
-
-	precision := 0
-	if len(_amount) == 2 {
-		precision = len(_amount[1])
-	}
-
-	amount = strings.Replace(amount, ".", "", 1)
-	nAmount, err := strconv.ParseInt(amount, 10, 64)
-	if err != nil {
-		return nil, false
-	}
-
-	if nAmount < 0 || nAmount > math.MaxInt64 {
-		return nil, false
-	}
-
-	enc := NewEncoder(8)
-	enc.PackInt64(nAmount)
-
-	enc.WriteBytes([]byte{byte(precision)})
-	_symbol_code := make([]byte, 7)
-	copy(_symbol_code[:], []byte(symbol_code)[:])
-	enc.WriteBytes(_symbol_code)
-	return enc.GetBytes(), true
-}
@@ -9,6 +9,7 @@ import (
 	"log"
 	"os"
 	"strings"
+	"sync"
 	"time"
 
 	"fmt"
@@ -16,6 +17,7 @@ import (
 
 	secp256k1 "github.com/armoniax/go-secp256k1"
 	"github.com/iancoleman/orderedmap"
+	"github.com/stretchr/testify/assert"
 )
 
 func TestOrderedMap(t *testing.T) {
@@ -33,7 +35,6 @@ func TestOrderedMap(t *testing.T) {
 
 func TestCrypto(t *testing.T) {
 	secp256k1.Init()
-	defer secp256k1.Destroy()
 
 	// digest := make([]byte, 32)
 	//	seckey := make([]byte, 32)
@@ -290,84 +291,6 @@ func TestPackAbiArray(t *testing.T) {
 	}
 
 }
-func TestTx(t *testing.T) {
-	rpc := NewRpc("https://testnode.uuos.network:8443")
-
-	chainInfo, err := rpc.GetInfo()
-	if err != nil {
-		panic(err)
-	}
-
-	//tx := &Transaction{}
-	expiration := int(time.Now().Unix()) + 60
-	tx := NewTransaction(expiration)
-	tx.SetReferenceBlock(chainInfo.LastIrreversibleBlockID)
-
-	pub := "EOS6AjF6hvF7GSuSd4sCgfPKq5uWaXvGM2aQtEUCwmEHygQaqxBSV"
-	priv := "5JRYimgLBrRLCBAcjHUWCYRv3asNedTYYzVgmiU4q2ZVxMBiJXL"
-	GetWallet().Import("test", "5JRYimgLBrRLCBAcjHUWCYRv3asNedTYYzVgmiU4q2ZVxMBiJXL")
-	privKey, err := secp256k1.NewPrivateKeyFromBase58(priv)
-	if err != nil {
-		panic(err)
-	}
-	pubKey, err := secp256k1.GetPublicKey(privKey)
-	if err != nil {
-		panic(err)
-	}
-	t.Log("+++++++pubKey:", pubKey.String())
-	action := NewAction(NewName("eosio.token"),
-		NewName("transfer"),
-		[]PermissionLevel{{NewName("helloworld11"), NewName("active")}},
-		NewName("helloworld11"),
-		NewName("eosio.token"),
-		NewAsset(1000, NewSymbol("EOS", 4)),
-		"transfer from alice")
-	action.AddPermission(NewName("helloworld11"), NewName("active"))
-	tx.AddAction(action)
-
-	chainId := chainInfo.ChainID
-	sign, err := tx.Sign(priv, chainId)
-	if err != nil {
-		panic(err)
-	}
-	t.Log("++++++sign:", sign)
-
-	packedTx := NewPackedTransaction(tx)
-	packedTx.SetChainId(chainId)
-	sign, err = packedTx.SignByPrivateKey(priv)
-	if err != nil {
-		panic(err)
-	}
-	log.Println(sign)
-
-	t.Log(packedTx.Pack(false))
-
-	sign, err = packedTx.Sign(pub)
-	if err != nil {
-		panic(err)
-	}
-	t.Log(packedTx.Pack(false))
-
-	r, err := rpc.PushTransaction(packedTx)
-	if err != nil {
-		panic(err)
-	}
-
-	{
-		v, err := r.Get("processed", "action_traces", 0, "action_ordinal")
-		if err != nil {
-			panic(err)
-		}
-		t.Logf("%T\n", v)
-	}
-
-	// rr, err := json.MarshalIndent(r, "", " ")
-	// if err != nil {
-	// 	panic(err)
-	// }
-
-	// t.Log(string(rr))
-}
 
 func TestIsoTime(tt *testing.T) {
 	// convert iso-8601 into rfc-3339 format
@@ -429,26 +352,170 @@ func TestPackAbi(t *testing.T) {
 	// Output: any
 }
 
-func TestRpc(t *testing.T) {
-	rpc := NewRpc("http://www.google.com")
-	info, err := rpc.GetInfo()
+func TestParseAsset(t *testing.T) {
+	v, err := ParseAsset("0.0100 EOS")
 	if err != nil {
 		panic(err)
 	}
-	r, err := json.MarshalIndent(info, "", " ")
-	if err != nil {
-		panic(err)
+	assert.Equal(t, int64(100), v.Amount)
+	assert.Equal(t, NewSymbol("EOS", 4), v.Symbol)
+	assert.Equal(t, "0.0100 EOS", v.String())
+
+	for _, s := range []string{"-1.2345 EOS", "100 EOS", "0.000000000000000001 WAX", "-0.1 A"} {
+		v, err := ParseAsset(s)
+		assert.Nil(t, err, s)
+		assert.Equal(t, s, v.String())
 	}
-	t.Log(string(r))
-	// Output: any
-}
 
-func TestParseAsset(t *testing.T) {
-	v, ok := ParseAsset("0.0100 EOS")
-	if !ok {
-		panic("bad value")
+	for _, s := range []string{"", "1.0 eos", "1.0  EOS", "1. EOS", ".1 EOS", "--1 EOS", "1e3 EOS", "+1 EOS",
+		"1.0000000000000000000 EOS", "4611686018427387904 EOS", "1.0 EOSEOSEOS"} {
+		_, err := ParseAsset(s)
+		assert.NotNil(t, err, s)
 	}
-	t.Log(v)
+
+	r, err := json.Marshal(struct{ Quantity Asset }{*v})
+	assert.Nil(t, err)
+	assert.Equal(t, `{"Quantity":"0.0100 EOS"}`, string(r))
+	var a struct{ Quantity Asset }
+	assert.Nil(t, json.Unmarshal([]byte(`{"Quantity":"-3.14 XYZ"}`), &a))
+	assert.Equal(t, "-3.14 XYZ", a.Quantity.String())
+}
+
+func TestAssetArithmetic(t *testing.T) {
+	a, _ := ParseAsset("1.0000 EOS")
+	b, _ := ParseAsset("0.2500 EOS")
+	c, _ := ParseAsset("1.00 EOS")
+
+	r, err := a.Add(b)
+	assert.Nil(t, err)
+	assert.Equal(t, "1.2500 EOS", r.String())
+	assert.Equal(t, "1.0000 EOS", a.String())
+
+	r, err = b.Sub(a)
+	assert.Nil(t, err)
+	assert.Equal(t, "-0.7500 EOS", r.String())
+
+	_, err = a.Add(c)
+	assert.NotNil(t, err)
+	_, err = a.Div(NewAsset(0, NewSymbol("EOS", 4)))
+	assert.NotNil(t, err)
+	max := NewAsset(MAX_AMOUNT, NewSymbol("EOS", 4))
+	_, err = max.Mul(a)
+	assert.NotNil(t, err)
+	_, err = max.Add(NewAsset(1, NewSymbol("EOS", 4)))
+	assert.NotNil(t, err)
+
+	cmp, err := a.Cmp(c)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, cmp)
+	ratio, err := b.Ratio(c)
+	assert.Nil(t, err)
+	assert.Equal(t, "1/4", ratio.String())
+
+	r, err = a.Rescale(8, RoundDown)
+	assert.Nil(t, err)
+	assert.Equal(t, "1.00000000 EOS", r.String())
+	_, err = max.Rescale(8, RoundDown)
+	assert.NotNil(t, err)
+
+	rounding := []struct {
+		value string
+		mode  RoundingMode
+		want  string
+	}{
+		{"1.25 EOS", RoundDown, "1.2 EOS"},
+		{"1.25 EOS", RoundUp, "1.3 EOS"},
+		{"1.25 EOS", RoundHalfUp, "1.3 EOS"},
+		{"1.25 EOS", RoundHalfEven, "1.2 EOS"},
+		{"1.35 EOS", RoundHalfEven, "1.4 EOS"},
+		{"-1.25 EOS", RoundHalfUp, "-1.3 EOS"},
+		{"-1.21 EOS", RoundFloor, "-1.3 EOS"},
+		{"-1.29 EOS", RoundCeil, "-1.2 EOS"},
+		{"1.21 EOS", RoundCeil, "1.3 EOS"},
+	}
+	for _, c := range rounding {
+		v, _ := ParseAsset(c.value)
+		r, err := v.Rescale(1, c.mode)
+		assert.Nil(t, err)
+		assert.Equal(t, c.want, r.String(), c.value)
+	}
+
+	r, err = a.MulRatio(1, 3, RoundHalfEven)
+	assert.Nil(t, err)
+	assert.Equal(t, "0.3333 EOS", r.String())
+	r, err = a.MulRatio(2, 3, RoundDown)
+	assert.Nil(t, err)
+	assert.Equal(t, "0.6666 EOS", r.String())
+	_, err = a.MulRatio(1, 0, RoundDown)
+	assert.NotNil(t, err)
+
+	r, err = a.Percent("2.5", RoundDown)
+	assert.Nil(t, err)
+	assert.Equal(t, "0.0250 EOS", r.String())
+	r, err = b.Percent("0.01", RoundUp)
+	assert.Nil(t, err)
+	assert.Equal(t, "0.0001 EOS", r.String())
+	_, err = a.Percent("abc", RoundDown)
+	assert.NotNil(t, err)
+}
+
+func TestParseName(t *testing.T) {
+	for _, s := range []string{"", "eosio", "eosio.token", "a.b.c", "abcdefghijklj", "123451234512", ".abc"} {
+		n, err := ParseName(s)
+		assert.Nil(t, err, s)
+		assert.Equal(t, s, n.String())
+	}
+	for _, s := range []string{"Eosio", "eosio6", "abcdefghijklk", "abcdefghijklmn", "hello.", "hello world", "eos-io"} {
+		_, err := ParseName(s)
+		assert.NotNil(t, err, s)
+		assert.False(t, IsNameValid(s), s)
+	}
+
+	var n Name
+	assert.Nil(t, json.Unmarshal([]byte(`"alice"`), &n))
+	assert.NotNil(t, json.Unmarshal([]byte(`"Alice"`), &n))
+
+	n = NewName("eosio.token")
+	assert.Equal(t, "token", n.Suffix().String())
+	assert.Equal(t, "eosio", n.Prefix().String())
+	assert.Equal(t, "c", NewName("a.b.c").Suffix().String())
+	assert.Equal(t, "a.b", NewName("a.b.c").Prefix().String())
+	assert.Equal(t, ".abc", NewName(".abc").Suffix().String())
+
+	assert.True(t, NewName("eosio").IsPremium())
+	assert.False(t, NewName("helloworld12").IsPremium())
+	assert.False(t, NewName("hello.x").IsPremium())
+	parent, ok := NewName("hello.x").ParentAccount()
+	assert.True(t, ok)
+	assert.Equal(t, NewName("x"), parent)
+	_, ok = NewName("helloworld12").ParentAccount()
+	assert.False(t, ok)
+
+	system := NewName("eosio")
+	assert.Nil(t, CheckNewAccountName(system, NewName("alice"), NewName("helloworld12")))
+	assert.Nil(t, CheckNewAccountName(system, NewName("x"), NewName("hello.x")))
+	assert.NotNil(t, CheckNewAccountName(system, NewName("alice"), NewName("hello.x")))
+	assert.NotNil(t, CheckNewAccountName(system, NewName("alice"), NewName("bob")))
+	assert.Nil(t, CheckNewAccountName(system, NewName("eosio"), NewName("bob")))
+	assert.NotNil(t, CheckNewAccountName(system, NewName("alice"), Name{}))
+	assert.Nil(t, CheckNewAccountName(NewName("amax"), NewName("amax"), NewName("bob")))
+	assert.NotNil(t, CheckNewAccountName(NewName("amax"), NewName("eosio"), NewName("bob")))
+
+	assert.True(t, NewName("alice").Less(NewName("bob")))
+	assert.Equal(t, 1, NewName("bob").Compare(NewName("alice")))
+	assert.Equal(t, 0, NewName("bob").Compare(NewName("bob")))
+
+	n = NewName("eosio")
+	assert.Equal(t, "6138663577826885632", n.I64Key())
+	assert.Equal(t, uint64(6138663577826885632), n.Uint64())
+	k, err := ParseNameKey("6138663577826885632")
+	assert.Nil(t, err)
+	assert.Equal(t, n, k)
+	k, err = ParseNameKey("eosio")
+	assert.Nil(t, err)
+	assert.Equal(t, n, k)
+	_, err = ParseNameKey("EOSIO")
+	assert.NotNil(t, err)
 }
 
 func TestTxMarshal(t *testing.T) {
@@ -484,6 +551,68 @@ func TestTimePointSec(t *testing.T) {
 	t.Logf("%v", tp.UTCSeconds)
 }
 
+func TestTimeTypes(t *testing.T) {
+	// results must not depend on the local time zone
+	local := time.Local
+	time.Local = time.FixedZone("UTC+8", 8*3600)
+	defer func() { time.Local = local }()
+
+	for _, s := range []string{"2023-03-10T14:44:30.123", "2023-03-10T14:44:30.123Z", "2023-03-10T22:44:30.123+08:00", "2023-03-10 14:44:30.123456"} {
+		tm, err := ParseTime(s)
+		assert.Nil(t, err, s)
+		assert.Equal(t, time.UTC, tm.Location())
+		assert.Equal(t, "2023-03-10T14:44:30.123", NewTimePoint(tm).String(), s)
+	}
+	_, err := ParseTime("10/03/2023")
+	assert.NotNil(t, err)
+
+	tp := TimePoint{1678459470123456}
+	assert.Equal(t, "2023-03-10T14:44:30.123", tp.String())
+	assert.Equal(t, int64(1678459470123456), tp.Time().UnixMicro())
+	r, err := json.Marshal(tp)
+	assert.Nil(t, err)
+	assert.Equal(t, `"2023-03-10T14:44:30.123"`, string(r))
+
+	tps, err := NewTimePointSec(tp.Time())
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(1678459470), tps.UTCSeconds)
+	assert.Equal(t, "2023-03-10T14:44:30", tps.String())
+	assert.Nil(t, json.Unmarshal([]byte(`"2023-03-10T14:44:30"`), &tps))
+	assert.Equal(t, uint32(1678459470), tps.UTCSeconds)
+
+	bt, err := NewBlockTimestamp(tp.Time())
+	assert.Nil(t, err)
+	assert.Equal(t, "2023-03-10T14:44:30.000", bt.String())
+	bt.Slot++
+	assert.Equal(t, "2023-03-10T14:44:30.500", bt.String())
+	_, err = NewBlockTimestamp(time.Unix(0, 0))
+	assert.NotNil(t, err)
+
+	var v struct {
+		T  TimePoint
+		B  BlockTimestampType
+		TS TimePointSec
+	}
+	assert.Nil(t, json.Unmarshal([]byte(`{"T":"2023-03-10T14:44:30.5","B":"2023-03-10T14:44:30.500","TS":"2023-03-10T14:44:30"}`), &v))
+	r, err = json.Marshal(v)
+	assert.Nil(t, err)
+	assert.Equal(t, `{"T":"2023-03-10T14:44:30.500","B":"2023-03-10T14:44:30.500","TS":"2023-03-10T14:44:30"}`, string(r))
+
+	s := NewABISerializer()
+	for typ, value := range map[string]string{
+		"time_point":           "2023-03-10T14:44:30.123",
+		"time_point_sec":       "2023-03-10T14:44:30",
+		"block_timestamp_type": "2023-03-10T14:44:30.500",
+	} {
+		s.SetContractABI("test", []byte(fmt.Sprintf(gAbi, typ)))
+		packed, err := s.PackAbiType("test", "test", fmt.Sprintf(`{"t": "%s"}`, value))
+		assert.Nil(t, err, typ)
+		r, err := s.UnpackAbiType("test", "test", packed)
+		assert.Nil(t, err, typ)
+		assert.Equal(t, fmt.Sprintf(`{"t":"%s"}`, value), string(r), typ)
+	}
+}
+
 func TestBytes(t *testing.T) {
 	bs := Bytes{}
 	err := json.Unmarshal([]byte(`"aabb"`), &bs)
@@ -583,3 +712,154 @@ func TestBinaryExtension(t *testing.T) {
 		}
 	}
 }
+
+func TestConcurrentChainContext(t *testing.T) {
+	ctx := NewChainContext()
+	chainId := "9b1605a3f7f14995641c6b19413841c26ca86747f054241951a298b556160674"
+	priv := "5JRYimgLBrRLCBAcjHUWCYRv3asNedTYYzVgmiU4q2ZVxMBiJXL"
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				GetWallet().Import("test", priv)
+				GetWallet().GetPublicKeys()
+
+				packedTx := NewPackedTransaction(NewTransaction(0))
+				packedTx.SetChainId(chainId)
+				index := ctx.AddPackedTx(packedTx)
+				if index < 0 {
+					t.Error("no free transaction slot")
+					return
+				}
+				tx, err := ctx.GetPackedTx(index)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				tx.AddAction(NewAction(NewName("hello"), NewName("sayhello")))
+				if _, err := tx.SignByPrivateKey(priv); err != nil {
+					t.Error(err)
+					return
+				}
+				tx.Pack(false)
+				if err := ctx.RemovePackedTx(index); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	// a transaction and the wallet shared between goroutines
+	pub := "EOS6AjF6hvF7GSuSd4sCgfPKq5uWaXvGM2aQtEUCwmEHygQaqxBSV"
+	shared := NewPackedTransaction(NewTransaction(0))
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				shared.AddAction(NewAction(NewName("hello"), NewName("sayhello")))
+				shared.SetChainId(chainId)
+				GetWallet().Remove("test", pub)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				GetWallet().Import("test", priv)
+				if _, err := shared.Digest(chainId); err != nil {
+					t.Error(err)
+					return
+				}
+				shared.Sign(pub)
+				GetWallet().Sign(make([]byte, 32), pub)
+			}
+		}()
+	}
+	wg.Wait()
+
+	GetWallet().Import("test", priv)
+	_, err := NewPackedTransaction(NewTransaction(0)).Sign(pub)
+	assert.NotNil(t, err, "sign without chain id")
+}
+
+func TestChainProfile(t *testing.T) {
+	priv := "5JRYimgLBrRLCBAcjHUWCYRv3asNedTYYzVgmiU4q2ZVxMBiJXL"
+	eosPub := "EOS6AjF6hvF7GSuSd4sCgfPKq5uWaXvGM2aQtEUCwmEHygQaqxBSV"
+	amPub := "AM6AjF6hvF7GSuSd4sCgfPKq5uWaXvGM2aQtEUCwmEHygQaqxBSV"
+
+	profile := EOSProfile()
+	pub, err := profile.ParsePublicKey(amPub)
+	assert.Nil(t, err)
+	assert.Equal(t, eosPub, profile.PublicKeyString(pub))
+	pub2, err := ParsePublicKey(pub.String())
+	assert.Nil(t, err)
+	assert.Equal(t, pub.Data, pub2.Data)
+	_, err = ParsePublicKey("XYZ6AjF6hvF7GSuSd4sCgfPKq5uWaXvGM2aQtEUCwmEHygQaqxBSV")
+	assert.NotNil(t, err)
+
+	// the wallet renders keys with the prefix of its profile
+	w := GetWallet()
+	prev := w.GetChainProfile()
+	defer w.SetChainProfile(prev)
+	w.SetChainProfile(profile)
+	assert.Nil(t, w.Import("test", priv))
+	assert.Contains(t, w.GetPublicKeys(), eosPub)
+	for _, k := range []string{eosPub, amPub, pub.String()} {
+		_, err := w.Sign(make([]byte, 32), k)
+		assert.Nil(t, err)
+	}
+
+	// the ABI codec packs and renders keys in the profile format
+	s := NewABISerializerWithProfile(profile)
+	assert.True(t, s.IsAbiCached("eosio.token"))
+	assert.Nil(t, s.SetContractABI("test", []byte(fmt.Sprintf(gAbi, "public_key"))))
+	packed, err := s.PackAbiType("test", "test", fmt.Sprintf(`{"t": "%s"}`, eosPub))
+	assert.Nil(t, err)
+	r, err := s.UnpackAbiType("test", "test", packed)
+	assert.Nil(t, err)
+	assert.Equal(t, fmt.Sprintf(`{"t":"%s"}`, eosPub), string(r))
+
+	// options without a key prefix keep the prefix of the profile
+	s.SetUnpackOptions(UnpackOptions{Int64AsString: true})
+	assert.Equal(t, "EOS", s.GetUnpackOptions().LegacyKeyPrefix)
+	r, err = s.UnpackAbiType("test", "test", packed)
+	assert.Nil(t, err)
+	assert.Equal(t, fmt.Sprintf(`{"t":"%s"}`, eosPub), string(r))
+
+	ctx := NewChainContextWithProfile(UUOSProfile())
+	assert.Equal(t, "UOS", ctx.Profile.KeyPrefix)
+	assert.Equal(t, "4,UUOS", ctx.Profile.CoreSymbol.String())
+
+	// the wallet of a context shares the keys of the global wallet and
+	// renders them with the prefix of the context
+	uosPub := "UOS" + eosPub[3:]
+	assert.Contains(t, ctx.Wallet.GetPublicKeys(), uosPub)
+	assert.Contains(t, w.GetPublicKeys(), eosPub)
+	packedTx := NewPackedTransaction(NewTransaction(0))
+	assert.Nil(t, packedTx.SetChainId("9b1605a3f7f14995641c6b19413841c26ca86747f054241951a298b556160674"))
+	index := ctx.AddPackedTx(packedTx)
+	sig, err := ctx.SignPackedTx(index, uosPub)
+	assert.Nil(t, err)
+	assert.NotEqual(t, "", sig)
+	assert.True(t, ctx.Wallet.Remove("test", uosPub))
+	_, err = w.GetPrivateKey(eosPub)
+	assert.NotNil(t, err)
+
+	b, err := json.Marshal(profile)
+	assert.Nil(t, err)
+	parsed, err := ParseChainProfile(b)
+	assert.Nil(t, err)
+	assert.Equal(t, profile, parsed)
+
+	parsed, err = ParseChainProfile([]byte(`{"name": "test", "key_prefix": "TST", "core_symbol": "8,TST"}`))
+	assert.Nil(t, err)
+	assert.Equal(t, NewSymbol("TST", 8), parsed.CoreSymbol)
+	assert.Equal(t, NewName("eosio"), parsed.SystemAccount)
+	_, err = ParseChainProfile([]byte(`{"core_symbol": "TST"}`))
+	assert.NotNil(t, err)
+}
@@ -1,23 +1,67 @@
 package uuoskit
 
 import (
+	"sync"
+
 	secp256k1 "github.com/armoniax/go-secp256k1"
 )
 
-type Wallet struct {
+// walletKeys holds private keys by the PUB_K1_ form of their public key, it
+// can be shared by wallets of different chain profiles.
+type walletKeys struct {
+	mu   sync.RWMutex
 	keys map[string]*secp256k1.PrivateKey
 }
 
+// Wallet stores keys by their PUB_K1_ form, public keys are accepted and
+// returned in the legacy format of the wallet's chain profile.
+type Wallet struct {
+	mu      sync.RWMutex
+	store   *walletKeys
+	profile *ChainProfile
+}
+
 var gWallet *Wallet
+var gWalletOnce sync.Once
 
 func GetWallet() *Wallet {
-	if gWallet == nil {
-		gWallet = &Wallet{}
-		gWallet.keys = make(map[string]*secp256k1.PrivateKey)
-	}
+	gWalletOnce.Do(func() {
+		gWallet = NewWallet(DefaultChainProfile())
+	})
 	return gWallet
 }
 
+// NewWallet returns an empty wallet using profile
+func NewWallet(profile *ChainProfile) *Wallet {
+	return &Wallet{
+		store:   &walletKeys{keys: make(map[string]*secp256k1.PrivateKey)},
+		profile: profile,
+	}
+}
+
+// WithChainProfile returns a wallet holding the same keys as w, keys
+// imported or removed through either wallet are seen by both, that accepts
+// and returns public keys in the format of profile.
+func (w *Wallet) WithChainProfile(profile *ChainProfile) *Wallet {
+	return &Wallet{store: w.store, profile: profile}
+}
+
+func (w *Wallet) SetChainProfile(profile *ChainProfile) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.profile = profile
+}
+
+func (w *Wallet) GetChainProfile() *ChainProfile {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.profile
+}
+
+func (w *Wallet) parsePublicKey(pubKey string) (*secp256k1.PublicKey, error) {
+	return w.GetChainProfile().ParsePublicKey(pubKey)
+}
+
 func (w *Wallet) Import(name string, strPriv string) error {
 	priv, err := secp256k1.NewPrivateKeyFromBase58(strPriv)
 	if err != nil {
@@ -25,55 +69,71 @@ func (w *Wallet) Import(name string, strPriv string) error {
 	}
 
 	pub := priv.GetPublicKey()
-	w.keys[pub.StringAM()] = priv
+	w.store.mu.Lock()
+	defer w.store.mu.Unlock()
+	w.store.keys[pub.String()] = priv
 	return nil
 }
 
 func (w *Wallet) Remove(name string, pubKey string) bool {
-	_pubKey, err := secp256k1.NewPublicKeyFromBase58(pubKey)
+	_pubKey, err := w.parsePublicKey(pubKey)
 	if err != nil {
 		return false
 	}
 
-	pubKey = _pubKey.StringAM()
-	if priv, ok := w.keys[pubKey]; ok {
-		for i := 0; i < len(w.keys); i++ {
+	pubKey = _pubKey.String()
+	w.store.mu.Lock()
+	defer w.store.mu.Unlock()
+	if priv, ok := w.store.keys[pubKey]; ok {
+		for i := 0; i < len(priv.Data); i++ {
 			priv.Data[i] = 0
 		}
-		delete(w.keys, pubKey)
+		delete(w.store.keys, pubKey)
 		return true
 	}
 	return false
 }
 
-//GetPublicKeys
+// GetPublicKeys returns the keys in the legacy format of the chain profile
 func (w *Wallet) GetPublicKeys() []string {
-	keys := make([]string, 0, len(w.keys))
-	for k := range w.keys {
-		keys = append(keys, k)
+	profile := w.GetChainProfile()
+	w.store.mu.RLock()
+	defer w.store.mu.RUnlock()
+	keys := make([]string, 0, len(w.store.keys))
+	for k := range w.store.keys {
+		pub, err := secp256k1.NewPublicKeyFromBase58(k)
+		if err != nil {
+			continue
+		}
+		keys = append(keys, profile.PublicKeyString(pub))
 	}
 	return keys
 }
 
+// GetPrivateKey returns a copy of the key, Remove only clears the key held
+// by the wallet.
 func (w *Wallet) GetPrivateKey(pubKey string) (*secp256k1.PrivateKey, error) {
-	priv, ok := w.keys[pubKey]
+	pub, err := w.parsePublicKey(pubKey)
+	if err != nil {
+		return nil, err
+	}
+	w.store.mu.RLock()
+	defer w.store.mu.RUnlock()
+	priv, ok := w.store.keys[pub.String()]
 	if !ok {
 		return nil, newErrorf("not found")
 	}
-	return priv, nil
+	key := *priv
+	return &key, nil
 }
 
 func (w *Wallet) Sign(digest []byte, pubKey string) (*secp256k1.Signature, error) {
-	pub, err := secp256k1.NewPublicKeyFromBase58(pubKey)
+	priv, err := w.GetPrivateKey(pubKey)
 	if err != nil {
-		return nil, newError(err)
-	}
-
-	priv, ok := w.keys[pub.StringAM()]
-	if !ok {
-		return nil, newErrorf("not found")
+		return nil, err
 	}
 	sig, err := priv.Sign(digest)
+	priv.Data = [32]byte{}
 	if err != nil {
 		return nil, newError(err)
 	}